@@ -2,8 +2,12 @@ package configs
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +19,38 @@ type AppConfig struct {
 	AccountSID    string
 	AuthToken     string
 	TwilioPhoneNo string
+	StripeSecret  string
+	PubKey        string
+	WebhookSecret string
+	Currency      string
+	//stripe or fake, fake keeps payments in memory for local runs
+	PaymentProvider string
+	//carrier used when a shipment doesn't name one
+	Carrier string
+	//optional tracking timelines for the simulator carrier
+	CarrierFixtures string
+	//optional tax rate table, the bundled one is used otherwise
+	TaxRules string
+	//platform commission in basis points on sales no commission rule covers
+	CommissionRate int64
+	//fake only for now, transfers are kept in memory
+	PayoutProvider string
+	//how often seller balances are paid out
+	PayoutInterval time.Duration
+	//sales are held back from payouts this long, to cover returns
+	PayoutHold time.Duration
+	//how long gift cards can be spent after they are issued
+	GiftCardValidity time.Duration
+	//account buyers paying by bank transfer send the money to
+	BankTransferDetails string
+	//orders are cancelled when their bank transfer takes longer
+	BankTransferWindow time.Duration
+	//how long a response is replayed for retries with the same Idempotency-Key
+	IdempotencyTTL time.Duration
+	//static only for now, rates are read from a file
+	FXProvider string
+	//optional exchange rate file, the bundled one is used otherwise
+	FXRates string
 }
 
 func SetupEnv() (cfg AppConfig, err error) {
@@ -53,6 +89,107 @@ func SetupEnv() (cfg AppConfig, err error) {
 		return AppConfig{}, errors.New("twilio phone number not found")
 	}
 
-	return AppConfig{ServerPort: httpPort, Dsn: Dsn, AppSecret: appSecret, AccountSID: accountSID, AuthToken: authToken, TwilioPhoneNo: twilioPhoneNo}, nil
+	paymentProvider := os.Getenv("PAYMENT_PROVIDER")
+	if len(paymentProvider) < 1 {
+		paymentProvider = "stripe"
+	}
+
+	stripeSecret := os.Getenv("STRIPE_SECRET")
+	if len(stripeSecret) < 1 && paymentProvider == "stripe" {
+		return AppConfig{}, errors.New("stripe secret not found")
+	}
+
+	pubKey := os.Getenv("STRIPE_PUB_KEY")
+
+	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+	if len(webhookSecret) < 1 && paymentProvider == "stripe" {
+		return AppConfig{}, errors.New("webhook secret not found")
+	}
+
+	//ISO 4217 code orders are paid in, products listed in other currencies
+	//are converted to it at checkout
+	currency := strings.ToUpper(os.Getenv("CURRENCY"))
+	if len(currency) < 1 {
+		currency = "USD"
+	}
+
+	carrier := os.Getenv("CARRIER")
+	if len(carrier) < 1 {
+		carrier = "simulator"
+	}
+
+	carrierFixtures := os.Getenv("CARRIER_FIXTURES")
+
+	taxRules := os.Getenv("TAX_RULES")
+
+	commissionRate := int64(1000)
+	if rate := os.Getenv("COMMISSION_RATE"); len(rate) > 0 {
+		commissionRate, err = strconv.ParseInt(rate, 10, 64)
+		if err != nil || commissionRate < 0 || commissionRate > 10000 {
+			return AppConfig{}, errors.New("commission rate should be basis points between 0 and 10000")
+		}
+	}
+
+	payoutProvider := os.Getenv("PAYOUT_PROVIDER")
+	if len(payoutProvider) < 1 {
+		payoutProvider = "fake"
+	}
+
+	payoutInterval, err := envDuration("PAYOUT_INTERVAL", 24*time.Hour)
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	payoutHold, err := envDuration("PAYOUT_HOLD", 7*24*time.Hour)
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	giftCardValidity, err := envDuration("GIFT_CARD_VALIDITY", 365*24*time.Hour)
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	bankTransferDetails := os.Getenv("BANK_TRANSFER_DETAILS")
+
+	bankTransferWindow, err := envDuration("BANK_TRANSFER_WINDOW", 72*time.Hour)
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	idempotencyTTL, err := envDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+	if err != nil {
+		return AppConfig{}, err
+	}
+
+	fxProvider := os.Getenv("FX_PROVIDER")
+	if len(fxProvider) < 1 {
+		fxProvider = "static"
+	}
+
+	fxRates := os.Getenv("FX_RATES")
+
+	return AppConfig{ServerPort: httpPort, Dsn: Dsn, AppSecret: appSecret, AccountSID: accountSID, AuthToken: authToken, TwilioPhoneNo: twilioPhoneNo,
+		StripeSecret: stripeSecret, PubKey: pubKey, WebhookSecret: webhookSecret, Currency: currency, PaymentProvider: paymentProvider,
+		Carrier: carrier, CarrierFixtures: carrierFixtures, TaxRules: taxRules, CommissionRate: commissionRate, PayoutProvider: payoutProvider,
+		PayoutInterval: payoutInterval, PayoutHold: payoutHold, GiftCardValidity: giftCardValidity,
+		BankTransferDetails: bankTransferDetails, BankTransferWindow: bankTransferWindow,
+		IdempotencyTTL: idempotencyTTL, FXProvider: fxProvider, FXRates: fxRates}, nil
+
+}
+
+// envDuration reads a duration such as 24h, fallback is used when unset
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+
+	value := os.Getenv(key)
+	if len(value) < 1 {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s should be a duration like 24h", key)
+	}
 
+	return d, nil
 }
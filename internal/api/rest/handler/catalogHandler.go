@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"errors"
 	"go-ecommerce-app/internal/api/rest"
 	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/repository"
@@ -20,9 +21,13 @@ func SetupCatalogRoutes(rh *RestHandler) {
 	app := rh.App
 
 	svc := service.CatalogService{
-		Repo:   repository.NewCatalogRepository(rh.DB),
-		Auth:   rh.Auth,
-		Config: rh.Config,
+		Repo:      repository.NewCatalogRepository(rh.DB),
+		PromoRepo: repository.NewPromotionRepository(rh.DB),
+		UserRepo:  repository.NewUserRepository(rh.DB),
+		Search:    repository.NewProductSearch(rh.DB),
+		Auth:      rh.Auth,
+		Config:    rh.Config,
+		Rates:     rh.Rates,
 	}
 
 	catalogHandler := CatalogHandler{
@@ -32,9 +37,12 @@ func SetupCatalogRoutes(rh *RestHandler) {
 	//public
 	//listing products and categories
 	app.Get("/products", catalogHandler.GetAllProducts)
+	//before /products/:id, which would take "search" as an id
+	app.Get("/products/search", catalogHandler.SearchProducts)
 	app.Get("/products/:id", catalogHandler.GetAProduct)
 	app.Get("/categories", catalogHandler.GetAllCategories)
 	app.Get("/categories/:id", catalogHandler.GetACategory)
+	app.Get("/currencies", catalogHandler.GetCurrencies)
 
 	//private
 	//manage products and categories
@@ -53,6 +61,12 @@ func SetupCatalogRoutes(rh *RestHandler) {
 	selRoutes.Put("/products/:id", catalogHandler.StockUpdate) //update stock
 	selRoutes.Delete("/products/:id", catalogHandler.DeleteProduct)
 
+	//variants
+	selRoutes.Put("/products/:id/options", catalogHandler.SetProductOptions)
+	selRoutes.Post("/products/:id/variants", catalogHandler.CreateVariant)
+	selRoutes.Patch("/products/:id/variants/:variantId", catalogHandler.UpdateVariant)
+	selRoutes.Delete("/products/:id/variants/:variantId", catalogHandler.DeleteVariant)
+
 }
 
 func (h *CatalogHandler) GetAllCategories(ctx *fiber.Ctx) error {
@@ -141,6 +155,9 @@ func (h *CatalogHandler) CreateProduct(ctx *fiber.Ctx) error {
 	//Getting current user for userid
 	user := h.svc.Auth.GetCurrentUser(ctx) 
 	prdct, err := h.svc.CreateProduct(user.ID, req)
+	if errors.Is(err, service.ErrCurrency) {
+		return rest.BadRequestError(ctx, "unsupported currency", err)
+	}
 	if err != nil {
 		return rest.InternalError(ctx, err)
 	}
@@ -150,13 +167,36 @@ func (h *CatalogHandler) CreateProduct(ctx *fiber.Ctx) error {
 
 func (h *CatalogHandler) GetAllProducts(ctx *fiber.Ctx) error {
 
-	allprdcts, err := h.svc.GetAllProducts()
+	//prices are also shown in ?currency=
+	allprdcts, err := h.svc.GetAllProducts(ctx.Query("currency"))
+	if errors.Is(err, service.ErrCurrency) {
+		return rest.BadRequestError(ctx, "unsupported currency", err)
+	}
 	if err != nil {
 		return rest.InternalError(ctx, err)
 	}
 	return rest.SuccessResponse(ctx, "products list", allprdcts)
 }
 
+func (h *CatalogHandler) SearchProducts(ctx *fiber.Ctx) error {
+
+	results, err := h.svc.SearchProducts(dto.ProductSearchRequest{
+		Query:      ctx.Query("q"),
+		CategoryId: uint(ctx.QueryInt("category")),
+		Page:       ctx.QueryInt("page", 1),
+		Limit:      ctx.QueryInt("limit", service.SEARCH_PAGE_SIZE),
+		Currency:   ctx.Query("currency"),
+	})
+	if errors.Is(err, service.ErrSearchQuery) || errors.Is(err, service.ErrCurrency) {
+		return rest.BadRequestError(ctx, "invalid search", err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "search results", results)
+}
+
 func (h *CatalogHandler) GetAProduct(ctx *fiber.Ctx) error {
 	//Extract id from URL params
 	id, err := strconv.Atoi(ctx.Params("id"))
@@ -165,7 +205,10 @@ func (h *CatalogHandler) GetAProduct(ctx *fiber.Ctx) error {
 	}
 
 	//Call service to get product using id
-	prdct, err := h.svc.FindProductById(id)
+	prdct, err := h.svc.FindProductById(id, ctx.Query("currency"))
+	if errors.Is(err, service.ErrCurrency) {
+		return rest.BadRequestError(ctx, "unsupported currency", err)
+	}
 	if err != nil {
 		return rest.InternalError(ctx, err)
 	}
@@ -189,6 +232,12 @@ func (h *CatalogHandler) EditProduct(ctx *fiber.Ctx) error {
 	}
 
 	updatedPrdct, err := h.svc.UpdateProduct(prdctId, req, &user)
+	if errors.Is(err, service.ErrCurrency) {
+		return rest.BadRequestError(ctx, "unsupported currency", err)
+	}
+	if errors.Is(err, service.ErrVariantStock) {
+		return rest.BadRequestError(ctx, "update the stock of the variants", err)
+	}
 	if err != nil {
 		return rest.InternalError(ctx, err)
 	}
@@ -211,6 +260,9 @@ func (h *CatalogHandler) StockUpdate(ctx *fiber.Ctx) error {
 	//Getting current userid for verifying product belongs to current seller
 	user := h.svc.Auth.GetCurrentUser(ctx)
 	updated, err := h.svc.StockUpdate(id, req, user)
+	if errors.Is(err, service.ErrVariantStock) {
+		return rest.BadRequestError(ctx, "update the stock of the variants", err)
+	}
 	if err != nil {
 		return rest.InternalError(ctx, err)
 	}
@@ -231,3 +283,114 @@ func (h *CatalogHandler) DeleteProduct(ctx *fiber.Ctx) error {
 
 	return rest.SuccessResponse(ctx, "product deletion success", nil)
 }
+
+func (h *CatalogHandler) GetCurrencies(ctx *fiber.Ctx) error {
+
+	currencies, err := h.svc.GetCurrencies()
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "currencies", currencies)
+}
+
+func (h *CatalogHandler) SetProductOptions(ctx *fiber.Ctx) error {
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid product id", err)
+	}
+
+	req := dto.ProductOptionsRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+	product, err := h.svc.SetProductOptions(id, req, user)
+	if err != nil {
+		return variantError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "product options updated", product)
+}
+
+func (h *CatalogHandler) CreateVariant(ctx *fiber.Ctx) error {
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid product id", err)
+	}
+
+	req := dto.VariantRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+	variant, err := h.svc.CreateVariant(id, req, user)
+	if err != nil {
+		return variantError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "variant created", variant)
+}
+
+func (h *CatalogHandler) UpdateVariant(ctx *fiber.Ctx) error {
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid product id", err)
+	}
+
+	variantId, err := strconv.Atoi(ctx.Params("variantId"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid variant id", err)
+	}
+
+	req := dto.VariantRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+	variant, err := h.svc.UpdateVariant(id, uint(variantId), req, user)
+	if err != nil {
+		return variantError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "variant updated", variant)
+}
+
+func (h *CatalogHandler) DeleteVariant(ctx *fiber.Ctx) error {
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid product id", err)
+	}
+
+	variantId, err := strconv.Atoi(ctx.Params("variantId"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid variant id", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+	if err := h.svc.DeleteVariant(id, uint(variantId), user); err != nil {
+		return variantError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "variant deleted", nil)
+}
+
+// variantError maps the variant management errors to their status
+func variantError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, service.ErrVariantNotFound):
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	case errors.Is(err, service.ErrSKUTaken):
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	case errors.Is(err, service.ErrVariantInvalid):
+		return rest.BadRequestError(ctx, "invalid variant", err)
+	}
+	return rest.InternalError(ctx, err)
+}
@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CommissionHandler struct {
+	svc service.CommissionService
+}
+
+func SetupCommissionRoutes(rh *RestHandler) {
+
+	app := rh.App
+
+	svc := service.CommissionService{
+		Repo:        repository.NewCommissionRepository(rh.DB),
+		LedgerRepo:  repository.NewLedgerRepository(rh.DB),
+		CatalogRepo: repository.NewCatalogRepository(rh.DB),
+		Auth:        rh.Auth,
+		Config:      rh.Config,
+	}
+
+	handler := CommissionHandler{
+		svc: svc,
+	}
+
+	adminRoutes := app.Group("/admin", rh.Auth.AuthorizeAdmin)
+	adminRoutes.Get("/commissions/rules", handler.GetRules)
+	adminRoutes.Post("/commissions/rules", handler.CreateRule)
+	adminRoutes.Patch("/commissions/rules/:id", handler.UpdateRule)
+	adminRoutes.Delete("/commissions/rules/:id", handler.DeleteRule)
+	adminRoutes.Get("/commissions/report", handler.GetReport)
+	adminRoutes.Patch("/sellers/:id/tier", handler.SetSellerTier)
+
+}
+
+func (h *CommissionHandler) GetRules(ctx *fiber.Ctx) error {
+
+	rules, err := h.svc.GetRules()
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "commission rules", rules)
+}
+
+func (h *CommissionHandler) CreateRule(ctx *fiber.Ctx) error {
+
+	req := dto.CommissionRuleRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	rule, err := h.svc.CreateRule(user, req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "commission rule created", rule)
+}
+
+func (h *CommissionHandler) UpdateRule(ctx *fiber.Ctx) error {
+	//Extract rule id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid rule id", err)
+	}
+
+	req := dto.CommissionRuleRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	rule, err := h.svc.UpdateRule(uint(id), req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "commission rule updated", rule)
+}
+
+func (h *CommissionHandler) DeleteRule(ctx *fiber.Ctx) error {
+	//Extract rule id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid rule id", err)
+	}
+
+	if err := h.svc.DeleteRule(uint(id)); err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "commission rule deleted", nil)
+}
+
+func (h *CommissionHandler) GetReport(ctx *fiber.Ctx) error {
+
+	report, err := h.svc.GetReport(ctx.Query("from"), ctx.Query("to"))
+	if errors.Is(err, service.ErrReportRange) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "commission report", report)
+}
+
+func (h *CommissionHandler) SetSellerTier(ctx *fiber.Ctx) error {
+	//Extract seller id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid seller id", err)
+	}
+
+	req := dto.SellerTierRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	if err := h.svc.SetSellerTier(id, req); err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller tier updated", nil)
+}
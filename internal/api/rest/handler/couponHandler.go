@@ -0,0 +1,113 @@
+package rest
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CouponHandler struct {
+	svc service.CouponService
+}
+
+func SetupCouponRoutes(rh *RestHandler) {
+
+	app := rh.App
+
+	svc := service.CouponService{
+		Repo:   repository.NewCouponRepository(rh.DB),
+		Auth:   rh.Auth,
+		Config: rh.Config,
+	}
+
+	handler := CouponHandler{
+		svc: svc,
+	}
+
+	//platform wide coupons
+	adminRoutes := app.Group("/admin", rh.Auth.AuthorizeAdmin)
+	adminRoutes.Get("/coupons", handler.GetCoupons)
+	adminRoutes.Get("/coupons/:id", handler.GetCoupon)
+	adminRoutes.Post("/coupons", handler.CreateCoupon)
+	adminRoutes.Patch("/coupons/:id", handler.UpdateCoupon)
+
+	//coupons for the seller's own items
+	selRoutes := app.Group("/seller", rh.Auth.AuthorizeSeller)
+	selRoutes.Get("/coupons", handler.GetCoupons)
+	selRoutes.Get("/coupons/:id", handler.GetCoupon)
+	selRoutes.Post("/coupons", handler.CreateCoupon)
+	selRoutes.Patch("/coupons/:id", handler.UpdateCoupon)
+
+}
+
+func (h *CouponHandler) GetCoupons(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	coupons, err := h.svc.GetCoupons(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "coupons", coupons)
+}
+
+func (h *CouponHandler) GetCoupon(ctx *fiber.Ctx) error {
+	//Extract coupon id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid coupon id", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	coupon, err := h.svc.GetCoupon(user, uint(id))
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "coupon", coupon)
+}
+
+func (h *CouponHandler) CreateCoupon(ctx *fiber.Ctx) error {
+
+	req := dto.CouponRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	coupon, err := h.svc.CreateCoupon(user, req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "coupon created", coupon)
+}
+
+func (h *CouponHandler) UpdateCoupon(ctx *fiber.Ctx) error {
+	//Extract coupon id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid coupon id", err)
+	}
+
+	req := dto.CouponRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	coupon, err := h.svc.UpdateCoupon(user, uint(id), req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "coupon updated", coupon)
+}
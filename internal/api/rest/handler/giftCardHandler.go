@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type GiftCardHandler struct {
+	svc service.GiftCardService
+}
+
+func SetupGiftCardRoutes(rh *RestHandler) {
+
+	app := rh.App
+
+	svc := service.GiftCardService{
+		Tx:     repository.NewTxManager(rh.DB),
+		Auth:   rh.Auth,
+		Config: rh.Config,
+	}
+
+	handler := GiftCardHandler{
+		svc: svc,
+	}
+
+	//buyers redeem and check cards under /users
+	adminRoutes := app.Group("/admin", rh.Auth.AuthorizeAdmin)
+	adminRoutes.Post("/giftcards", handler.IssueGiftCards)
+	adminRoutes.Get("/giftcards", handler.GetGiftCards)
+	adminRoutes.Get("/giftcards/:code", handler.GetGiftCard)
+	adminRoutes.Patch("/giftcards/:id", handler.UpdateGiftCard)
+	adminRoutes.Post("/giftcards/products", handler.CreateGiftCardProduct)
+
+}
+
+func (h *GiftCardHandler) IssueGiftCards(ctx *fiber.Ctx) error {
+
+	req := dto.IssueGiftCardsRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	issued, err := h.svc.IssueGiftCards(user, req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "gift cards issued", issued)
+}
+
+func (h *GiftCardHandler) GetGiftCards(ctx *fiber.Ctx) error {
+
+	cards, err := h.svc.GetGiftCards(ctx.Query("batch"))
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "gift cards", cards)
+}
+
+func (h *GiftCardHandler) GetGiftCard(ctx *fiber.Ctx) error {
+
+	card, err := h.svc.GetGiftCard(ctx.Params("code"))
+	if errors.Is(err, service.ErrGiftCardNotFound) {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "gift card", card)
+}
+
+func (h *GiftCardHandler) UpdateGiftCard(ctx *fiber.Ctx) error {
+	//Extract gift card id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid gift card id", err)
+	}
+
+	req := dto.UpdateGiftCardRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	card, err := h.svc.UpdateGiftCard(uint(id), req)
+	if errors.Is(err, service.ErrGiftCardNotFound) {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "gift card updated", card)
+}
+
+func (h *GiftCardHandler) CreateGiftCardProduct(ctx *fiber.Ctx) error {
+
+	req := dto.CreateProductRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.CreateGiftCardProduct(user, req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "gift card product created", product)
+}
@@ -3,15 +3,26 @@ package rest
 import (
 	"go-ecommerce-app/configs"
 	"go-ecommerce-app/internal/helper"
+	payment "go-ecommerce-app/pkg/Payment"
+	"go-ecommerce-app/pkg/carrier"
+	"go-ecommerce-app/pkg/fx"
+	"go-ecommerce-app/pkg/payout"
+	"go-ecommerce-app/pkg/tax"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
 type RestHandler struct {
-	App  *fiber.App
-	DB   *gorm.DB
-	Auth helper.Auth
-	Config configs.AppConfig
-	
+	App      *fiber.App
+	DB       *gorm.DB
+	Auth     helper.Auth
+	Config   configs.AppConfig
+	Pc       payment.PaymentClient
+	Carriers carrier.Carriers
+	Tax      *tax.Rules
+	Rates    fx.Source
+	Payouts  payout.PayoutClient
+	//makes mutating routes safe to retry, see rest.Idempotency
+	Idempotent fiber.Handler
 }
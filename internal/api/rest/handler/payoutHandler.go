@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/payout"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type PayoutHandler struct {
+	svc service.PayoutService
+}
+
+func InitializePayoutService(db *gorm.DB, auth helper.Auth, config configs.AppConfig, pc payout.PayoutClient) service.PayoutService {
+	return service.PayoutService{
+		Repo:   repository.NewLedgerRepository(db),
+		Tx:     repository.NewTxManager(db),
+		Auth:   auth,
+		Config: config,
+		Payout: pc,
+	}
+}
+
+func SetupPayoutRoutes(rh *RestHandler) {
+
+	app := rh.App
+	svc := InitializePayoutService(rh.DB, rh.Auth, rh.Config, rh.Payouts)
+
+	handler := PayoutHandler{
+		svc: svc,
+	}
+
+	//payouts also run on a schedule, this is for running one early
+	adminRoutes := app.Group("/admin", rh.Auth.AuthorizeAdmin)
+	adminRoutes.Post("/payouts/run", handler.RunPayouts)
+
+	sellerRoutes := app.Group("/seller", rh.Auth.AuthorizeSeller)
+	sellerRoutes.Get("/balance", handler.GetBalance)
+	sellerRoutes.Get("/payouts", handler.GetPayouts)
+
+}
+
+func (h *PayoutHandler) GetBalance(ctx *fiber.Ctx) error {
+	//Getting current seller
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	balances, err := h.svc.GetBalance(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller balance", balances)
+}
+
+func (h *PayoutHandler) GetPayouts(ctx *fiber.Ctx) error {
+	//Getting current seller
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	payouts, err := h.svc.GetPayouts(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller payouts", payouts)
+}
+
+func (h *PayoutHandler) RunPayouts(ctx *fiber.Ctx) error {
+
+	run, err := h.svc.RunPayouts()
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "payout run finished", run)
+}
@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PromotionHandler struct {
+	svc service.PromotionService
+}
+
+func SetupPromotionRoutes(rh *RestHandler) {
+
+	app := rh.App
+
+	svc := service.PromotionService{
+		Repo:   repository.NewPromotionRepository(rh.DB),
+		CRepo:  repository.NewCatalogRepository(rh.DB),
+		Auth:   rh.Auth,
+		Config: rh.Config,
+	}
+
+	handler := PromotionHandler{
+		svc: svc,
+	}
+
+	//sales, buy-get offers and bundles on the seller's products
+	selRoutes := app.Group("/seller", rh.Auth.AuthorizeSeller)
+	selRoutes.Get("/promotions", handler.GetPromotions)
+	selRoutes.Post("/promotions", handler.CreatePromotion)
+	selRoutes.Patch("/promotions/:id", handler.UpdatePromotion)
+	selRoutes.Delete("/promotions/:id", handler.DeletePromotion)
+
+}
+
+func (h *PromotionHandler) GetPromotions(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	promotions, err := h.svc.GetPromotions(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "promotions", promotions)
+}
+
+func (h *PromotionHandler) CreatePromotion(ctx *fiber.Ctx) error {
+
+	req := dto.PromotionRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	promotion, err := h.svc.CreatePromotion(user, req)
+	if errors.Is(err, service.ErrPromotionCurrency) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "promotion created", promotion)
+}
+
+func (h *PromotionHandler) UpdatePromotion(ctx *fiber.Ctx) error {
+	//Extract promotion id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid promotion id", err)
+	}
+
+	req := dto.PromotionRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	promotion, err := h.svc.UpdatePromotion(user, uint(id), req)
+	if errors.Is(err, service.ErrPromotionCurrency) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "promotion updated", promotion)
+}
+
+func (h *PromotionHandler) DeletePromotion(ctx *fiber.Ctx) error {
+	//Extract promotion id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid promotion id", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	if err := h.svc.DeletePromotion(user, uint(id)); err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "promotion deleted", nil)
+}
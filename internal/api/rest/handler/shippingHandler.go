@@ -0,0 +1,168 @@
+package rest
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ShippingHandler struct {
+	svc service.ShippingService
+}
+
+func SetupShippingRoutes(rh *RestHandler) {
+
+	app := rh.App
+
+	svc := service.ShippingService{
+		Repo:   repository.NewShippingRepository(rh.DB),
+		Auth:   rh.Auth,
+		Config: rh.Config,
+	}
+
+	handler := ShippingHandler{
+		svc: svc,
+	}
+
+	//seller shipping zones and their rates
+	selRoutes := app.Group("/seller", rh.Auth.AuthorizeSeller)
+	selRoutes.Get("/shipping/zones", handler.GetZones)
+	selRoutes.Post("/shipping/zones", handler.CreateZone)
+	selRoutes.Patch("/shipping/zones/:id", handler.UpdateZone)
+	selRoutes.Delete("/shipping/zones/:id", handler.DeleteZone)
+	selRoutes.Post("/shipping/zones/:id/methods", handler.CreateMethod)
+	selRoutes.Patch("/shipping/methods/:id", handler.UpdateMethod)
+	selRoutes.Delete("/shipping/methods/:id", handler.DeleteMethod)
+
+}
+
+func (h *ShippingHandler) GetZones(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	zones, err := h.svc.GetZones(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipping zones", zones)
+}
+
+func (h *ShippingHandler) CreateZone(ctx *fiber.Ctx) error {
+
+	req := dto.ShippingZoneRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	zone, err := h.svc.CreateZone(user, req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipping zone created", zone)
+}
+
+func (h *ShippingHandler) UpdateZone(ctx *fiber.Ctx) error {
+	//Extract zone id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid zone id", err)
+	}
+
+	req := dto.ShippingZoneRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	zone, err := h.svc.UpdateZone(user, uint(id), req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipping zone updated", zone)
+}
+
+func (h *ShippingHandler) DeleteZone(ctx *fiber.Ctx) error {
+	//Extract zone id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid zone id", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	if err := h.svc.DeleteZone(user, uint(id)); err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipping zone deleted", nil)
+}
+
+func (h *ShippingHandler) CreateMethod(ctx *fiber.Ctx) error {
+	//Extract zone id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid zone id", err)
+	}
+
+	req := dto.ShippingMethodRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	method, err := h.svc.CreateMethod(user, uint(id), req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipping method created", method)
+}
+
+func (h *ShippingHandler) UpdateMethod(ctx *fiber.Ctx) error {
+	//Extract method id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid method id", err)
+	}
+
+	req := dto.ShippingMethodRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	method, err := h.svc.UpdateMethod(user, uint(id), req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipping method updated", method)
+}
+
+func (h *ShippingHandler) DeleteMethod(ctx *fiber.Ctx) error {
+	//Extract method id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid method id", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	if err := h.svc.DeleteMethod(user, uint(id)); err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipping method deleted", nil)
+}
@@ -1,9 +1,19 @@
 package rest
 
 import (
+	"errors"
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/helper"
 	"go-ecommerce-app/internal/repository"
 	"go-ecommerce-app/internal/service"
+	payment "go-ecommerce-app/pkg/Payment"
+	"go-ecommerce-app/pkg/carrier"
+	"go-ecommerce-app/pkg/fx"
+	"go-ecommerce-app/pkg/tax"
+	"net/http"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -13,38 +23,318 @@ type TransactionHandler struct {
 	svc service.TransactionService
 }
 
-func InitializeTransactionService(db *gorm.DB, auth helper.Auth) service.TransactionService {
+func InitializeTransactionService(db *gorm.DB, auth helper.Auth, config configs.AppConfig, pc payment.PaymentClient, carriers carrier.Carriers, rules *tax.Rules, rates fx.Source) service.TransactionService {
 	return service.TransactionService{
-		Repo: repository.NewTransactionRepo(db),
-		Auth: auth,
+		Repo:         repository.NewTransactionRepo(db),
+		UserRepo:     repository.NewUserRepository(db),
+		CatalogRepo:  repository.NewCatalogRepository(db),
+		ShipmentRepo: repository.NewShipmentRepository(db),
+		Tx:           repository.NewTxManager(db),
+		Auth:         auth,
+		Config:       config,
+		Payment:      pc,
+		Carriers:     carriers,
+		Tax:          rules,
+		Rates:        rates,
 	}
 }
 
 func SetupTransactionRoutes(rh *RestHandler) {
 
 	app := rh.App
-	svc := InitializeTransactionService(rh.DB, rh.Auth)
+	svc := InitializeTransactionService(rh.DB, rh.Auth, rh.Config, rh.Pc, rh.Carriers, rh.Tax, rh.Rates)
 
 	handler := TransactionHandler{
 		svc: svc,
 	}
 
-	secRoute := app.Group("/", rh.Auth.Authorize)
-	secRoute.Get("/payment", handler.MakePayment)
+	//gateway webhooks are authenticated by their signature
+	app.Post("/webhooks/payment", handler.PaymentWebhook)
 
+	//a group on "/" would put every later route behind auth, so the buyer
+	//route carries its own middleware
+	app.Get("/payment", rh.Auth.Authorize, rh.Idempotent, handler.MakePayment)
+	app.Get("/payment/methods", handler.GetPaymentMethods)
+
+	//bank transfers are matched against the platform account by hand
+	adminRoutes := app.Group("/admin", rh.Auth.AuthorizeAdmin)
+	adminRoutes.Get("/payments/transfers", handler.GetPendingTransfers)
+	adminRoutes.Post("/payments/transfers/reconcile", rh.Idempotent, handler.ReconcileTransfer)
+	//so is cash a courier hands over without tracking the delivery
+	adminRoutes.Post("/payments/cod/collect", rh.Idempotent, handler.ConfirmCashCollection)
+
+	//seller orders are the seller's fulfilments, :id is the fulfilment id
 	sellerRoutes := app.Group("/seller", rh.Auth.AuthorizeSeller)
 	sellerRoutes.Get("/orders", handler.GetOrders)
 	sellerRoutes.Get("/orders/:id", handler.GetOrderDetails)
+	sellerRoutes.Get("/orders/:id/invoice", handler.GetInvoice)
+	sellerRoutes.Patch("/orders/:id/status", handler.UpdateOrderStatus)
+	sellerRoutes.Post("/orders/:id/cancel", rh.Idempotent, handler.CancelFulfilment)
+	sellerRoutes.Post("/shipments", handler.CreateShipment)
+	sellerRoutes.Get("/shipments/:id", handler.GetShipment)
+	sellerRoutes.Get("/returns", handler.GetReturns)
+	sellerRoutes.Patch("/returns/:id/status", handler.UpdateReturnStatus)
 
 }
 
 func (h *TransactionHandler) MakePayment(ctx *fiber.Ctx) error {
-	return nil
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	paymentInfo, err := h.svc.MakePayment(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "payment started", paymentInfo)
+}
+
+func (h *TransactionHandler) GetPaymentMethods(ctx *fiber.Ctx) error {
+	return rest.SuccessResponse(ctx, "payment methods", h.svc.GetPaymentMethods())
+}
+
+func (h *TransactionHandler) GetPendingTransfers(ctx *fiber.Ctx) error {
+
+	transfers, err := h.svc.GetPendingTransfers()
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "pending bank transfers", transfers)
+}
+
+func (h *TransactionHandler) ReconcileTransfer(ctx *fiber.Ctx) error {
+
+	req := dto.ReconcileTransferRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	p, err := h.svc.ReconcileBankTransfer(user, req)
+	if errors.Is(err, service.ErrTransferNotFound) {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+	if errors.Is(err, service.ErrInvalidTransition) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "bank transfer reconciled", p)
+}
+
+func (h *TransactionHandler) ConfirmCashCollection(ctx *fiber.Ctx) error {
+
+	req := dto.ConfirmCashRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	p, err := h.svc.ConfirmCashCollection(user, req)
+	if errors.Is(err, service.ErrCashNotFound) {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+	if errors.Is(err, service.ErrInvalidTransition) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "cash on delivery collected", p)
+}
+
+func (h *TransactionHandler) PaymentWebhook(ctx *fiber.Ctx) error {
+
+	err := h.svc.HandlePaymentWebhook(ctx.Body(), ctx.Get("Stripe-Signature"))
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		return rest.BadRequestError(ctx, "invalid webhook signature", err)
+	}
+	if err != nil {
+		//non 2xx makes the gateway redeliver the event
+		return rest.ErrorMessage(ctx, http.StatusInternalServerError, err)
+	}
+
+	return rest.SuccessResponse(ctx, "event received", nil)
 }
 
 func (h *TransactionHandler) GetOrders(ctx *fiber.Ctx) error {
-	return nil
+	//Getting current seller
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	orders, err := h.svc.GetOrders(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller orders list", orders)
 }
+
 func (h *TransactionHandler) GetOrderDetails(ctx *fiber.Ctx) error {
-	return nil
+	//Extract fulfilment id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid order id", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	order, err := h.svc.GetOrderDetails(user, uint(id))
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller order details", order)
+}
+
+func (h *TransactionHandler) GetInvoice(ctx *fiber.Ctx) error {
+	//Extract fulfilment id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid order id", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	file, err := h.svc.GetInvoice(user, uint(id), ctx.Query("format"))
+	if errors.Is(err, service.ErrInvoiceFormat) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if errors.Is(err, service.ErrInvoiceNotReady) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.FileResponse(ctx, file.Number+"."+file.Extension, file.ContentType, file.Data)
+}
+
+func (h *TransactionHandler) UpdateOrderStatus(ctx *fiber.Ctx) error {
+	//Extract fulfilment id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid order id", err)
+	}
+
+	req := dto.UpdateOrderStatusRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	fulfilment, err := h.svc.UpdateOrderStatus(user, uint(id), req)
+	if errors.Is(err, service.ErrInvalidTransition) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "order status updated", fulfilment)
+}
+
+func (h *TransactionHandler) CancelFulfilment(ctx *fiber.Ctx) error {
+	//Extract fulfilment id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid order id", err)
+	}
+
+	req := dto.CancelOrderRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	cancelled, err := h.svc.CancelFulfilment(user, uint(id), req)
+	if errors.Is(err, service.ErrInvalidTransition) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "order items cancelled", cancelled)
+}
+
+func (h *TransactionHandler) GetReturns(ctx *fiber.Ctx) error {
+	//Getting current seller
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	returns, err := h.svc.GetReturns(user, ctx.Query("status"))
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller return requests", returns)
+}
+
+func (h *TransactionHandler) UpdateReturnStatus(ctx *fiber.Ctx) error {
+	//Extract return id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid return id", err)
+	}
+
+	req := dto.UpdateReturnStatusRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	request, err := h.svc.UpdateReturnStatus(user, uint(id), req)
+	if errors.Is(err, service.ErrInvalidTransition) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "return status updated", request)
+}
+
+func (h *TransactionHandler) CreateShipment(ctx *fiber.Ctx) error {
+
+	req := dto.CreateShipmentRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	shipment, err := h.svc.CreateShipment(user, req)
+	if errors.Is(err, service.ErrInvalidTransition) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipment created", shipment)
+}
+
+func (h *TransactionHandler) GetShipment(ctx *fiber.Ctx) error {
+	//Extract shipment id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid shipment id", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	shipment, err := h.svc.GetShipment(user, uint(id))
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipment details", shipment)
 }
@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"errors"
 	"go-ecommerce-app/internal/api/rest"
 	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/repository"
@@ -21,10 +22,17 @@ func SetupUserRoutes(rh *RestHandler) {
 	app := rh.App
 
 	svc := service.UserService{
-		Repo:   repository.NewUserRepository(rh.DB),
-		CRepo:  repository.NewCatalogRepository(rh.DB),
-		Auth:   rh.Auth,
-		Config: rh.Config,
+		Repo:     repository.NewUserRepository(rh.DB),
+		CRepo:    repository.NewCatalogRepository(rh.DB),
+		TRepo:    repository.NewTransactionRepo(rh.DB),
+		SRepo:    repository.NewShipmentRepository(rh.DB),
+		Tx:       repository.NewTxManager(rh.DB),
+		Auth:     rh.Auth,
+		Config:   rh.Config,
+		Payment:  rh.Pc,
+		Carriers: rh.Carriers,
+		Tax:      rh.Tax,
+		Rates:    rh.Rates,
 	}
 
 	userHandler := UserHandler{
@@ -47,10 +55,25 @@ func SetupUserRoutes(rh *RestHandler) {
 
 	pvtRoutes.Post("/cart", userHandler.AddToCart)
 	pvtRoutes.Get("/cart", userHandler.GetCart)
-
-	pvtRoutes.Post("/order", userHandler.CreateOrder)
+	pvtRoutes.Post("/cart/shipping", userHandler.SelectShipping)
+	pvtRoutes.Post("/cart/coupon", userHandler.ApplyCoupon)
+	pvtRoutes.Delete("/cart/coupon", userHandler.RemoveCoupon)
+	pvtRoutes.Post("/cart/wallet", userHandler.UseWallet)
+	pvtRoutes.Delete("/cart/wallet", userHandler.StopUsingWallet)
+	pvtRoutes.Get("/wallet", userHandler.GetWallet)
+	pvtRoutes.Post("/cart/giftcards", userHandler.RedeemGiftCard)
+	pvtRoutes.Delete("/cart/giftcards/:id", userHandler.RemoveGiftCard)
+	pvtRoutes.Get("/giftcards", userHandler.GetGiftCards)
+	pvtRoutes.Post("/giftcards/balance", userHandler.CheckGiftCard)
+
+	pvtRoutes.Post("/order", rh.Idempotent, userHandler.CreateOrder)
 	pvtRoutes.Get("/order", userHandler.Getorders)
 	pvtRoutes.Get("/order/:id", userHandler.GetOrder)
+	pvtRoutes.Patch("/order/:id/status", userHandler.UpdateOrderStatus)
+	pvtRoutes.Post("/order/:id/cancel", rh.Idempotent, userHandler.CancelOrder)
+	pvtRoutes.Post("/order/:id/returns", userHandler.RequestReturn)
+	pvtRoutes.Get("/order/:id/invoice", userHandler.GetInvoice)
+	pvtRoutes.Get("/returns", userHandler.GetReturns)
 
 	pvtRoutes.Post("/become-seller", userHandler.BecomeSeller)
 
@@ -183,7 +206,11 @@ func (h *UserHandler) UpdateProfile(ctx *fiber.Ctx) error {
 		return rest.BadRequestError(ctx, "invalid input parameters", err)
 	}
 
-	if err := h.svc.UpdateProfile(user.ID, &req); err != nil {
+	err := h.svc.UpdateProfile(user.ID, &req)
+	if errors.Is(err, service.ErrCurrency) {
+		return rest.BadRequestError(ctx, "unsupported currency", err)
+	}
+	if err != nil {
 		return rest.ErrorMessage(ctx, http.StatusInternalServerError, err)
 	}
 
@@ -201,6 +228,9 @@ func (h *UserHandler) AddToCart(ctx *fiber.Ctx) error {
 	user := h.svc.Auth.GetCurrentUser(ctx)
 
 	cartItems, err := h.svc.CreateCart(req, user)
+	if errors.Is(err, service.ErrVariantRequired) {
+		return rest.BadRequestError(ctx, "invalid variant", err)
+	}
 	if err != nil {
 		return rest.InternalError(ctx, err)
 	}
@@ -226,7 +256,28 @@ func (h *UserHandler) CreateOrder(ctx *fiber.Ctx) error {
 	//Getting current user
 	user := h.svc.Auth.GetCurrentUser(ctx)
 
-	orderRef, err := h.svc.CreateOrder(user)
+	req := dto.CreateOrderRequest{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return rest.BadRequestError(ctx, "invalid request parameters", err)
+		}
+	}
+
+	orderRef, err := h.svc.CreateOrder(user, req)
+
+	var changed *service.CartChangedError
+	if errors.As(err, &changed) {
+		return ctx.Status(http.StatusConflict).JSON(&fiber.Map{
+			"message": changed.Error(),
+			"data":    changed.Cart,
+		})
+	}
+	if errors.Is(err, repository.ErrInsufficientStock) || errors.Is(err, service.ErrCartChanged) || errors.Is(err, service.ErrShippingUnavailable) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if errors.Is(err, service.ErrPaymentMethod) || errors.Is(err, service.ErrCodGiftCards) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
 	if err != nil {
 		return rest.InternalError(ctx, err)
 	}
@@ -260,6 +311,285 @@ func (h *UserHandler) GetOrder(ctx *fiber.Ctx) error {
 	return rest.SuccessResponse(ctx, "order fetched successfully", order)
 }
 
+func (h *UserHandler) UpdateOrderStatus(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	orderId, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid order id", err)
+	}
+
+	req := dto.UpdateOrderStatusRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	order, err := h.svc.UpdateOrderStatus(user, uint(orderId), req)
+	if errors.Is(err, service.ErrInvalidTransition) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "order status updated", order)
+}
+
+func (h *UserHandler) SelectShipping(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	req := dto.SelectShippingRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	cart, err := h.svc.SelectShipping(user, req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipping method selected", cart)
+}
+
+func (h *UserHandler) GetInvoice(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	orderId, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid order id", err)
+	}
+
+	file, err := h.svc.GetInvoice(user, uint(orderId), ctx.Query("format"))
+	if errors.Is(err, service.ErrInvoiceFormat) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if errors.Is(err, service.ErrInvoiceNotReady) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.FileResponse(ctx, file.Number+"."+file.Extension, file.ContentType, file.Data)
+}
+
+func (h *UserHandler) ApplyCoupon(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	req := dto.ApplyCouponRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	cart, err := h.svc.ApplyCoupon(user, req)
+	if errors.Is(err, service.ErrCouponNotFound) {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+	if errors.Is(err, service.ErrCouponInvalid) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "coupon applied", cart)
+}
+
+func (h *UserHandler) RemoveCoupon(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	cart, err := h.svc.RemoveCoupon(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "coupon removed", cart)
+}
+
+func (h *UserHandler) UseWallet(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	req := dto.UseWalletRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	cart, err := h.svc.UseWallet(user, req)
+	if errors.Is(err, service.ErrWalletBalance) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "paying with wallet", cart)
+}
+
+func (h *UserHandler) StopUsingWallet(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	cart, err := h.svc.StopUsingWallet(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "wallet removed from cart", cart)
+}
+
+func (h *UserHandler) GetWallet(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	wallet, err := h.svc.GetWallet(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "wallet", wallet)
+}
+
+func (h *UserHandler) RedeemGiftCard(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	req := dto.RedeemGiftCardRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	cart, err := h.svc.RedeemGiftCard(user, req)
+	if errors.Is(err, service.ErrGiftCardNotFound) {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+	if errors.Is(err, service.ErrGiftCardInvalid) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "gift card redeemed", cart)
+}
+
+func (h *UserHandler) RemoveGiftCard(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	//Extract gift card id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid gift card id", err)
+	}
+
+	cart, err := h.svc.RemoveGiftCard(user, uint(id))
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "gift card removed from cart", cart)
+}
+
+func (h *UserHandler) GetGiftCards(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	cards, err := h.svc.GetGiftCards(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "gift cards", cards)
+}
+
+func (h *UserHandler) CheckGiftCard(ctx *fiber.Ctx) error {
+
+	req := dto.RedeemGiftCardRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	balance, err := h.svc.CheckGiftCard(req)
+	if errors.Is(err, service.ErrGiftCardNotFound) {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "gift card balance", balance)
+}
+
+func (h *UserHandler) CancelOrder(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	orderId, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid order id", err)
+	}
+
+	req := dto.CancelOrderRequest{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return rest.BadRequestError(ctx, "invalid request parameters", err)
+		}
+	}
+
+	cancelled, err := h.svc.CancelOrder(user, uint(orderId), req)
+	if errors.Is(err, service.ErrInvalidTransition) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "order cancelled", cancelled)
+}
+
+func (h *UserHandler) RequestReturn(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	orderId, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid order id", err)
+	}
+
+	req := dto.CreateReturnRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	request, err := h.svc.RequestReturn(user, uint(orderId), req)
+	if errors.Is(err, service.ErrInvalidTransition) {
+		return rest.ErrorMessage(ctx, http.StatusConflict, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "return requested", request)
+}
+
+func (h *UserHandler) GetReturns(ctx *fiber.Ctx) error {
+	//Getting current user
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	returns, err := h.svc.GetReturns(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "return requests", returns)
+}
+
 func (h *UserHandler) BecomeSeller(ctx *fiber.Ctx) error {
 
 	user := h.svc.Auth.GetCurrentUser(ctx)
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WalletHandler struct {
+	svc service.WalletService
+}
+
+func SetupWalletRoutes(rh *RestHandler) {
+
+	app := rh.App
+
+	svc := service.WalletService{
+		Tx:     repository.NewTxManager(rh.DB),
+		Auth:   rh.Auth,
+		Config: rh.Config,
+	}
+
+	handler := WalletHandler{
+		svc: svc,
+	}
+
+	//buyers see their wallet under /users/wallet
+	adminRoutes := app.Group("/admin", rh.Auth.AuthorizeAdmin)
+	adminRoutes.Post("/users/:id/wallet", handler.AdjustWallet)
+
+}
+
+func (h *WalletHandler) AdjustWallet(ctx *fiber.Ctx) error {
+	//Extract user id from URL params
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return rest.BadRequestError(ctx, "invalid user id", err)
+	}
+
+	req := dto.WalletAdjustmentRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "invalid request parameters", err)
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	txn, err := h.svc.AdjustWallet(user, id, req)
+	if errors.Is(err, service.ErrWalletBalance) {
+		return rest.ErrorMessage(ctx, http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "wallet adjusted", txn)
+}
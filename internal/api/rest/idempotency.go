@@ -0,0 +1,160 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/repository"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+var (
+	ErrIdempotencyKeyLength   = errors.New("Idempotency-Key should be at most 255 characters")
+	ErrIdempotencyKeyReused   = errors.New("Idempotency-Key was already used for a different request")
+	ErrIdempotencyKeyInFlight = errors.New("a request with this Idempotency-Key is still in progress")
+)
+
+// Idempotency makes a route safe to retry. The first response to a request
+// carrying an Idempotency-Key header is stored for the user and replayed
+// for identical retries until the key expires after ttl. A retry arriving
+// while the first request still runs is rejected. Requests without the
+// header go through as usual. Must come after the auth middleware.
+func Idempotency(repo repository.IdempotencyRepository, ttl time.Duration) fiber.Handler {
+
+	return func(ctx *fiber.Ctx) error {
+
+		key := ctx.Get(IdempotencyKeyHeader)
+		if len(key) < 1 {
+			return ctx.Next()
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			return ErrorMessage(ctx, http.StatusBadRequest, ErrIdempotencyKeyLength)
+		}
+
+		user, ok := ctx.Locals("user").(domain.User)
+		if !ok {
+			return ctx.Next()
+		}
+
+		hash := sha256.New()
+		hash.Write([]byte(ctx.Method() + " " + ctx.OriginalURL() + "\n"))
+		hash.Write(ctx.Body())
+
+		record := &domain.IdempotencyKey{
+			UserId:      user.ID,
+			Key:         key,
+			Method:      ctx.Method(),
+			Path:        ctx.OriginalURL(),
+			RequestHash: hex.EncodeToString(hash.Sum(nil)),
+			Status:      domain.IDEMPOTENCY_IN_PROGRESS,
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+
+		reserved, err := reserveKey(repo, record)
+		if err != nil {
+			return InternalError(ctx, err)
+		}
+
+		if !reserved {
+			existing, err := repo.FindKey(user.ID, key)
+			if err != nil {
+				return InternalError(ctx, err)
+			}
+
+			//the first request just failed and released the key
+			if existing == nil {
+				return ErrorMessage(ctx, http.StatusConflict, ErrIdempotencyKeyInFlight)
+			}
+
+			if existing.RequestHash != record.RequestHash {
+				return ErrorMessage(ctx, http.StatusUnprocessableEntity, ErrIdempotencyKeyReused)
+			}
+
+			if existing.Status != domain.IDEMPOTENCY_COMPLETED {
+				return ErrorMessage(ctx, http.StatusConflict, ErrIdempotencyKeyInFlight)
+			}
+
+			ctx.Set(IdempotentReplayedHeader, "true")
+			ctx.Set(fiber.HeaderContentType, existing.ContentType)
+			return ctx.Status(existing.ResponseStatus).Send(existing.ResponseBody)
+		}
+
+		if err := ctx.Next(); err != nil {
+			if err := repo.DeleteKey(record.ID); err != nil {
+				log.Printf("releasing idempotency key %d failed %v", record.ID, err)
+			}
+			return err
+		}
+
+		//server errors and conflicts may clear up, so the client can retry
+		//them with the same key
+		status := ctx.Response().StatusCode()
+		if status >= http.StatusInternalServerError || status == http.StatusConflict {
+			if err := repo.DeleteKey(record.ID); err != nil {
+				log.Printf("releasing idempotency key %d failed %v", record.ID, err)
+			}
+			return nil
+		}
+
+		record.ResponseStatus = status
+		record.ContentType = string(ctx.Response().Header.ContentType())
+		record.ResponseBody = append([]byte(nil), ctx.Response().Body()...)
+		if err := repo.CompleteKey(record); err != nil {
+			//the response is sent anyway, retries are told the request is
+			//in progress until the key expires
+			log.Printf("storing response for idempotency key %d failed %v", record.ID, err)
+		}
+
+		return nil
+	}
+}
+
+// reserveKey claims the key for the request, taking over a key that has
+// expired
+func reserveKey(repo repository.IdempotencyRepository, record *domain.IdempotencyKey) (bool, error) {
+
+	reserved, err := repo.ReserveKey(record)
+	if err != nil || reserved {
+		return reserved, err
+	}
+
+	existing, err := repo.FindKey(record.UserId, record.Key)
+	if err != nil {
+		return false, err
+	}
+
+	if existing == nil || existing.ExpiresAt.After(time.Now()) {
+		return false, nil
+	}
+
+	if err := repo.DeleteKey(existing.ID); err != nil {
+		return false, err
+	}
+
+	return repo.ReserveKey(record)
+}
+
+// PurgeIdempotencyKeys deletes expired keys every interval
+func PurgeIdempotencyKeys(repo repository.IdempotencyRepository, interval time.Duration) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := repo.DeleteExpiredKeys(time.Now()); err != nil {
+			log.Printf("purging idempotency keys failed %v", err)
+		}
+	}
+}
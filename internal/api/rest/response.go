@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
@@ -27,3 +28,10 @@ func SuccessResponse(ctx *fiber.Ctx, message string, data interface{}) error {
 		"data":    data,
 	})
 }
+
+// FileResponse sends data as a download named filename
+func FileResponse(ctx *fiber.Ctx, filename string, contentType string, data []byte) error {
+	ctx.Set(fiber.HeaderContentType, contentType)
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return ctx.Status(http.StatusOK).Send(data)
+}
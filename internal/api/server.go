@@ -2,10 +2,18 @@ package api
 
 import (
 	"go-ecommerce-app/configs"
+	restapi "go-ecommerce-app/internal/api/rest"
 	rest "go-ecommerce-app/internal/api/rest/handler"
 	"go-ecommerce-app/internal/domain"
 	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	payment "go-ecommerce-app/pkg/Payment"
+	"go-ecommerce-app/pkg/carrier"
+	"go-ecommerce-app/pkg/fx"
+	"go-ecommerce-app/pkg/payout"
+	"go-ecommerce-app/pkg/tax"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/driver/postgres"
@@ -31,12 +39,51 @@ func StartServer(config configs.AppConfig) {
 		&domain.Address{},
 		&domain.Order{},
 		&domain.OrderItem{},
+		&domain.OrderItemTax{},
+		&domain.Fulfilment{},
+		&domain.OrderHistory{},
 		&domain.Payment{},
+		&domain.PaymentEvent{},
+		&domain.Refund{},
+		&domain.RefundItem{},
+		&domain.ReturnRequest{},
+		&domain.ShippingZone{},
+		&domain.ShippingMethod{},
+		&domain.ShippingSelection{},
+		&domain.Shipment{},
+		&domain.ShipmentItem{},
+		&domain.ShipmentEvent{},
+		&domain.Coupon{},
+		&domain.CouponScope{},
+		&domain.CouponRedemption{},
+		&domain.CartCoupon{},
+		&domain.Promotion{},
+		&domain.PromotionProduct{},
+		&domain.Invoice{},
+		&domain.LedgerEntry{},
+		&domain.LedgerPosting{},
+		&domain.Payout{},
+		&domain.CommissionRule{},
+		&domain.Wallet{},
+		&domain.WalletTransaction{},
+		&domain.CartWallet{},
+		&domain.GiftCard{},
+		&domain.GiftCardTransaction{},
+		&domain.CartGiftCard{},
+		&domain.IdempotencyKey{},
+		&domain.ExchangeRate{},
+		&domain.ProductOption{},
+		&domain.Variant{},
+		&domain.VariantOption{},
 	)
 	if err != nil {
 		log.Fatalf("Migration failed due to %s", err)
 	}
 
+	if err := repository.DataMigrations(db, config.Currency); err != nil {
+		log.Fatalf("Data migration failed due to %s", err)
+	}
+
 	log.Println("Migration was successfull")
 
 	// //cors configuration
@@ -52,15 +99,76 @@ func StartServer(config configs.AppConfig) {
 
 	// log.Printf("Config DSN %v", config.Dsn)
 
+	//payment gateway
+	pc := payment.NewPaymentClient(config)
+	if config.PaymentProvider == "fake" {
+		log.Println("using in-memory fake payment gateway")
+		pc = payment.NewFakePaymentClient(config)
+	}
+
+	//shipping carriers
+	carriers, err := carrier.NewCarriers(config)
+	if err != nil {
+		log.Fatalf("carrier setup failed %v", err)
+	}
+
+	//tax rate table
+	rules, err := tax.LoadRules(config.TaxRules)
+	if err != nil {
+		log.Fatalf("tax rules setup failed %v", err)
+	}
+	log.Printf("using tax rules version %s", rules.Version)
+
+	//exchange rates, orders are paid in the configured currency
+	rates, err := fx.NewSource(config)
+	if err != nil {
+		log.Fatalf("exchange rate setup failed %v", err)
+	}
+	table, err := rates.Latest()
+	if err != nil {
+		log.Fatalf("exchange rates unavailable %v", err)
+	}
+	if !table.Supports(config.Currency) {
+		log.Fatalf("exchange rates don't cover the currency %s", config.Currency)
+	}
+	log.Printf("using %s exchange rates as of %s", table.Source, table.AsOf.Format("2006-01-02"))
+
+	//seller payouts
+	payouts, err := payout.NewPayoutClient(config)
+	if err != nil {
+		log.Fatalf("payout provider setup failed %v", err)
+	}
+	if config.PayoutProvider == "fake" {
+		log.Println("using in-memory fake payout provider")
+	}
+
+	//retried requests replay the first response
+	idempotencyKeys := repository.NewIdempotencyRepository(db)
+
 	rh := &rest.RestHandler{
-		App:    app,
-		DB:     db,
-		Auth:   auth,
-		Config: config,
+		App:        app,
+		DB:         db,
+		Auth:       auth,
+		Config:     config,
+		Pc:         pc,
+		Carriers:   carriers,
+		Tax:        rules,
+		Rates:      rates,
+		Payouts:    payouts,
+		Idempotent: restapi.Idempotency(idempotencyKeys, config.IdempotencyTTL),
 	}
 
 	SetupRoutes(rh)
 
+	payoutService := rest.InitializePayoutService(db, auth, config, payouts)
+	go payoutService.RunSchedule(config.PayoutInterval)
+
+	//orders waiting on a bank transfer hold stock, overdue ones are cancelled
+	transactionService := rest.InitializeTransactionService(db, auth, config, pc, carriers, rules, rates)
+	go transactionService.RunTransferExpiry(time.Hour)
+
+	go restapi.PurgeIdempotencyKeys(idempotencyKeys, time.Hour)
+
 	app.Listen(config.ServerPort)
 }
 
@@ -68,7 +176,21 @@ func SetupRoutes(rh *rest.RestHandler) {
 	//user handler
 	rest.SetupUserRoutes(rh)
 	//transactions
+	rest.SetupTransactionRoutes(rh)
 	//catalog
 	rest.SetupCatalogRoutes(rh)
+	//shipping
+	rest.SetupShippingRoutes(rh)
+	//coupons
+	rest.SetupCouponRoutes(rh)
+	//promotions
+	rest.SetupPromotionRoutes(rh)
+	//seller balances and payouts
+	rest.SetupPayoutRoutes(rh)
+	//marketplace commission
+	rest.SetupCommissionRoutes(rh)
+	//store credit
+	rest.SetupWalletRoutes(rh)
+	rest.SetupGiftCardRoutes(rh)
 
 }
@@ -2,15 +2,45 @@ package domain
 
 import "time"
 
+// Order lifecycle states
+const (
+	ORDER_PENDING_PAYMENT = "pending_payment"
+	ORDER_PAID            = "paid"
+	//cash on delivery, goes ahead unpaid and is paid when delivered
+	ORDER_CONFIRMED  = "confirmed"
+	ORDER_PROCESSING = "processing"
+	ORDER_SHIPPED    = "shipped"
+	ORDER_DELIVERED  = "delivered"
+	ORDER_CANCELLED  = "cancelled"
+	ORDER_REFUNDED   = "refunded"
+)
+
 type Order struct {
-	ID             uint        `json:"id" gorm:"PrimaryKey"`
-	UserId         uint        `json:"userid"`
-	Status         string      `json:"status"`
-	Amount         float64     `json:"amount"`
-	PaymentId      string      `json:"paymentid"`
-	TransactionId  string      `json:"transactionid"`
-	OrderRefNumber int         `json:"orderrefnumber"`
-	Items          []OrderItem `json:"items"`
-	CreatedAt      time.Time   `gorm:"default:current_timestamp"`
-	UpdatedAt      time.Time   `gorm:"default:current_timestamp"`
+	ID               uint            `json:"id" gorm:"PrimaryKey"`
+	UserId           uint            `json:"userid"`
+	Status           string          `json:"status" gorm:"default:pending_payment"`
+	Subtotal         Money           `json:"subtotal" gorm:"embedded;embeddedPrefix:subtotal_"`
+	Shipping         Money           `json:"shipping" gorm:"embedded;embeddedPrefix:shipping_"`
+	CouponCode       string          `json:"couponcode"`
+	Discount         Money           `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	Tax              Money           `json:"tax" gorm:"embedded;embeddedPrefix:tax_"` //inclusive tax is already part of the subtotal
+	Amount           Money           `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	WalletAmount     Money           `json:"walletamount" gorm:"embedded;embeddedPrefix:wallet_"` //paid from the wallet, the payment covers the rest
+	WalletRefunded   Money           `json:"walletrefunded" gorm:"embedded;embeddedPrefix:walletrefunded_"`
+	GiftCardAmount   Money           `json:"giftcardamount" gorm:"embedded;embeddedPrefix:giftcard_"` //paid with gift cards
+	GiftCardRefunded Money           `json:"giftcardrefunded" gorm:"embedded;embeddedPrefix:giftcardrefunded_"`
+	PaymentId        string          `json:"paymentid"`
+	TransactionId    string          `json:"transactionid"`
+	OrderRefNumber   int             `json:"orderrefnumber"`
+	DisplayCurrency  string          `json:"displaycurrency"`
+	DisplayTotal     Money           `json:"displaytotal" gorm:"embedded;embeddedPrefix:displaytotal_"` //what the buyer was shown, Amount is charged
+	ExchangeRates    []ExchangeRate  `json:"exchangerates"`
+	Items            []OrderItem     `json:"items"`
+	Fulfilments      []Fulfilment    `json:"fulfilments"`
+	History          []OrderHistory  `json:"history"`
+	Shipments        []Shipment      `json:"shipments"`
+	Refunds          []Refund        `json:"refunds"`
+	Returns          []ReturnRequest `json:"returns"`
+	CreatedAt        time.Time       `gorm:"default:current_timestamp"`
+	UpdatedAt        time.Time       `gorm:"default:current_timestamp"`
 }
@@ -2,15 +2,33 @@ package domain
 
 import "time"
 
+const (
+	ITEM_ACTIVE    = "active"
+	ITEM_CANCELLED = "cancelled"
+	//every unit was returned and refunded
+	ITEM_RETURNED = "returned"
+)
+
 type OrderItem struct {
-	ID        int       `json:"id" gorm:"PrimaryKey"`
-	ProductId int       `json:"productid"`
-	OrderId   int       `json:"orderid"`
-	Name      string    `json:"name"`
-	ImageUrl  string    `json:"imageurl"`
-	Price     float64   `json:"price"`
-	Qty       int       `json:"qty"`
-	SellerId  int       `json:"sellerid"`
-	CreatedAt time.Time `json:"createdAt" gorm:"default:current_timestamp"`
-	UpdatedAt time.Time `json:"updatedAt" gorm:"default:current_timestamp"`
+	ID             int            `json:"id" gorm:"PrimaryKey"`
+	ProductId      int            `json:"productid"`
+	VariantId      uint           `json:"variantid"`
+	SKU            string         `json:"sku"`
+	OrderId        int            `json:"orderid"`
+	Name           string         `json:"name"`
+	ImageUrl       string         `json:"imageurl"`
+	Price          Money          `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	ListPrice      Money          `json:"listprice" gorm:"embedded;embeddedPrefix:listprice_"` //unit price in the seller's currency
+	Qty            int            `json:"qty"`
+	Discount       Money          `json:"discount" gorm:"embedded;embeddedPrefix:discount_"` //share of the order discount for the whole line
+	Commission     Money          `json:"-" gorm:"embedded;embeddedPrefix:commission_"`      //platform share of the line
+	CommissionRate int64          `json:"-"`                                                 //basis points, fixed when the order is placed
+	SellerId       int            `json:"sellerid"`
+	GiftCard       bool           `json:"giftcard"`
+	FulfilmentId   uint           `json:"fulfilmentid" gorm:"index"`
+	Status         string         `json:"status" gorm:"default:active"`
+	CancelReason   string         `json:"cancelreason"`
+	Taxes          []OrderItemTax `json:"taxes"`
+	CreatedAt      time.Time      `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt      time.Time      `json:"updatedAt" gorm:"default:current_timestamp"`
 }
@@ -2,14 +2,43 @@ package domain
 
 import "time"
 
+const (
+	PAYMENT_INITIAL = "initial"
+	PAYMENT_SUCCESS = "success"
+	PAYMENT_FAILED  = "failed"
+	//unpaid payment dropped because its order was cancelled
+	PAYMENT_CANCELLED = "cancelled"
+
+	PAYMENT_REFUNDED           = "refunded"
+	PAYMENT_PARTIALLY_REFUNDED = "partially_refunded"
+)
+
+// Capture methods, how the buyer pays for an order
+const (
+	CAPTURE_CARD = "card"
+	//paid to the courier, collected once the order is delivered
+	CAPTURE_COD = "cod"
+	//paid into the platform bank account, reconciled by an admin
+	CAPTURE_BANK_TRANSFER = "bank_transfer"
+)
+
+// Deferred reports whether the payment is settled outside the gateway,
+// after the order is placed
+func (p *Payment) Deferred() bool {
+	return p.CaptureMethod == CAPTURE_COD || p.CaptureMethod == CAPTURE_BANK_TRANSFER
+}
+
 type Payment struct {
 	ID            uint      `json:"id" gorm:"PrimaryKey"`
 	UserId        uint      `json:"userid"`
+	OrderId       uint      `json:"orderid" gorm:"index"`
 	CaptureMethod string    `json:"capturemethod"`
-	Amount        float64   `json:"amount"`
-	TransactionId uint      `json:"transactionid"`
+	Amount        Money     `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Refunded      Money     `json:"refunded" gorm:"embedded;embeddedPrefix:refunded_"`
+	TransactionId string    `json:"transactionid"`
 	CustomerId    string    `json:"customerid"`
-	PaymentId     string    `json:"paymentid"`
+	PaymentId     string    `json:"paymentid" gorm:"index"`
+	ClientSecret  string    `json:"-"`
 	Status        string    `json:"status"`
 	Response      string    `json:"response"`
 	CreatedAt     time.Time `json:"createdAt" gorm:"default:current_timestamp"`
@@ -2,15 +2,42 @@ package domain
 
 import "time"
 
+// Product types
+const (
+	PRODUCT_STANDARD = "standard"
+	//sold by the platform, a gift card is issued for every unit once paid
+	PRODUCT_GIFT_CARD = "giftcard"
+)
+
 type Product struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"index;"`
-	Description string    `json:"description"`
-	CategoryID  uint      `json:"categoryid"`
-	ImageUrl    string    `json:"imageurl"`
-	Price       float64   `json:"price"`
-	UserId      int       `json:"userid"`
-	Stock       uint      `json:"stock"`
-	CreatedAt   time.Time `json:"createdAt" gorm:"default:current_timestamp"`
-	UpdatedAt   time.Time `json:"updatedAt" gorm:"default:current_timestamp"`
+	ID           uint            `json:"id" gorm:"primaryKey"`
+	Name         string          `json:"name" gorm:"index;"`
+	Description  string          `json:"description"`
+	CategoryID   uint            `json:"categoryid"`
+	ImageUrl     string          `json:"imageurl"`
+	Price        Money           `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	UserId       int             `json:"userid"`
+	Type         string          `json:"type" gorm:"default:standard"`
+	Stock        uint            `json:"stock"`  //total of the variants when there are any
+	Weight       uint            `json:"weight"` //grams
+	Length       uint            `json:"length"` //millimetres
+	Width        uint            `json:"width"`
+	Height       uint            `json:"height"`
+	SalePrice    *Money          `json:"saleprice" gorm:"-"`    //while a sale runs
+	ListPrice    *Money          `json:"listprice" gorm:"-"`    //the seller's price, once Price is converted for checkout
+	DisplayPrice *Money          `json:"displayprice" gorm:"-"` //selling price in the buyer's currency
+	Promotions   []*Promotion    `json:"promotions" gorm:"-"`
+	Options      []ProductOption `json:"options"`
+	Variants     []Variant       `json:"variants"`
+	CreatedAt    time.Time       `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt    time.Time       `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+// SellingPrice is what the product sells for right now, the sale price
+// while a sale runs.
+func (p *Product) SellingPrice() Money {
+	if p.SalePrice != nil {
+		return *p.SalePrice
+	}
+	return p.Price
 }
@@ -6,9 +6,11 @@ type Cart struct {
 	ID        int       `json:"id" gorm:"PrimaryKey"`
 	UserId    int       `json:"userid"`
 	ProductId int       `json:"productid"`
+	VariantId uint      `json:"variantid"`
+	SKU       string    `json:"sku"`
 	Name      string    `json:"name"`
 	ImageUrl  string    `json:"imageurl"`
-	Price     float64   `json:"price"`
+	Price     Money     `json:"price" gorm:"embedded;embeddedPrefix:price_"`
 	Qty       int       `json:"qty"`
 	SellerId  int       `json:"sellerid"`
 	CreatedAt time.Time `gorm:"default:current_timestamp"`
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// Commission rule scopes. The most specific active rule matching a sale
+// wins: its category first, then the seller's tier, then the global rule.
+const (
+	COMMISSION_GLOBAL   = "global"
+	COMMISSION_CATEGORY = "category"
+	COMMISSION_TIER     = "tier"
+)
+
+// CommissionRule sets the platform commission, in basis points of the
+// discounted item price, for the sales it covers.
+type CommissionRule struct {
+	ID         uint      `json:"id" gorm:"PrimaryKey"`
+	Scope      string    `json:"scope" gorm:"uniqueIndex:idx_commission_rule"`
+	CategoryId uint      `json:"categoryid" gorm:"uniqueIndex:idx_commission_rule"`
+	Tier       string    `json:"tier" gorm:"uniqueIndex:idx_commission_rule"`
+	Rate       int64     `json:"rate"` //basis points
+	Active     bool      `json:"active"`
+	CreatedBy  int       `json:"createdby"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt  time.Time `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+// CommissionSummary is the commission earned from one seller in one
+// currency, Reversed is what refunds gave back.
+type CommissionSummary struct {
+	SellerId int   `json:"sellerid,omitempty"` //zero on totals
+	Earned   Money `json:"earned"`
+	Reversed Money `json:"reversed"`
+	Net      Money `json:"net"`
+}
@@ -0,0 +1,68 @@
+package domain
+
+import "time"
+
+// Coupon discount types
+const (
+	COUPON_FIXED   = "fixed"
+	COUPON_PERCENT = "percent"
+)
+
+// Coupon scope kinds
+const (
+	SCOPE_CATEGORY = "category"
+	SCOPE_PRODUCT  = "product"
+	SCOPE_SELLER   = "seller"
+)
+
+// Coupon is a discount code buyers apply to their cart. Fixed coupons take
+// Amount off, percent coupons take Percent basis points off the eligible
+// items. Without scopes every item is eligible, otherwise an item has to
+// match one target of every scope kind the coupon has.
+//
+// Zero MaxUses and MaxUsesPerUser mean unlimited.
+type Coupon struct {
+	ID             uint          `json:"id" gorm:"PrimaryKey"`
+	Code           string        `json:"code" gorm:"uniqueIndex;not null"`
+	Description    string        `json:"description"`
+	Type           string        `json:"type"`
+	Amount         Money         `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Percent        int64         `json:"percent"` //basis points
+	MinSpend       Money         `json:"minspend" gorm:"embedded;embeddedPrefix:minspend_"`
+	MaxUses        int           `json:"maxuses"`
+	MaxUsesPerUser int           `json:"maxusesperuser"`
+	Used           int           `json:"used"`
+	StartsAt       *time.Time    `json:"startsat"`
+	EndsAt         *time.Time    `json:"endsat"`
+	Active         bool          `json:"active"`
+	CreatedBy      int           `json:"createdby" gorm:"index"`
+	Scopes         []CouponScope `json:"scopes"`
+	CreatedAt      time.Time     `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt      time.Time     `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+type CouponScope struct {
+	ID       uint   `json:"id" gorm:"PrimaryKey"`
+	CouponId uint   `json:"couponid" gorm:"index"`
+	Kind     string `json:"kind"`
+	TargetId uint   `json:"targetid"`
+}
+
+// CouponRedemption is one use of a coupon by an order, released again when
+// the order is cancelled.
+type CouponRedemption struct {
+	ID        uint      `json:"id" gorm:"PrimaryKey"`
+	CouponId  uint      `json:"couponid" gorm:"index"`
+	UserId    int       `json:"userid" gorm:"index"`
+	OrderId   uint      `json:"orderid" gorm:"uniqueIndex"`
+	Discount  Money     `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	CreatedAt time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+}
+
+// CartCoupon is the coupon a buyer applied to their cart, kept until
+// checkout.
+type CartCoupon struct {
+	ID       uint `json:"id" gorm:"PrimaryKey"`
+	UserId   int  `json:"userid" gorm:"uniqueIndex"`
+	CouponId uint `json:"couponid"`
+}
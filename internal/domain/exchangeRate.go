@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// ExchangeRate is a rate an order was priced with: the price of one unit
+// of FromCurrency in ToCurrency. Orders keep the rates in effect when they
+// were placed, their amounts and refunds never go through a newer rate.
+type ExchangeRate struct {
+	ID           uint      `json:"id" gorm:"PrimaryKey"`
+	OrderId      uint      `json:"orderid" gorm:"index"`
+	FromCurrency string    `json:"from" gorm:"size:3"`
+	ToCurrency   string    `json:"to" gorm:"size:3"`
+	Rate         string    `json:"rate"` //decimal, the exact rate prices were converted at
+	Source       string    `json:"source"`
+	AsOf         time.Time `json:"asof"`
+}
@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// Fulfilment states. A fulfilment groups one seller's items of an order and
+// the order status follows its fulfilments.
+const (
+	FULFILMENT_PENDING    = "pending"
+	FULFILMENT_PROCESSING = "processing"
+	FULFILMENT_SHIPPED    = "shipped"
+	FULFILMENT_DELIVERED  = "delivered"
+	FULFILMENT_CANCELLED  = "cancelled"
+)
+
+// fulfilmentTransitions lists, for every state, the states a fulfilment may
+// move to and which actors may make that move.
+var fulfilmentTransitions = map[string]map[string][]string{
+	FULFILMENT_PENDING: {
+		FULFILMENT_PROCESSING: {SELLER},
+		FULFILMENT_CANCELLED:  {BUYER, SELLER, SYSTEM},
+	},
+	FULFILMENT_PROCESSING: {
+		FULFILMENT_SHIPPED:   {SELLER},
+		FULFILMENT_CANCELLED: {BUYER, SELLER, SYSTEM},
+	},
+	FULFILMENT_SHIPPED: {
+		FULFILMENT_DELIVERED: {BUYER, SELLER, SYSTEM},
+	},
+}
+
+func CanTransitionFulfilment(from string, to string, actor string) bool {
+	for _, allowed := range fulfilmentTransitions[from][to] {
+		if allowed == actor {
+			return true
+		}
+	}
+	return false
+}
+
+// FulfilmentCancellable reports whether items in a fulfilment can still be
+// cancelled, which is until they are handed to the carrier.
+func FulfilmentCancellable(status string) bool {
+	return status == FULFILMENT_PENDING || status == FULFILMENT_PROCESSING
+}
+
+type Fulfilment struct {
+	ID               uint        `json:"id" gorm:"PrimaryKey"`
+	OrderId          uint        `json:"orderid" gorm:"index"`
+	SellerId         int         `json:"sellerid" gorm:"index"`
+	Status           string      `json:"status" gorm:"default:pending"`
+	ShippingMethodId uint        `json:"shippingmethodid"`
+	ShippingMethod   string      `json:"shippingmethod"`
+	ShippingCost     Money       `json:"shippingcost" gorm:"embedded;embeddedPrefix:shipping_"`
+	Carrier          string      `json:"carrier"`
+	TrackingNumber   string      `json:"trackingnumber"`
+	Items            []OrderItem `json:"items,omitempty"`
+	ShippedAt        *time.Time  `json:"shippedAt"`
+	DeliveredAt      *time.Time  `json:"deliveredAt"`
+	CancelledAt      *time.Time  `json:"cancelledAt"`
+	CreatedAt        time.Time   `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt        time.Time   `json:"updatedAt" gorm:"default:current_timestamp"`
+}
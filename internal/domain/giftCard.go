@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Gift card transaction kinds
+const (
+	GIFT_CARD_ISSUE  = "issue"
+	GIFT_CARD_REDEEM = "redeem"
+	GIFT_CARD_REFUND = "refund"
+)
+
+// GiftCard is prepaid credit anyone holding the code can spend, over as
+// many orders as the balance lasts until it expires. Cards are either
+// bought by a buyer or issued by an admin.
+type GiftCard struct {
+	ID           uint      `json:"id" gorm:"PrimaryKey"`
+	Code         string    `json:"code" gorm:"uniqueIndex"`
+	InitialValue Money     `json:"initialvalue" gorm:"embedded;embeddedPrefix:initial_"`
+	Balance      Money     `json:"balance" gorm:"embedded;embeddedPrefix:balance_"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	Disabled     bool      `json:"disabled"`
+	OrderId      uint      `json:"orderid" gorm:"index"` //order the card was bought with
+	OrderItemId  int       `json:"orderitemid"`
+	PurchaserId  int       `json:"purchaserid" gorm:"index"`
+	IssuedBy     int       `json:"issuedby"` //admin who issued it
+	Batch        string    `json:"batch" gorm:"index"`
+	CreatedAt    time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt    time.Time `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+func (g *GiftCard) Expired(now time.Time) bool {
+	return !now.Before(g.ExpiresAt)
+}
+
+// MaskedCode shows only the last four characters, for everyone but the
+// card holder
+func (g *GiftCard) MaskedCode() string {
+	if len(g.Code) <= 4 {
+		return g.Code
+	}
+	return strings.Repeat("*", len(g.Code)-4) + g.Code[len(g.Code)-4:]
+}
+
+// GiftCardTransaction is one change of a card balance, negative amounts
+// are spent.
+type GiftCardTransaction struct {
+	ID           uint      `json:"id" gorm:"PrimaryKey"`
+	GiftCardId   uint      `json:"giftcardid" gorm:"index"`
+	Kind         string    `json:"kind"`
+	Amount       Money     `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	BalanceAfter Money     `json:"balanceafter" gorm:"embedded;embeddedPrefix:balanceafter_"`
+	OrderId      uint      `json:"orderid" gorm:"index"`
+	RefundId     uint      `json:"refundid"`
+	ActorId      int       `json:"actorid"`
+	ActorRole    string    `json:"actorrole"`
+	CreatedAt    time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+}
+
+// CartGiftCard is a gift card the buyer redeems against their cart
+type CartGiftCard struct {
+	ID         uint      `json:"id" gorm:"PrimaryKey"`
+	UserId     int       `json:"userid" gorm:"uniqueIndex:idx_cart_gift_card"`
+	GiftCardId uint      `json:"giftcardid" gorm:"uniqueIndex:idx_cart_gift_card"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+// Idempotency key states
+const (
+	IDEMPOTENCY_IN_PROGRESS = "in_progress"
+	IDEMPOTENCY_COMPLETED   = "completed"
+)
+
+// IdempotencyKey remembers the response to a request the client tagged
+// with an Idempotency-Key header, so a retry gets the same response instead
+// of doing the work twice. Keys are per user.
+type IdempotencyKey struct {
+	ID             uint      `json:"id" gorm:"PrimaryKey"`
+	UserId         int       `json:"userid" gorm:"uniqueIndex:idx_idempotency_key"`
+	Key            string    `json:"key" gorm:"uniqueIndex:idx_idempotency_key"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	RequestHash    string    `json:"requesthash"`
+	Status         string    `json:"status"`
+	ResponseStatus int       `json:"responsestatus"`
+	ContentType    string    `json:"contenttype"`
+	ResponseBody   []byte    `json:"-"`
+	ExpiresAt      time.Time `json:"expiresAt" gorm:"index"`
+	CreatedAt      time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt      time.Time `json:"updatedAt" gorm:"default:current_timestamp"`
+}
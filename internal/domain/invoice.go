@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// Invoice is issued once per order, or per fulfilment for the seller's
+// portion, and never changes afterwards. Document holds what was printed
+// on it as JSON.
+type Invoice struct {
+	ID           uint      `json:"id" gorm:"PrimaryKey"`
+	Number       string    `json:"number" gorm:"uniqueIndex;not null"`
+	OrderId      uint      `json:"orderid" gorm:"uniqueIndex:idx_invoice_order"`
+	FulfilmentId uint      `json:"fulfilmentid" gorm:"uniqueIndex:idx_invoice_order"` //zero for the whole order
+	UserId       uint      `json:"userid" gorm:"index"`
+	Total        Money     `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+	Document     string    `json:"-" gorm:"type:text"`
+	IssuedAt     time.Time `json:"issuedat"`
+}
@@ -0,0 +1,80 @@
+package domain
+
+import "time"
+
+// Ledger accounts. Sellers each have their own seller account, the others
+// are the platform's.
+const (
+	//buyer money taken in, runs negative by what buyers paid
+	ACCOUNT_CLEARING = "clearing"
+	//what the platform owes a seller
+	ACCOUNT_SELLER = "seller"
+	//what the platform earned
+	ACCOUNT_COMMISSION = "commission"
+	//money sent out to seller bank accounts
+	ACCOUNT_PAYOUTS = "payouts"
+	//gift cards sold, owed to whichever seller they are spent with
+	ACCOUNT_GIFT_CARDS = "gift_cards"
+)
+
+// Ledger entry kinds
+const (
+	ENTRY_SALE          = "sale"
+	ENTRY_REFUND        = "refund"
+	ENTRY_PAYOUT        = "payout"
+	ENTRY_PAYOUT_FAILED = "payout_failed"
+)
+
+// LedgerEntry is one balanced movement of money, its postings add up to
+// zero. A positive posting raises the balance of its account, a negative
+// one lowers it. Reference is unique so an event is never booked twice.
+type LedgerEntry struct {
+	ID        uint            `json:"id" gorm:"PrimaryKey"`
+	Kind      string          `json:"kind"`
+	Reference string          `json:"reference" gorm:"uniqueIndex;not null"`
+	OrderId   uint            `json:"orderid" gorm:"index"`
+	PayoutId  uint            `json:"payoutid" gorm:"index"`
+	Memo      string          `json:"memo"`
+	Postings  []LedgerPosting `json:"postings" gorm:"foreignKey:EntryId"`
+	CreatedAt time.Time       `json:"createdAt" gorm:"default:current_timestamp"`
+}
+
+type LedgerPosting struct {
+	ID        uint      `json:"id" gorm:"PrimaryKey"`
+	EntryId   uint      `json:"entryid" gorm:"index"`
+	Account   string    `json:"account" gorm:"index:idx_posting_account"`
+	OwnerId   int       `json:"ownerid" gorm:"index:idx_posting_account"` //seller id on seller accounts, the seller it was earned from on the commission account
+	Amount    Money     `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	CreatedAt time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+}
+
+// SellerBalance is what the platform owes a seller in one currency.
+// Available leaves out sales still inside the payout hold period.
+type SellerBalance struct {
+	SellerId  int   `json:"sellerid"`
+	Balance   Money `json:"balance"`
+	Available Money `json:"available"`
+}
+
+// Payout statuses
+const (
+	PAYOUT_PENDING = "pending"
+	PAYOUT_PAID    = "paid"
+	PAYOUT_FAILED  = "failed"
+)
+
+// Payout is a transfer of a seller's available balance to their bank
+// account. The balance is taken when the payout is created and given back
+// if the transfer fails.
+type Payout struct {
+	ID            uint       `json:"id" gorm:"PrimaryKey"`
+	SellerId      int        `json:"sellerid" gorm:"index"`
+	BankAccountId int        `json:"bankaccountid"`
+	Amount        Money      `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Status        string     `json:"status" gorm:"index"`
+	ProviderRef   string     `json:"providerref"`
+	FailureReason string     `json:"failurereason"`
+	PaidAt        *time.Time `json:"paidat"`
+	CreatedAt     time.Time  `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt     time.Time  `json:"updatedAt" gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,221 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Money is an exact amount in the smallest unit of its currency (cents for
+// USD). It is embedded into tables as <prefix>_minor and <prefix>_currency.
+//
+// Arithmetic requires both sides to share a currency, a zero value without
+// a currency takes on the currency of the other side. Mixing currencies is
+// a programming error and panics, convert first.
+type Money struct {
+	Amount   int64  `json:"amount" gorm:"column:minor"`
+	Currency string `json:"currency" gorm:"column:currency;size:3"`
+}
+
+// decimals per currency, ISO 4217 minor units; anything not listed uses 2
+var currencyDecimals = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"VND": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+func CurrencyDecimals(currency string) int {
+	if d, ok := currencyDecimals[strings.ToUpper(currency)]; ok {
+		return d
+	}
+	return 2
+}
+
+func (m Money) currencyWith(o Money) string {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency
+	case m.Currency == "" && m.Amount == 0:
+		return o.Currency
+	case o.Currency == "" && o.Amount == 0:
+		return m.Currency
+	}
+	panic(fmt.Sprintf("money currency mismatch %s and %s", m.Currency, o.Currency))
+}
+
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currencyWith(o)}
+}
+
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currencyWith(o)}
+}
+
+func (m Money) Mul(qty int64) Money {
+	return Money{Amount: m.Amount * qty, Currency: m.Currency}
+}
+
+// Percent returns bps basis points (1/100 of a percent) of m, rounded half
+// away from zero.
+func (m Money) Percent(bps int64) Money {
+	return Money{Amount: divRound(m.Amount*bps, 10000), Currency: m.Currency}
+}
+
+// Allocate splits m across weights in proportion. Leftover minor units go
+// to the parts with the largest remainders, so the parts always add up to m.
+func (m Money) Allocate(weights []int64) []Money {
+	parts := make([]Money, len(weights))
+
+	var total int64
+	for _, w := range weights {
+		total += w
+	}
+
+	for i := range parts {
+		parts[i] = Money{Currency: m.Currency}
+	}
+
+	if total == 0 {
+		return parts
+	}
+
+	type remainder struct {
+		index int
+		value int64
+	}
+
+	var allocated int64
+	remainders := make([]remainder, len(weights))
+	for i, w := range weights {
+		share := m.Amount * w
+		parts[i].Amount = share / total
+		allocated += parts[i].Amount
+
+		r := share % total
+		if r < 0 {
+			r = -r
+		}
+		remainders[i] = remainder{index: i, value: r}
+	}
+
+	sort.SliceStable(remainders, func(a, b int) bool {
+		return remainders[a].value > remainders[b].value
+	})
+
+	leftover := m.Amount - allocated
+	step := int64(1)
+	if leftover < 0 {
+		step = -1
+	}
+
+	for k := 0; leftover != 0; k++ {
+		parts[remainders[k].index].Amount += step
+		leftover -= step
+	}
+
+	return parts
+}
+
+// Convert prices m in currency at rate, the price of one unit of m's
+// currency, rounded half away from zero to the minor unit.
+func (m Money) Convert(currency string, rate *big.Rat) Money {
+
+	currency = strings.ToUpper(currency)
+	if currency == m.Currency {
+		return m
+	}
+
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+
+	//minor units of one currency to minor units of the other
+	shift := CurrencyDecimals(currency) - CurrencyDecimals(m.Currency)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil))
+	if shift >= 0 {
+		value.Mul(value, scale)
+	} else {
+		value.Quo(value, scale)
+	}
+
+	q, r := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	if r.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(value.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(r.Sign())))
+	}
+
+	return Money{Amount: q.Int64(), Currency: currency}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) Equal(o Money) bool {
+	return m.Amount == o.Amount && (m.Currency == o.Currency || m.Amount == 0)
+}
+
+func (m Money) GreaterThan(o Money) bool {
+	m.currencyWith(o)
+	return m.Amount > o.Amount
+}
+
+func (m Money) LessThan(o Money) bool {
+	m.currencyWith(o)
+	return m.Amount < o.Amount
+}
+
+func (m Money) Min(o Money) Money {
+	if o.LessThan(m) {
+		return o
+	}
+	return m
+}
+
+func (m Money) String() string {
+	decimals := CurrencyDecimals(m.Currency)
+	if decimals == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+
+	unit := int64(1)
+	for i := 0; i < decimals; i++ {
+		unit *= 10
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/unit, decimals, amount%unit, m.Currency)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func divRound(a int64, b int64) int64 {
+	q := a / b
+	r := a % b
+	if r*2 >= b {
+		q++
+	} else if r*2 <= -b {
+		q--
+	}
+	return q
+}
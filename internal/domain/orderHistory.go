@@ -0,0 +1,77 @@
+package domain
+
+import "time"
+
+// SYSTEM is the actor for transitions driven by the platform itself, such
+// as payment gateway events or the order following its fulfilments.
+const SYSTEM = "system"
+
+// orderTransitions lists, for every state, the states it may move to and
+// which actors may make that move. Cancelling is not one of them, see
+// orderCancellations.
+var orderTransitions = map[string]map[string][]string{
+	ORDER_PENDING_PAYMENT: {
+		ORDER_PAID: {SYSTEM},
+	},
+	ORDER_CONFIRMED: {
+		ORDER_PROCESSING: {SELLER, SYSTEM},
+	},
+	ORDER_PAID: {
+		ORDER_PROCESSING: {SELLER, SYSTEM},
+		ORDER_REFUNDED:   {SYSTEM},
+	},
+	ORDER_PROCESSING: {
+		ORDER_SHIPPED: {SELLER, SYSTEM},
+	},
+	ORDER_SHIPPED: {
+		ORDER_DELIVERED: {BUYER, SELLER, SYSTEM},
+	},
+	ORDER_DELIVERED: {
+		ORDER_REFUNDED: {SYSTEM},
+	},
+	ORDER_CANCELLED: {
+		ORDER_REFUNDED: {SYSTEM},
+	},
+}
+
+// orderCancellations lists the states an order can be cancelled in and who
+// may cancel it. Cancelling restocks the items and refunds the buyer, so it
+// is never a plain status change.
+var orderCancellations = map[string][]string{
+	ORDER_PENDING_PAYMENT: {BUYER, SELLER, SYSTEM},
+	ORDER_CONFIRMED:       {BUYER, SELLER, SYSTEM},
+	ORDER_PAID:            {BUYER, SELLER, SYSTEM},
+	ORDER_PROCESSING:      {BUYER, SELLER, SYSTEM},
+}
+
+// CanCancelOrder reports whether actor may cancel an order in the state
+func CanCancelOrder(from string, actor string) bool {
+	for _, allowed := range orderCancellations[from] {
+		if allowed == actor {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransitionOrder reports whether actor may move an order from one
+// state to another.
+func CanTransitionOrder(from string, to string, actor string) bool {
+	for _, allowed := range orderTransitions[from][to] {
+		if allowed == actor {
+			return true
+		}
+	}
+	return false
+}
+
+type OrderHistory struct {
+	ID         uint      `json:"id" gorm:"PrimaryKey"`
+	OrderId    uint      `json:"orderid" gorm:"index"`
+	FromStatus string    `json:"fromstatus"`
+	ToStatus   string    `json:"tostatus"`
+	ActorId    int       `json:"actorid"`
+	ActorRole  string    `json:"actorrole"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+}
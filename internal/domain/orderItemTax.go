@@ -0,0 +1,14 @@
+package domain
+
+// OrderItemTax is a tax charged on an order item. Inclusive tax is part of
+// the item price, exclusive tax is paid on top of it. RulesVersion is the
+// rate table the tax was worked out with.
+type OrderItemTax struct {
+	ID           uint   `json:"id" gorm:"PrimaryKey"`
+	OrderItemId  int    `json:"orderitemid" gorm:"index"`
+	Name         string `json:"name"`
+	Rate         int64  `json:"rate"` //basis points
+	Inclusive    bool   `json:"inclusive"`
+	Amount       Money  `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	RulesVersion string `json:"rulesversion"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// PaymentEvent is a gateway webhook event, stored once per event id so a
+// redelivered event is never applied twice.
+type PaymentEvent struct {
+	ID           uint      `json:"id" gorm:"PrimaryKey"`
+	EventId      string    `json:"eventid" gorm:"index;unique;not null"`
+	Type         string    `json:"type"`
+	ProviderType string    `json:"providertype"`
+	PaymentId    string    `json:"paymentid" gorm:"index"`
+	Payload      string    `json:"payload"`
+	CreatedAt    time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// Promotion types
+const (
+	PROMO_SALE    = "sale"
+	PROMO_BUY_GET = "buyget"
+	PROMO_BUNDLE  = "bundle"
+)
+
+// Promotion is a seller offer applied automatically between StartsAt and
+// EndsAt, a promotion without an end runs until it is switched off.
+//
+// Sales sell the products at Price, or Percent basis points off when Price
+// is zero. Buy-get offers give GetQty units Percent basis points off for
+// every BuyQty units of a product bought, 10000 makes them free. Bundles
+// sell one set of the products, Qty of each, at Price.
+type Promotion struct {
+	ID        uint               `json:"id" gorm:"PrimaryKey"`
+	SellerId  int                `json:"sellerid" gorm:"index"`
+	Name      string             `json:"name"`
+	Type      string             `json:"type"`
+	Price     Money              `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Percent   int64              `json:"percent"` //basis points
+	BuyQty    int                `json:"buyqty"`
+	GetQty    int                `json:"getqty"`
+	StartsAt  time.Time          `json:"startsat"`
+	EndsAt    *time.Time         `json:"endsat"`
+	Active    bool               `json:"active"`
+	Products  []PromotionProduct `json:"products"`
+	CreatedAt time.Time          `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt time.Time          `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+type PromotionProduct struct {
+	ID          uint `json:"id" gorm:"PrimaryKey"`
+	PromotionId uint `json:"promotionid" gorm:"index"`
+	ProductId   uint `json:"productid" gorm:"index"`
+	Qty         int  `json:"qty"`
+}
+
+// SalePrice is what the promotion sells the product at, the list price when
+// the promotion is not a sale.
+func (p *Promotion) SalePrice(listPrice Money) Money {
+	switch {
+	case p.Type != PROMO_SALE:
+		return listPrice
+	case !p.Price.IsZero():
+		return p.Price
+	}
+	return listPrice.Sub(listPrice.Percent(p.Percent))
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "time"
+
+type Refund struct {
+	ID               uint         `json:"id" gorm:"PrimaryKey"`
+	OrderId          uint         `json:"orderid" gorm:"index"`
+	PaymentId        string       `json:"paymentid" gorm:"index"`
+	ProviderRefundId string       `json:"providerrefundid"`
+	Amount           Money        `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	WalletAmount     Money        `json:"walletamount" gorm:"embedded;embeddedPrefix:wallet_"`     //part credited to the wallet
+	GiftCardAmount   Money        `json:"giftcardamount" gorm:"embedded;embeddedPrefix:giftcard_"` //part put back on gift cards, the rest goes back to the card
+	Reason           string       `json:"reason"`
+	Status           string       `json:"status"`
+	ActorId          int          `json:"actorid"`
+	ActorRole        string       `json:"actorrole"`
+	Items            []RefundItem `json:"items"`
+	CreatedAt        time.Time    `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt        time.Time    `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+type RefundItem struct {
+	ID          uint  `json:"id" gorm:"PrimaryKey"`
+	RefundId    uint  `json:"refundid" gorm:"index"`
+	OrderItemId int   `json:"orderitemid" gorm:"index"`
+	Qty         int   `json:"qty"`
+	Amount      Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+}
@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// Return request states
+const (
+	RETURN_REQUESTED = "requested"
+	RETURN_APPROVED  = "approved"
+	RETURN_REJECTED  = "rejected"
+	RETURN_RECEIVED  = "received"
+	RETURN_REFUNDED  = "refunded"
+)
+
+// returnTransitions lists the states a return may move to, every move is
+// made by the seller of the returned item.
+var returnTransitions = map[string][]string{
+	RETURN_REQUESTED: {RETURN_APPROVED, RETURN_REJECTED},
+	RETURN_APPROVED:  {RETURN_RECEIVED},
+	RETURN_RECEIVED:  {RETURN_REFUNDED},
+}
+
+func CanTransitionReturn(from string, to string) bool {
+	for _, allowed := range returnTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+type ReturnRequest struct {
+	ID          uint      `json:"id" gorm:"PrimaryKey"`
+	OrderId     uint      `json:"orderid" gorm:"index"`
+	OrderItemId int       `json:"orderitemid" gorm:"index"`
+	UserId      uint      `json:"userid" gorm:"index"`
+	SellerId    int       `json:"sellerid" gorm:"index"`
+	Qty         int       `json:"qty"`
+	Reason      string    `json:"reason"`
+	Status      string    `json:"status" gorm:"default:requested"`
+	SellerNote  string    `json:"sellernote"`
+	RefundId    uint      `json:"refundid"`
+	Item        OrderItem `json:"item" gorm:"foreignKey:OrderItemId"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt   time.Time `json:"updatedAt" gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Shipment states, the tracking statuses reported by the carriers
+const (
+	SHIPMENT_LABEL_CREATED    = "label_created"
+	SHIPMENT_IN_TRANSIT       = "in_transit"
+	SHIPMENT_OUT_FOR_DELIVERY = "out_for_delivery"
+	SHIPMENT_DELIVERED        = "delivered"
+	SHIPMENT_EXCEPTION        = "exception"
+)
+
+// Shipment is a parcel a seller handed to a carrier, carrying some or all
+// of the items of their fulfilment.
+type Shipment struct {
+	ID             uint            `json:"id" gorm:"PrimaryKey"`
+	OrderId        uint            `json:"orderid" gorm:"index"`
+	FulfilmentId   uint            `json:"fulfilmentid" gorm:"index"`
+	SellerId       int             `json:"sellerid" gorm:"index"`
+	Carrier        string          `json:"carrier"`
+	Service        string          `json:"service"`
+	TrackingNumber string          `json:"trackingnumber" gorm:"index"`
+	LabelRef       string          `json:"labelref"`
+	Status         string          `json:"status" gorm:"default:label_created"`
+	Items          []ShipmentItem  `json:"items"`
+	Events         []ShipmentEvent `json:"events"`
+	LastCheckedAt  *time.Time      `json:"lastCheckedAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+	CreatedAt      time.Time       `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt      time.Time       `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+type ShipmentItem struct {
+	ID          uint `json:"id" gorm:"PrimaryKey"`
+	ShipmentId  uint `json:"shipmentid" gorm:"index"`
+	OrderItemId int  `json:"orderitemid" gorm:"index"`
+	Qty         int  `json:"qty"`
+}
+
+// ShipmentEvent is one step of the carrier's tracking timeline
+type ShipmentEvent struct {
+	ID          uint      `json:"id" gorm:"PrimaryKey"`
+	ShipmentId  uint      `json:"shipmentid" gorm:"uniqueIndex:idx_shipment_event"`
+	Status      string    `json:"status" gorm:"uniqueIndex:idx_shipment_event"`
+	Description string    `json:"description"`
+	Location    string    `json:"location"`
+	OccurredAt  time.Time `json:"occurredAt" gorm:"uniqueIndex:idx_shipment_event"`
+	CreatedAt   time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+}
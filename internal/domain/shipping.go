@@ -0,0 +1,60 @@
+package domain
+
+import "time"
+
+// Shipping method pricing
+const (
+	SHIPPING_FLAT   = "flat"
+	SHIPPING_WEIGHT = "weight"
+)
+
+// ShippingZone is an area a seller ships to. A zone covers a country, or a
+// post code range within it when PostCodeTo is set, an empty country covers
+// everywhere else. The most specific matching zone prices the parcel.
+type ShippingZone struct {
+	ID           uint             `json:"id" gorm:"PrimaryKey"`
+	SellerId     int              `json:"sellerid" gorm:"index"`
+	Name         string           `json:"name"`
+	Country      string           `json:"country"`
+	PostCodeFrom uint             `json:"postcodefrom"`
+	PostCodeTo   uint             `json:"postcodeto"`
+	Methods      []ShippingMethod `json:"methods" gorm:"foreignKey:ZoneId"`
+	CreatedAt    time.Time        `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt    time.Time        `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+// ShippingMethod prices a parcel in a zone. Flat methods cost Rate, weight
+// methods add PerKg for every started kilogram. Orders of FreeOver or more
+// from the seller ship free, a zero FreeOver never does.
+type ShippingMethod struct {
+	ID        uint      `json:"id" gorm:"PrimaryKey"`
+	ZoneId    uint      `json:"zoneid" gorm:"index"`
+	SellerId  int       `json:"sellerid" gorm:"index"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Rate      Money     `json:"rate" gorm:"embedded;embeddedPrefix:rate_"`
+	PerKg     Money     `json:"perkg" gorm:"embedded;embeddedPrefix:perkg_"`
+	FreeOver  Money     `json:"freeover" gorm:"embedded;embeddedPrefix:freeover_"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+// ShippingSelection is the method a buyer picked for a seller's items in
+// their cart, kept until checkout.
+type ShippingSelection struct {
+	ID       uint `json:"id" gorm:"PrimaryKey"`
+	UserId   int  `json:"userid" gorm:"uniqueIndex:idx_shipping_selection"`
+	SellerId int  `json:"sellerid" gorm:"uniqueIndex:idx_shipping_selection"`
+	MethodId uint `json:"methodid"`
+}
+
+// ParcelWeight is the weight a product is charged at in grams, the larger
+// of its actual and volumetric weight (5000 cm³ per kg).
+func ParcelWeight(p *Product) uint {
+	volumetric := uint(uint64(p.Length) * uint64(p.Width) * uint64(p.Height) / 5000)
+	if volumetric > p.Weight {
+		return volumetric
+	}
+	return p.Weight
+}
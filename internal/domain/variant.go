@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// ProductOption is a way a product comes in, such as size or colour.
+// Every variant of the product has a value for each option.
+type ProductOption struct {
+	ID        uint   `json:"id" gorm:"PrimaryKey"`
+	ProductId uint   `json:"productid" gorm:"index"`
+	Name      string `json:"name"`
+	Position  int    `json:"position"`
+}
+
+// Variant is one sellable version of a product with its own SKU and stock.
+// Price overrides the product price when it has a currency, a zero Money
+// means the variant sells at the product price.
+type Variant struct {
+	ID           uint            `json:"id" gorm:"PrimaryKey"`
+	ProductId    uint            `json:"productid" gorm:"index"`
+	SKU          string          `json:"sku" gorm:"uniqueIndex;not null"`
+	Price        Money           `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Stock        uint            `json:"stock"`
+	ImageUrl     string          `json:"imageurl"`
+	Options      []VariantOption `json:"options"`
+	SalePrice    *Money          `json:"saleprice" gorm:"-"` //while a sale runs, when the variant has its own price
+	ListPrice    *Money          `json:"listprice" gorm:"-"`
+	DisplayPrice *Money          `json:"displayprice" gorm:"-"`
+	CreatedAt    time.Time       `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt    time.Time       `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+// VariantOption is the variant's value for one of the product options
+type VariantOption struct {
+	ID        uint   `json:"id" gorm:"PrimaryKey"`
+	VariantId uint   `json:"variantid" gorm:"index"`
+	OptionId  uint   `json:"optionid"`
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+}
+
+func (v *Variant) HasPrice() bool {
+	return v.Price.Currency != ""
+}
+
+// Title names the variant by its option values, e.g. "M / Red"
+func (v *Variant) Title() string {
+	values := make([]string, 0, len(v.Options))
+	for _, option := range v.Options {
+		values = append(values, option.Value)
+	}
+	return strings.Join(values, " / ")
+}
+
+// Variant finds one of the product's variants, nil when it has no such
+// variant.
+func (p *Product) Variant(id uint) *Variant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// VariantPrice is what a variant sells for right now: its own price or the
+// product's, the sale price while a sale runs.
+func (p *Product) VariantPrice(v *Variant) Money {
+	if !v.HasPrice() {
+		return p.SellingPrice()
+	}
+	if v.SalePrice != nil {
+		return *v.SalePrice
+	}
+	return v.Price
+}
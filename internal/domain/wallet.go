@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// Wallet transaction kinds
+const (
+	WALLET_REFUND     = "refund"
+	WALLET_PROMOTION  = "promotion"
+	WALLET_ADJUSTMENT = "adjustment"
+	WALLET_CHECKOUT   = "checkout"
+)
+
+// Wallet is a buyer's store credit. The balance only ever changes together
+// with a WalletTransaction saying why, and never goes below zero.
+type Wallet struct {
+	ID        uint      `json:"id" gorm:"PrimaryKey"`
+	UserId    int       `json:"userid" gorm:"uniqueIndex"`
+	Balance   Money     `json:"balance" gorm:"embedded;embeddedPrefix:balance_"`
+	CreatedAt time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"default:current_timestamp"`
+}
+
+// WalletTransaction is one change of a wallet balance, negative amounts
+// are spent.
+type WalletTransaction struct {
+	ID           uint      `json:"id" gorm:"PrimaryKey"`
+	WalletId     uint      `json:"walletid" gorm:"index"`
+	UserId       int       `json:"userid" gorm:"index"`
+	Kind         string    `json:"kind"`
+	Amount       Money     `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	BalanceAfter Money     `json:"balanceafter" gorm:"embedded;embeddedPrefix:balanceafter_"`
+	OrderId      uint      `json:"orderid" gorm:"index"`
+	RefundId     uint      `json:"refundid"`
+	Note         string    `json:"note"`
+	ActorId      int       `json:"actorid"`
+	ActorRole    string    `json:"actorrole"`
+	CreatedAt    time.Time `json:"createdAt" gorm:"default:current_timestamp"`
+}
+
+// CartWallet is how much of their wallet a buyer wants to spend on their
+// cart, as much as the cart needs when Amount is zero.
+type CartWallet struct {
+	ID     uint  `json:"id" gorm:"PrimaryKey"`
+	UserId int   `json:"userid" gorm:"uniqueIndex"`
+	Amount Money `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "go-ecommerce-app/internal/domain"
+
+// rate in basis points
+type CommissionRuleRequest struct {
+	Scope      string `json:"scope"`
+	CategoryId uint   `json:"categoryid"`
+	Tier       string `json:"tier"`
+	Rate       int64  `json:"rate"`
+	Active     *bool  `json:"active"`
+}
+
+type SellerTierRequest struct {
+	Tier string `json:"tier"`
+}
+
+// CommissionReport is the commission booked from From to To, both days
+// included. Totals are per currency, over every seller.
+type CommissionReport struct {
+	From    string                     `json:"from"`
+	To      string                     `json:"to"`
+	Totals  []domain.CommissionSummary `json:"totals"`
+	Sellers []domain.CommissionSummary `json:"sellers"`
+}
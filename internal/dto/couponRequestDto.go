@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// amounts in the smallest currency unit, percent in basis points
+type CouponRequest struct {
+	Code           string     `json:"code"`
+	Description    string     `json:"description"`
+	Type           string     `json:"type"`
+	Amount         int64      `json:"amount"`
+	Percent        int64      `json:"percent"`
+	MinSpend       int64      `json:"minspend"`
+	MaxUses        int        `json:"maxuses"`
+	MaxUsesPerUser int        `json:"maxusesperuser"`
+	StartsAt       *time.Time `json:"startsat"`
+	EndsAt         *time.Time `json:"endsat"`
+	Active         *bool      `json:"active"`
+	CategoryIds    []uint     `json:"categoryids"`
+	ProductIds     []uint     `json:"productids"`
+	SellerIds      []uint     `json:"sellerids"`
+}
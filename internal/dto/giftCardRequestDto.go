@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"time"
+)
+
+// amount in the smallest currency unit, expiresat as YYYY-MM-DD with the
+// configured validity used when empty
+type IssueGiftCardsRequest struct {
+	Count     int    `json:"count"`
+	Amount    int64  `json:"amount"`
+	ExpiresAt string `json:"expiresat"`
+	Batch     string `json:"batch"`
+}
+
+type IssueGiftCardsResponse struct {
+	Batch string             `json:"batch"`
+	Cards []*domain.GiftCard `json:"cards"`
+}
+
+// GiftCardBalance is what anyone holding a code may see of the card
+type GiftCardBalance struct {
+	Code      string       `json:"code"` //masked
+	Balance   domain.Money `json:"balance"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+	Expired   bool         `json:"expired"`
+	Disabled  bool         `json:"disabled"`
+}
+
+type GiftCardDetails struct {
+	Card         *domain.GiftCard              `json:"card"`
+	Transactions []*domain.GiftCardTransaction `json:"transactions"`
+}
+
+type UpdateGiftCardRequest struct {
+	Disabled bool `json:"disabled"`
+}
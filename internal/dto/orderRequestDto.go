@@ -0,0 +1,66 @@
+package dto
+
+import "go-ecommerce-app/internal/domain"
+
+type CreateOrderRequest struct {
+	//buyer has seen the price, stock or availability changes flagged on the cart
+	AcceptChanges bool `json:"acceptchanges"`
+	//card, cod or bank_transfer, card when empty
+	PaymentMethod string `json:"paymentmethod"`
+}
+
+// amount in the smallest currency unit, as it arrived on the account
+type ReconcileTransferRequest struct {
+	Reference     string `json:"reference"`
+	Amount        int64  `json:"amount"`
+	BankReference string `json:"bankreference"`
+}
+
+type ConfirmCashRequest struct {
+	Reference string `json:"reference"`
+	Amount    int64  `json:"amount"`
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+	//required from the seller when a fulfilment is shipped
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"trackingnumber"`
+}
+
+// REFUND_TO_WALLET asks for the refund as store credit instead of back to the card
+const REFUND_TO_WALLET = "wallet"
+
+type CancelOrderRequest struct {
+	//empty cancels every item the caller may cancel
+	ItemIds []int  `json:"itemids"`
+	Reason  string `json:"reason"`
+	//buyers only, see REFUND_TO_WALLET
+	RefundTo string `json:"refundto"`
+}
+
+type CancelOrderResponse struct {
+	OrderId uint           `json:"orderid"`
+	Status  string         `json:"status"`
+	Refund  *domain.Refund `json:"refund"`
+}
+
+type CreateReturnRequest struct {
+	OrderItemId int    `json:"orderitemid"`
+	Qty         int    `json:"qty"`
+	Reason      string `json:"reason"`
+}
+
+type UpdateReturnStatusRequest struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+type CreateShipmentRequest struct {
+	FulfilmentId uint `json:"fulfilmentid"`
+	//empty ships every item of the fulfilment not shipped yet
+	ItemIds []int  `json:"itemids"`
+	Carrier string `json:"carrier"`
+	Service string `json:"service"`
+}
@@ -1,14 +1,78 @@
 package dto
 
+import (
+	"go-ecommerce-app/internal/domain"
+	"time"
+)
+
 type CreateProductRequest struct {
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	ImageUrl    string  `json:"imageurl"`
-	Description string  `json:"description"`
-	CategoryID  uint    `json:"categoryid"`
-	Stock       uint    `json:"stock"`
+	Name string `json:"name"`
+	//price in the smallest currency unit, e.g. cents
+	Price       int64  `json:"price"`
+	Currency    string `json:"currency"` //the seller's currency when empty
+	ImageUrl    string `json:"imageurl"`
+	Description string `json:"description"`
+	CategoryID  uint   `json:"categoryid"`
+	Stock       uint   `json:"stock"`
+	Weight      uint   `json:"weight"` //grams
+	Length      uint   `json:"length"` //millimetres
+	Width       uint   `json:"width"`
+	Height      uint   `json:"height"`
 }
 
 type UpdateStockRequest struct {
 	Stock uint `json:"stock"`
 }
+
+// option types in display order, e.g. size then colour
+type ProductOptionsRequest struct {
+	Options []string `json:"options"`
+}
+
+// VariantRequest creates or updates a variant. Options maps every option of
+// the product to the variant's value and can't be changed later. Price is
+// in the smallest unit of the product's currency, zero sells at the product
+// price or keeps the current one on updates. Stock is left alone on
+// updates when missing.
+type VariantRequest struct {
+	SKU      string            `json:"sku"`
+	Price    int64             `json:"price"`
+	Stock    *uint             `json:"stock"`
+	ImageUrl string            `json:"imageurl"`
+	Options  map[string]string `json:"options"`
+}
+
+// CurrenciesResponse lists the currencies prices can be listed and shown
+// in, rates are the price of one unit of Base.
+type CurrenciesResponse struct {
+	Currency string            `json:"currency"` //orders are paid in it
+	Base     string            `json:"base"`
+	Source   string            `json:"source"`
+	AsOf     time.Time         `json:"asof"`
+	Rates    map[string]string `json:"rates"`
+}
+
+type ProductSearchRequest struct {
+	Query      string
+	CategoryId uint
+	Page       int
+	Limit      int
+	Currency   string //prices are also shown in it
+}
+
+// ProductSearchHit is a matching product, Name and Snippet mark the
+// matched words with <mark></mark>.
+type ProductSearchHit struct {
+	Product *domain.Product `json:"product"`
+	Rank    float64         `json:"rank"`
+	Name    string          `json:"name"`
+	Snippet string          `json:"snippet"`
+}
+
+type ProductSearchResponse struct {
+	Query   string             `json:"query"`
+	Total   int64              `json:"total"`
+	Page    int                `json:"page"`
+	Limit   int                `json:"limit"`
+	Results []ProductSearchHit `json:"results"`
+}
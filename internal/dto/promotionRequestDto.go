@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+type PromotionProductRequest struct {
+	ProductId uint `json:"productid"`
+	Qty       int  `json:"qty"`
+}
+
+// amounts in the smallest currency unit, percent in basis points
+type PromotionRequest struct {
+	Name     string                    `json:"name"`
+	Type     string                    `json:"type"`
+	Price    int64                     `json:"price"`
+	Percent  int64                     `json:"percent"`
+	BuyQty   int                       `json:"buyqty"`
+	GetQty   int                       `json:"getqty"`
+	StartsAt *time.Time                `json:"startsat"`
+	EndsAt   *time.Time                `json:"endsat"`
+	Active   *bool                     `json:"active"`
+	Products []PromotionProductRequest `json:"products"`
+}
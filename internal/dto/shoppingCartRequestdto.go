@@ -1,6 +1,129 @@
 package dto
 
+import "go-ecommerce-app/internal/domain"
+
 type CreateCartRequest struct {
 	ProductId uint `json:"productid"`
+	VariantId uint `json:"variantid"` //required for products with variants
 	Qty       uint `json:"qty"`
 }
+
+type CartItemResponse struct {
+	domain.Cart
+	CurrentPrice      domain.Money          `json:"currentprice"`
+	Available         uint                  `json:"available"`
+	PriceChanged      bool                  `json:"pricechanged"`
+	Removed           bool                  `json:"removed"`
+	Discount          domain.Money          `json:"discount"`
+	InsufficientStock bool                  `json:"insufficientstock"`
+	GiftCard          bool                  `json:"giftcard"`
+	Taxes             []domain.OrderItemTax `json:"taxes"`
+}
+
+type ShippingOption struct {
+	MethodId uint         `json:"methodid"`
+	Name     string       `json:"name"`
+	Price    domain.Money `json:"price"`
+}
+
+// SellerShipping is how one seller's items in the cart can be shipped
+type SellerShipping struct {
+	SellerId int              `json:"sellerid"`
+	Subtotal domain.Money     `json:"subtotal"`
+	Weight   uint             `json:"weight"` //grams
+	Options  []ShippingOption `json:"options"`
+	Selected *ShippingOption  `json:"selected"`
+	//seller does not ship to the buyer's address
+	Unavailable bool `json:"unavailable"`
+}
+
+type AppliedPromotion struct {
+	PromotionId uint         `json:"promotionid"`
+	Name        string       `json:"name"`
+	Type        string       `json:"type"`
+	Discount    domain.Money `json:"discount"`
+}
+
+// AppliedCoupon is the coupon on the cart, Invalid says why it gives no
+// discount right now
+type AppliedCoupon struct {
+	CouponId    uint         `json:"couponid"`
+	Code        string       `json:"code"`
+	Description string       `json:"description"`
+	Discount    domain.Money `json:"discount"`
+	Invalid     string       `json:"invalid"`
+}
+
+// AppliedGiftCard is a gift card redeemed against the cart, Invalid says
+// why it pays nothing right now
+type AppliedGiftCard struct {
+	GiftCardId uint         `json:"giftcardid"`
+	Code       string       `json:"code"` //masked
+	Balance    domain.Money `json:"balance"`
+	Amount     domain.Money `json:"amount"`
+	Invalid    string       `json:"invalid"`
+}
+
+type CartResponse struct {
+	Items               []CartItemResponse `json:"items"`
+	Subtotal            domain.Money       `json:"subtotal"`
+	Promotions          []AppliedPromotion `json:"promotions"`
+	Coupon              *AppliedCoupon     `json:"coupon"`
+	Discount            domain.Money       `json:"discount"`
+	Shipping            []SellerShipping   `json:"shipping"`
+	ShippingTotal       domain.Money       `json:"shippingtotal"`
+	Tax                 domain.Money       `json:"tax"` //inclusive tax is already part of the subtotal
+	Total               domain.Money       `json:"total"`
+	GiftCards           []AppliedGiftCard  `json:"giftcards"`
+	GiftCardCredit      domain.Money       `json:"giftcardcredit"` //paid with gift cards
+	WalletCredit        domain.Money       `json:"walletcredit"`   //paid from the wallet
+	AmountDue           domain.Money       `json:"amountdue"`      //left to pay by card
+	Display             *CartDisplay       `json:"display"`        //in the buyer's currency, when it isn't the order currency
+	HasChanges          bool               `json:"haschanges"`
+	ShippingUnavailable bool               `json:"shippingunavailable"`
+}
+
+// CartDisplay shows the cart totals in the buyer's currency at Rate, the
+// buyer is charged the amounts of the cart itself.
+type CartDisplay struct {
+	Currency  string       `json:"currency"`
+	Rate      string       `json:"rate"`
+	Subtotal  domain.Money `json:"subtotal"`
+	Total     domain.Money `json:"total"`
+	AmountDue domain.Money `json:"amountdue"`
+}
+
+// amount in the smallest currency unit, zero spends as much as the cart needs
+type UseWalletRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+type RedeemGiftCardRequest struct {
+	Code string `json:"code"`
+}
+
+type ApplyCouponRequest struct {
+	Code string `json:"code"`
+}
+
+type SelectShippingRequest struct {
+	SellerId int  `json:"sellerid"`
+	MethodId uint `json:"methodid"`
+}
+
+type ShippingZoneRequest struct {
+	Name         string `json:"name"`
+	Country      string `json:"country"`
+	PostCodeFrom uint   `json:"postcodefrom"`
+	PostCodeTo   uint   `json:"postcodeto"`
+}
+
+// amounts in the smallest currency unit, e.g. cents
+type ShippingMethodRequest struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Rate     int64  `json:"rate"`
+	PerKg    int64  `json:"perkg"`
+	FreeOver int64  `json:"freeover"`
+	Active   *bool  `json:"active"`
+}
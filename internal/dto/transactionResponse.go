@@ -1,17 +1,56 @@
 package dto
 
+import (
+	"go-ecommerce-app/internal/domain"
+	"time"
+)
+
+// SellerOrderDetails is one seller's fulfilment of an order with the buyer
+// details needed to ship it
 type SellerOrderDetails struct {
-	OrderrRefNumber int    `json:"orderrefnumber"`
-	OrderStatus     int    `json:"order_status"`
-	CreatedAt       string `json:"createdat"`
-	OrderItemId     uint   `json:"orderitemid"`
-	ProductId       uint   `json:"product_id"`
-	Name            string `json:"name"`
-	ImageUrl        string `json:"imageurl"`
-	Price           string `json:"price"`
-	Qty             uint   `json:"qty"`
-	CustomerName    string `json:"customername"`
-	CustomerEmail   string `json:"customeremail"`
-	CustomerPhone   string `json:"customerphone"`
-	CustomerAddress string `json:"customeraddress"`
+	FulfilmentId    uint               `json:"fulfilmentid"`
+	OrderId         uint               `json:"orderid"`
+	OrderrRefNumber int                `json:"orderrefnumber"`
+	OrderStatus     string             `json:"order_status"`
+	Status          string             `json:"status"`
+	Carrier         string             `json:"carrier"`
+	TrackingNumber  string             `json:"trackingnumber"`
+	ShippedAt       *time.Time         `json:"shippedat"`
+	DeliveredAt     *time.Time         `json:"deliveredat"`
+	CreatedAt       string             `json:"createdat"`
+	Items           []domain.OrderItem `json:"items" gorm:"-"`
+	CustomerName    string             `json:"customername"`
+	CustomerEmail   string             `json:"customeremail"`
+	CustomerPhone   string             `json:"customerphone"`
+	CustomerAddress string             `json:"customeraddress"`
+}
+
+// InvoiceFile is a rendered invoice ready for download
+type InvoiceFile struct {
+	Number      string
+	ContentType string
+	Extension   string
+	Data        []byte
+}
+
+type PaymentResponse struct {
+	PaymentId string       `json:"paymentid"`
+	Secret    string       `json:"secret"`
+	PubKey    string       `json:"pubkey"`
+	Amount    domain.Money `json:"amount"`
+}
+
+type PaymentMethodsResponse struct {
+	Methods             []string `json:"methods"`
+	BankTransferDetails string   `json:"banktransferdetails"`
+	BankTransferWindow  string   `json:"banktransferwindow"` //unpaid orders are cancelled after
+}
+
+// PayoutRunResponse counts what a payout run did
+type PayoutRunResponse struct {
+	Created int `json:"created"`
+	Paid    int `json:"paid"`
+	Failed  int `json:"failed"`
+	//transfers that errored and are retried on the next run
+	Pending int `json:"pending"`
 }
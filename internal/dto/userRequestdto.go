@@ -37,6 +37,7 @@ type ProfileInput struct {
 	FirstName    string       `json:"firstname"`
 	LastName     string       `json:"lastname"`
 	AddressInput AddressInput `json:"address"`
+	Currency     string       `json:"currency"` //ISO 4217, prices are shown and listed in it
 }
 
 type UserProfileResponse struct {
@@ -47,6 +48,19 @@ type UserProfileResponse struct {
 	Address   domain.Address `json:"address"` //relation
 	Verified  bool           `json:"verified" gorm:"default:false"`
 	UserType  string         `json:"usertype" gorm:"default:buyer"`
+	Currency  string         `json:"currency"`
 	Cart      domain.Cart    `json:"cart"`
 	Orders    []domain.Order `json:"orders"`
 }
+
+// amount in the smallest currency unit, negative to take credit away
+type WalletAdjustmentRequest struct {
+	Kind   string `json:"kind"`
+	Amount int64  `json:"amount"`
+	Note   string `json:"note"`
+}
+
+type WalletResponse struct {
+	Wallet       *domain.Wallet              `json:"wallet"`
+	Transactions []*domain.WalletTransaction `json:"transactions"`
+}
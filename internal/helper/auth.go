@@ -159,3 +159,25 @@ func (a Auth) AuthorizeSeller(ctx *fiber.Ctx) error {
 		})
 	}
 }
+
+// AuthorizeAdmin lets through platform admins only, admin accounts are set
+// up directly in the database.
+func (a Auth) AuthorizeAdmin(ctx *fiber.Ctx) error {
+
+	authHeader := ctx.Get("Authorization")
+	user, err := a.VerifyToken(authHeader)
+
+	if err != nil {
+		return ctx.Status(401).JSON(&fiber.Map{
+			"message": "authorization failed",
+			"reason":  err.Error(),
+		})
+	} else if user.ID > 0 && user.UserType == domain.ADMIN {
+		ctx.Locals("user", user)
+		return ctx.Next()
+	} else {
+		return ctx.Status(403).JSON(&fiber.Map{
+			"message": "this feature is for admins only",
+		})
+	}
+}
@@ -23,3 +23,23 @@ func RandomNumbers(length int) (int, error) {
 
 	return strconv.Atoi(string(buffer))
 }
+
+// RandomCode returns length characters drawn with crypto/rand from an
+// alphabet without the easily confused 0, O, 1 and I. The alphabet has 32
+// characters, so every random byte maps onto it without bias.
+func RandomCode(length int) (string, error) {
+
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	buffer := make([]byte, length)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+
+	for i := 0; i < length; i++ {
+		buffer[i] = alphabet[int(buffer[i])%len(alphabet)]
+	}
+
+	return string(buffer), nil
+}
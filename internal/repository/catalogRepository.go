@@ -7,8 +7,11 @@ import (
 	"log"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrInsufficientStock = errors.New("insufficient stock")
+
 type CatalogRepository interface {
 	CreateCategory(e *domain.Category) (*domain.Category, error)
 	FindCategories() ([]domain.Category, error)
@@ -19,9 +22,25 @@ type CatalogRepository interface {
 	CreateProduct(prdct *domain.Product) (*domain.Product, error)
 	FindProduct() ([]*domain.Product, error)
 	FindProductById(id int) (*domain.Product, error)
+	FindProductsByIds(ids []int) ([]*domain.Product, error)
 	FindSellerProducts(id int) ([]*domain.Product, error)
 	UpdateProduct(prdct *domain.Product) (*domain.Product, error)
 	DeleteProduct(id int) error
+
+	//Variants
+	ReplaceProductOptions(productId uint, options []domain.ProductOption) error
+	CreateVariant(v *domain.Variant) error
+	UpdateVariant(v *domain.Variant) error
+	DeleteVariant(id uint) error
+	FindVariantBySKU(sku string) (*domain.Variant, error)
+
+	//Stock
+	FindProductsForUpdate(ids []int) ([]*domain.Product, error)
+	DecrementStock(id int, qty uint) error
+	IncrementStock(id int, qty uint) error
+	DecrementVariantStock(id uint, qty uint) error
+	IncrementVariantStock(id uint, qty uint) error
+	SyncProductStock(productId uint) error
 }
 
 type catalogRepository struct {
@@ -111,9 +130,16 @@ func (c *catalogRepository) CreateProduct(prdct *domain.Product) (*domain.Produc
 	return prdct, nil
 }
 
+// withVariants loads the options and variants of the products found
+func withVariants(db *gorm.DB) *gorm.DB {
+	return db.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Variants.Options", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+}
+
 func (c *catalogRepository) FindProduct() ([]*domain.Product, error) {
 	var allProducts []*domain.Product
-	result := c.db.Find(&allProducts)
+	result := c.db.Scopes(withVariants).Find(&allProducts)
 	if result.Error != nil {
 		log.Println("product fetching failed at db level", result.Error)
 		return nil, errors.New("fetching products failed due to some internal error")
@@ -124,7 +150,7 @@ func (c *catalogRepository) FindProduct() ([]*domain.Product, error) {
 
 func (c *catalogRepository) FindProductById(id int) (*domain.Product, error) {
 	var product *domain.Product
-	result := c.db.Where("id=?", id).First(&product)
+	result := c.db.Scopes(withVariants).Where("id=?", id).First(&product)
 	if result.Error != nil {
 		log.Println("Product fetching db error", result.Error)
 		return nil, errors.New("product fetching failed-db error")
@@ -133,10 +159,22 @@ func (c *catalogRepository) FindProductById(id int) (*domain.Product, error) {
 	return product, nil
 }
 
+func (c *catalogRepository) FindProductsByIds(ids []int) ([]*domain.Product, error) {
+	var prdcts []*domain.Product
+
+	result := c.db.Scopes(withVariants).Where("id IN ?", ids).Find(&prdcts)
+	if result.Error != nil {
+		log.Println("products by ids db error", result.Error)
+		return nil, errors.New("fetching products failed due to some internal error")
+	}
+
+	return prdcts, nil
+}
+
 func (c *catalogRepository) FindSellerProducts(id int) ([]*domain.Product, error) {
 	var prdcts []*domain.Product
 
-	result := c.db.Where("userid=?", id).Find(&prdcts)
+	result := c.db.Scopes(withVariants).Where("userid=?", id).Find(&prdcts)
 	if result.Error != nil {
 		// log.Println("finding seller products db error", result.Error)
 		return nil, fmt.Errorf("fetching sellers products failed due to -%s", result.Error)
@@ -147,7 +185,8 @@ func (c *catalogRepository) FindSellerProducts(id int) ([]*domain.Product, error
 
 func (c *catalogRepository) UpdateProduct(prdct *domain.Product) (*domain.Product, error) {
 
-	err := c.db.Save(&prdct).Error
+	//options and variants are managed on their own
+	err := c.db.Omit(clause.Associations).Save(&prdct).Error
 	if err != nil {
 		// log.Printf("product editing failed at db level due to %v", err.Error())
 		return &domain.Product{}, fmt.Errorf("product updation failed due to-%s", err.Error())
@@ -158,11 +197,203 @@ func (c *catalogRepository) UpdateProduct(prdct *domain.Product) (*domain.Produc
 
 func (c *catalogRepository) DeleteProduct(id int) error {
 
-	result := c.db.Delete(&domain.Product{}, id)
-	if result.Error != nil {
-		// log.Println("Product deletion failed at db level", result.Error)
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		variants := tx.Model(&domain.Variant{}).Select("id").Where("product_id=?", id)
+		if err := tx.Where("variant_id IN (?)", variants).Delete(&domain.VariantOption{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id=?", id).Delete(&domain.Variant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id=?", id).Delete(&domain.ProductOption{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Product{}, id).Error
+	})
+	if err != nil {
+		// log.Println("Product deletion failed at db level", err)
 		return errors.New("product deletion failed due to some internal error")
 	}
 
 	return nil
 }
+
+// FindProductsForUpdate locks the product rows until the surrounding
+// transaction ends. Rows are locked in id order so concurrent checkouts
+// wait on each other instead of deadlocking.
+func (c *catalogRepository) FindProductsForUpdate(ids []int) ([]*domain.Product, error) {
+	var prdcts []*domain.Product
+
+	result := c.db.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(withVariants).Where("id IN ?", ids).Order("id").Find(&prdcts)
+	if result.Error != nil {
+		log.Println("locking products db error", result.Error)
+		return nil, errors.New("fetching products failed due to some internal error")
+	}
+
+	return prdcts, nil
+}
+
+func (c *catalogRepository) DecrementStock(id int, qty uint) error {
+
+	result := c.db.Model(&domain.Product{}).Where("id=? AND stock>=?", id, qty).
+		Update("stock", gorm.Expr("stock - ?", qty))
+	if result.Error != nil {
+		log.Println("stock decrement db error", result.Error)
+		return errors.New("stock updation failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) IncrementStock(id int, qty uint) error {
+
+	//product may have been deleted since, nothing to restock then
+	result := c.db.Model(&domain.Product{}).Where("id=?", id).
+		Update("stock", gorm.Expr("stock + ?", qty))
+	if result.Error != nil {
+		log.Println("stock increment db error", result.Error)
+		return errors.New("stock updation failed")
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) DecrementVariantStock(id uint, qty uint) error {
+
+	var variant domain.Variant
+	result := c.db.Model(&variant).Clauses(clause.Returning{Columns: []clause.Column{{Name: "product_id"}}}).
+		Where("id=? AND stock>=?", id, qty).
+		Update("stock", gorm.Expr("stock - ?", qty))
+	if result.Error != nil {
+		log.Println("variant stock decrement db error", result.Error)
+		return errors.New("stock updation failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+
+	//the product stock is the total of its variants
+	result = c.db.Model(&domain.Product{}).Where("id=?", variant.ProductId).
+		Update("stock", gorm.Expr("GREATEST(stock - ?, 0)", qty))
+	if result.Error != nil {
+		log.Println("stock decrement db error", result.Error)
+		return errors.New("stock updation failed")
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) IncrementVariantStock(id uint, qty uint) error {
+
+	//variant may have been deleted since, nothing to restock then
+	var variant domain.Variant
+	result := c.db.Model(&variant).Clauses(clause.Returning{Columns: []clause.Column{{Name: "product_id"}}}).
+		Where("id=?", id).
+		Update("stock", gorm.Expr("stock + ?", qty))
+	if result.Error != nil {
+		log.Println("variant stock increment db error", result.Error)
+		return errors.New("stock updation failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	return c.IncrementStock(int(variant.ProductId), qty)
+}
+
+// SyncProductStock sets the product stock to the total of its variants
+func (c *catalogRepository) SyncProductStock(productId uint) error {
+
+	result := c.db.Exec(`UPDATE products SET stock = (SELECT COALESCE(SUM(stock), 0) FROM variants WHERE product_id = ?)
+		WHERE id = ?`, productId, productId)
+	if result.Error != nil {
+		log.Println("product stock sync db error", result.Error)
+		return errors.New("stock updation failed")
+	}
+
+	return nil
+}
+
+// ReplaceProductOptions swaps the option types of a product without
+// variants for a new set.
+func (c *catalogRepository) ReplaceProductOptions(productId uint, options []domain.ProductOption) error {
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id=?", productId).Delete(&domain.ProductOption{}).Error; err != nil {
+			return err
+		}
+
+		if len(options) == 0 {
+			return nil
+		}
+
+		return tx.Create(&options).Error
+	})
+	if err != nil {
+		log.Printf("product options db error %v", err)
+		return errors.New("product options updation failed")
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) CreateVariant(v *domain.Variant) error {
+
+	result := c.db.Create(v)
+	if result.Error != nil {
+		log.Printf("variant creation db error %v", result.Error)
+		return errors.New("variant creation failed")
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) UpdateVariant(v *domain.Variant) error {
+
+	result := c.db.Omit(clause.Associations).Save(v)
+	if result.Error != nil {
+		log.Printf("variant updation db error %v", result.Error)
+		return errors.New("variant updation failed")
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) DeleteVariant(id uint) error {
+
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("variant_id=?", id).Delete(&domain.VariantOption{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Variant{}, id).Error
+	})
+	if err != nil {
+		log.Printf("variant deletion db error %v", err)
+		return errors.New("variant deletion failed")
+	}
+
+	return nil
+}
+
+// FindVariantBySKU returns nil when no variant has the SKU
+func (c *catalogRepository) FindVariantBySKU(sku string) (*domain.Variant, error) {
+
+	var variant domain.Variant
+	result := c.db.Where("sku=?", sku).Limit(1).Find(&variant)
+	if result.Error != nil {
+		log.Printf("variant by sku db error %v", result.Error)
+		return nil, errors.New("variant search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &variant, nil
+}
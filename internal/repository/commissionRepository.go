@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type CommissionRepository interface {
+	CreateRule(rule *domain.CommissionRule) error
+	UpdateRule(rule *domain.CommissionRule) error
+	DeleteRule(ruleId uint) error
+	FindRule(ruleId uint) (*domain.CommissionRule, error)
+	FindRules() ([]*domain.CommissionRule, error)
+	FindActiveRules() ([]*domain.CommissionRule, error)
+	UpdateSellerTier(sellerId int, tier string) error
+}
+
+type commissionRepository struct {
+	db *gorm.DB
+}
+
+func NewCommissionRepository(db *gorm.DB) CommissionRepository {
+	return &commissionRepository{
+		db: db,
+	}
+}
+
+func (r *commissionRepository) CreateRule(rule *domain.CommissionRule) error {
+
+	result := r.db.Create(rule)
+	if result.Error != nil {
+		log.Printf("commission rule creation db error %v", result.Error)
+		return errors.New("commission rule creation failed")
+	}
+
+	return nil
+}
+
+func (r *commissionRepository) UpdateRule(rule *domain.CommissionRule) error {
+
+	result := r.db.Save(rule)
+	if result.Error != nil {
+		log.Printf("commission rule update db error %v", result.Error)
+		return errors.New("commission rule updation failed")
+	}
+
+	return nil
+}
+
+func (r *commissionRepository) DeleteRule(ruleId uint) error {
+
+	result := r.db.Delete(&domain.CommissionRule{}, ruleId)
+	if result.Error != nil {
+		log.Printf("commission rule delete db error %v", result.Error)
+		return errors.New("commission rule deletion failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("commission rule not found")
+	}
+
+	return nil
+}
+
+func (r *commissionRepository) FindRule(ruleId uint) (*domain.CommissionRule, error) {
+
+	var rule domain.CommissionRule
+	result := r.db.Where("id=?", ruleId).First(&rule)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("commission rule not found")
+		}
+		log.Printf("find commission rule db error %v", result.Error)
+		return nil, errors.New("commission rule search failed")
+	}
+
+	return &rule, nil
+}
+
+func (r *commissionRepository) FindRules() ([]*domain.CommissionRule, error) {
+
+	var rules []*domain.CommissionRule
+	result := r.db.Order("scope, id").Find(&rules)
+	if result.Error != nil {
+		log.Printf("find commission rules db error %v", result.Error)
+		return nil, errors.New("commission rule search failed")
+	}
+
+	return rules, nil
+}
+
+func (r *commissionRepository) FindActiveRules() ([]*domain.CommissionRule, error) {
+
+	var rules []*domain.CommissionRule
+	result := r.db.Where("active=?", true).Find(&rules)
+	if result.Error != nil {
+		log.Printf("find active commission rules db error %v", result.Error)
+		return nil, errors.New("commission rule search failed")
+	}
+
+	return rules, nil
+}
+
+func (r *commissionRepository) UpdateSellerTier(sellerId int, tier string) error {
+
+	result := r.db.Model(&domain.User{}).Where("id=? AND user_type=?", sellerId, domain.SELLER).Update("seller_tier", tier)
+	if result.Error != nil {
+		log.Printf("seller tier update db error %v", result.Error)
+		return errors.New("seller tier updation failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("seller not found")
+	}
+
+	return nil
+}
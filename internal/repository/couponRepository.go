@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponRepository interface {
+	CreateCoupon(coupon *domain.Coupon) error
+	UpdateCoupon(coupon *domain.Coupon) error
+	UpdateUsage(coupon *domain.Coupon) error
+	FindCoupon(couponId uint, createdBy int) (*domain.Coupon, error)
+	FindCoupons(createdBy int) ([]*domain.Coupon, error)
+	FindCouponByCode(code string) (*domain.Coupon, error)
+	FindCouponById(couponId uint) (*domain.Coupon, error)
+	FindCouponForUpdate(couponId uint) (*domain.Coupon, error)
+	UserRedemptions(couponId uint, userId int) (int64, error)
+	CreateRedemption(redemption *domain.CouponRedemption) error
+	FindOrderRedemption(orderId uint) (*domain.CouponRedemption, error)
+	DeleteRedemption(redemptionId uint) error
+	FindCartCoupon(userId int) (*domain.CartCoupon, error)
+	SaveCartCoupon(cartCoupon *domain.CartCoupon) error
+	DeleteCartCoupon(userId int) error
+}
+
+type couponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &couponRepository{
+		db: db,
+	}
+}
+
+func (r *couponRepository) CreateCoupon(coupon *domain.Coupon) error {
+
+	result := r.db.Create(coupon)
+	if result.Error != nil {
+		log.Printf("coupon creation db error %v", result.Error)
+		return errors.New("coupon creation failed")
+	}
+
+	return nil
+}
+
+// UpdateCoupon saves the coupon and replaces its scopes
+func (r *couponRepository) UpdateCoupon(coupon *domain.Coupon) error {
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Scopes").Save(coupon).Error; err != nil {
+			log.Printf("coupon update db error %v", err)
+			return errors.New("coupon updation failed")
+		}
+
+		if err := tx.Where("coupon_id=?", coupon.ID).Delete(&domain.CouponScope{}).Error; err != nil {
+			log.Printf("coupon scopes delete db error %v", err)
+			return errors.New("coupon updation failed")
+		}
+
+		for i := range coupon.Scopes {
+			coupon.Scopes[i].ID = 0
+			coupon.Scopes[i].CouponId = coupon.ID
+		}
+
+		if len(coupon.Scopes) > 0 {
+			if err := tx.Create(&coupon.Scopes).Error; err != nil {
+				log.Printf("coupon scopes creation db error %v", err)
+				return errors.New("coupon updation failed")
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *couponRepository) UpdateUsage(coupon *domain.Coupon) error {
+
+	result := r.db.Model(coupon).Update("used", coupon.Used)
+	if result.Error != nil {
+		log.Printf("coupon usage update db error %v", result.Error)
+		return errors.New("coupon updation failed")
+	}
+
+	return nil
+}
+
+func (r *couponRepository) FindCoupon(couponId uint, createdBy int) (*domain.Coupon, error) {
+
+	var coupon domain.Coupon
+	result := r.db.Preload("Scopes").Where("id=? AND created_by=?", couponId, createdBy).First(&coupon)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("coupon not found")
+		}
+		log.Printf("find coupon db error %v", result.Error)
+		return nil, errors.New("coupon search failed")
+	}
+
+	return &coupon, nil
+}
+
+func (r *couponRepository) FindCoupons(createdBy int) ([]*domain.Coupon, error) {
+
+	var coupons []*domain.Coupon
+	result := r.db.Preload("Scopes").Where("created_by=?", createdBy).Order("id desc").Find(&coupons)
+	if result.Error != nil {
+		log.Printf("find coupons db error %v", result.Error)
+		return nil, errors.New("coupons search failed")
+	}
+
+	return coupons, nil
+}
+
+// FindCouponByCode returns nil when no coupon has the code
+func (r *couponRepository) FindCouponByCode(code string) (*domain.Coupon, error) {
+
+	var coupon domain.Coupon
+	result := r.db.Preload("Scopes").Where("code=?", code).Limit(1).Find(&coupon)
+	if result.Error != nil {
+		log.Printf("find coupon by code db error %v", result.Error)
+		return nil, errors.New("coupon search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &coupon, nil
+}
+
+func (r *couponRepository) FindCouponById(couponId uint) (*domain.Coupon, error) {
+
+	var coupon domain.Coupon
+	result := r.db.Preload("Scopes").First(&coupon, couponId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("coupon not found")
+		}
+		log.Printf("find coupon db error %v", result.Error)
+		return nil, errors.New("coupon search failed")
+	}
+
+	return &coupon, nil
+}
+
+// FindCouponForUpdate locks the coupon so usage counts can't race
+func (r *couponRepository) FindCouponForUpdate(couponId uint) (*domain.Coupon, error) {
+
+	var coupon domain.Coupon
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, couponId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("coupon not found")
+		}
+		log.Printf("find coupon for update db error %v", result.Error)
+		return nil, errors.New("coupon search failed")
+	}
+
+	return &coupon, nil
+}
+
+func (r *couponRepository) UserRedemptions(couponId uint, userId int) (int64, error) {
+
+	var count int64
+	result := r.db.Model(&domain.CouponRedemption{}).Where("coupon_id=? AND user_id=?", couponId, userId).Count(&count)
+	if result.Error != nil {
+		log.Printf("count coupon redemptions db error %v", result.Error)
+		return 0, errors.New("coupon redemption search failed")
+	}
+
+	return count, nil
+}
+
+func (r *couponRepository) CreateRedemption(redemption *domain.CouponRedemption) error {
+
+	result := r.db.Create(redemption)
+	if result.Error != nil {
+		log.Printf("coupon redemption creation db error %v", result.Error)
+		return errors.New("coupon redemption failed")
+	}
+
+	return nil
+}
+
+// FindOrderRedemption returns nil when the order used no coupon
+func (r *couponRepository) FindOrderRedemption(orderId uint) (*domain.CouponRedemption, error) {
+
+	var redemption domain.CouponRedemption
+	result := r.db.Where("order_id=?", orderId).Limit(1).Find(&redemption)
+	if result.Error != nil {
+		log.Printf("find order redemption db error %v", result.Error)
+		return nil, errors.New("coupon redemption search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &redemption, nil
+}
+
+func (r *couponRepository) DeleteRedemption(redemptionId uint) error {
+
+	result := r.db.Delete(&domain.CouponRedemption{}, redemptionId)
+	if result.Error != nil {
+		log.Printf("delete coupon redemption db error %v", result.Error)
+		return errors.New("coupon redemption deletion failed")
+	}
+
+	return nil
+}
+
+// FindCartCoupon returns nil when the buyer applied no coupon
+func (r *couponRepository) FindCartCoupon(userId int) (*domain.CartCoupon, error) {
+
+	var cartCoupon domain.CartCoupon
+	result := r.db.Where("user_id=?", userId).Limit(1).Find(&cartCoupon)
+	if result.Error != nil {
+		log.Printf("find cart coupon db error %v", result.Error)
+		return nil, errors.New("cart coupon search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &cartCoupon, nil
+}
+
+func (r *couponRepository) SaveCartCoupon(cartCoupon *domain.CartCoupon) error {
+
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"coupon_id"}),
+	}).Create(cartCoupon)
+	if result.Error != nil {
+		log.Printf("save cart coupon db error %v", result.Error)
+		return errors.New("applying coupon failed")
+	}
+
+	return nil
+}
+
+func (r *couponRepository) DeleteCartCoupon(userId int) error {
+
+	result := r.db.Where("user_id=?", userId).Delete(&domain.CartCoupon{})
+	if result.Error != nil {
+		log.Printf("delete cart coupon db error %v", result.Error)
+		return errors.New("removing coupon failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GiftCardRepository interface {
+	CreateGiftCard(card *domain.GiftCard) error
+	UpdateGiftCard(card *domain.GiftCard) error
+	FindGiftCardByCode(code string) (*domain.GiftCard, error)
+	FindGiftCardForUpdate(id uint) (*domain.GiftCard, error)
+	FindOrderGiftCards(orderId uint) ([]*domain.GiftCard, error)
+	FindPurchasedGiftCards(userId int) ([]*domain.GiftCard, error)
+	FindGiftCards(batch string) ([]*domain.GiftCard, error)
+
+	CreateTransaction(txn *domain.GiftCardTransaction) error
+	FindTransactions(giftCardId uint) ([]*domain.GiftCardTransaction, error)
+	FindOrderTransactions(orderId uint) ([]*domain.GiftCardTransaction, error)
+
+	FindCartGiftCards(userId int) ([]*domain.GiftCard, error)
+	LockCartGiftCards(userId int) ([]*domain.GiftCard, error)
+	AddCartGiftCard(userId int, giftCardId uint) error
+	RemoveCartGiftCard(userId int, giftCardId uint) error
+	DeleteCartGiftCards(userId int) error
+}
+
+type giftCardRepository struct {
+	db *gorm.DB
+}
+
+func NewGiftCardRepository(db *gorm.DB) GiftCardRepository {
+	return &giftCardRepository{
+		db: db,
+	}
+}
+
+func (r *giftCardRepository) CreateGiftCard(card *domain.GiftCard) error {
+
+	result := r.db.Create(card)
+	if result.Error != nil {
+		log.Printf("gift card creation db error %v", result.Error)
+		return errors.New("gift card creation failed")
+	}
+
+	return nil
+}
+
+func (r *giftCardRepository) UpdateGiftCard(card *domain.GiftCard) error {
+
+	result := r.db.Save(card)
+	if result.Error != nil {
+		log.Printf("gift card update db error %v", result.Error)
+		return errors.New("gift card updation failed")
+	}
+
+	return nil
+}
+
+// FindGiftCardByCode returns nil when no card has the code
+func (r *giftCardRepository) FindGiftCardByCode(code string) (*domain.GiftCard, error) {
+
+	var card domain.GiftCard
+	result := r.db.Where("code=?", code).Limit(1).Find(&card)
+	if result.Error != nil {
+		log.Printf("find gift card db error %v", result.Error)
+		return nil, errors.New("gift card search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &card, nil
+}
+
+func (r *giftCardRepository) FindGiftCardForUpdate(id uint) (*domain.GiftCard, error) {
+
+	var card domain.GiftCard
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&card, id)
+	if result.Error != nil {
+		log.Printf("lock gift card db error %v", result.Error)
+		return nil, errors.New("gift card not found")
+	}
+
+	return &card, nil
+}
+
+// FindOrderGiftCards returns the cards bought with the order
+func (r *giftCardRepository) FindOrderGiftCards(orderId uint) ([]*domain.GiftCard, error) {
+
+	var cards []*domain.GiftCard
+	result := r.db.Where("order_id=?", orderId).Order("id").Find(&cards)
+	if result.Error != nil {
+		log.Printf("find order gift cards db error %v", result.Error)
+		return nil, errors.New("gift card search failed")
+	}
+
+	return cards, nil
+}
+
+func (r *giftCardRepository) FindPurchasedGiftCards(userId int) ([]*domain.GiftCard, error) {
+
+	var cards []*domain.GiftCard
+	result := r.db.Where("purchaser_id=?", userId).Order("id DESC").Find(&cards)
+	if result.Error != nil {
+		log.Printf("find purchased gift cards db error %v", result.Error)
+		return nil, errors.New("gift card search failed")
+	}
+
+	return cards, nil
+}
+
+// FindGiftCards returns the cards issued in a batch, every card when batch
+// is empty
+func (r *giftCardRepository) FindGiftCards(batch string) ([]*domain.GiftCard, error) {
+
+	var cards []*domain.GiftCard
+	query := r.db.Order("id DESC")
+	if len(batch) > 0 {
+		query = query.Where("batch=?", batch)
+	}
+
+	result := query.Find(&cards)
+	if result.Error != nil {
+		log.Printf("find gift cards db error %v", result.Error)
+		return nil, errors.New("gift card search failed")
+	}
+
+	return cards, nil
+}
+
+func (r *giftCardRepository) CreateTransaction(txn *domain.GiftCardTransaction) error {
+
+	result := r.db.Create(txn)
+	if result.Error != nil {
+		log.Printf("gift card transaction creation db error %v", result.Error)
+		return errors.New("gift card transaction creation failed")
+	}
+
+	return nil
+}
+
+func (r *giftCardRepository) FindTransactions(giftCardId uint) ([]*domain.GiftCardTransaction, error) {
+
+	var txns []*domain.GiftCardTransaction
+	result := r.db.Where("gift_card_id=?", giftCardId).Order("id DESC").Find(&txns)
+	if result.Error != nil {
+		log.Printf("find gift card transactions db error %v", result.Error)
+		return nil, errors.New("gift card transaction search failed")
+	}
+
+	return txns, nil
+}
+
+// FindOrderTransactions returns what the order took from and gave back to
+// gift cards, oldest first
+func (r *giftCardRepository) FindOrderTransactions(orderId uint) ([]*domain.GiftCardTransaction, error) {
+
+	var txns []*domain.GiftCardTransaction
+	result := r.db.Where("order_id=? AND kind IN ?", orderId, []string{domain.GIFT_CARD_REDEEM, domain.GIFT_CARD_REFUND}).Order("id").Find(&txns)
+	if result.Error != nil {
+		log.Printf("find order gift card transactions db error %v", result.Error)
+		return nil, errors.New("gift card transaction search failed")
+	}
+
+	return txns, nil
+}
+
+// FindCartGiftCards returns the cards the buyer redeems against their cart,
+// in the order they were added
+func (r *giftCardRepository) FindCartGiftCards(userId int) ([]*domain.GiftCard, error) {
+
+	var cards []*domain.GiftCard
+	result := r.db.Joins("JOIN cart_gift_cards ON cart_gift_cards.gift_card_id = gift_cards.id").
+		Where("cart_gift_cards.user_id=?", userId).Order("cart_gift_cards.id").Find(&cards)
+	if result.Error != nil {
+		log.Printf("find cart gift cards db error %v", result.Error)
+		return nil, errors.New("gift card search failed")
+	}
+
+	return cards, nil
+}
+
+// LockCartGiftCards is FindCartGiftCards with the card rows locked, so
+// their balances hold until checkout has spent them
+func (r *giftCardRepository) LockCartGiftCards(userId int) ([]*domain.GiftCard, error) {
+
+	var cards []*domain.GiftCard
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "gift_cards"}}).
+		Joins("JOIN cart_gift_cards ON cart_gift_cards.gift_card_id = gift_cards.id").
+		Where("cart_gift_cards.user_id=?", userId).Order("gift_cards.id").Find(&cards)
+	if result.Error != nil {
+		log.Printf("lock cart gift cards db error %v", result.Error)
+		return nil, errors.New("gift card search failed")
+	}
+
+	return cards, nil
+}
+
+func (r *giftCardRepository) AddCartGiftCard(userId int, giftCardId uint) error {
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&domain.CartGiftCard{UserId: userId, GiftCardId: giftCardId})
+	if result.Error != nil {
+		log.Printf("add cart gift card db error %v", result.Error)
+		return errors.New("redeeming the gift card failed")
+	}
+
+	return nil
+}
+
+func (r *giftCardRepository) RemoveCartGiftCard(userId int, giftCardId uint) error {
+
+	result := r.db.Where("user_id=? AND gift_card_id=?", userId, giftCardId).Delete(&domain.CartGiftCard{})
+	if result.Error != nil {
+		log.Printf("remove cart gift card db error %v", result.Error)
+		return errors.New("removing the gift card from the cart failed")
+	}
+
+	return nil
+}
+
+func (r *giftCardRepository) DeleteCartGiftCards(userId int) error {
+
+	result := r.db.Where("user_id=?", userId).Delete(&domain.CartGiftCard{})
+	if result.Error != nil {
+		log.Printf("delete cart gift cards db error %v", result.Error)
+		return errors.New("removing the gift cards from the cart failed")
+	}
+
+	return nil
+}
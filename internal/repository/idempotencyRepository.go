@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	ReserveKey(key *domain.IdempotencyKey) (bool, error)
+	FindKey(userId int, key string) (*domain.IdempotencyKey, error)
+	CompleteKey(key *domain.IdempotencyKey) error
+	DeleteKey(id uint) error
+	DeleteExpiredKeys(now time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{
+		db: db,
+	}
+}
+
+// ReserveKey stores the key as in progress, false when the user already
+// has it
+func (r *idempotencyRepository) ReserveKey(key *domain.IdempotencyKey) (bool, error) {
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		log.Printf("idempotency key creation db error %v", result.Error)
+		return false, errors.New("idempotency key creation failed")
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// FindKey returns nil when the user has no such key
+func (r *idempotencyRepository) FindKey(userId int, key string) (*domain.IdempotencyKey, error) {
+
+	var found domain.IdempotencyKey
+	result := r.db.Where("user_id=? AND key=?", userId, key).Limit(1).Find(&found)
+	if result.Error != nil {
+		log.Printf("find idempotency key db error %v", result.Error)
+		return nil, errors.New("idempotency key search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &found, nil
+}
+
+func (r *idempotencyRepository) CompleteKey(key *domain.IdempotencyKey) error {
+
+	result := r.db.Model(key).Updates(map[string]interface{}{
+		"status":          domain.IDEMPOTENCY_COMPLETED,
+		"response_status": key.ResponseStatus,
+		"content_type":    key.ContentType,
+		"response_body":   key.ResponseBody,
+	})
+	if result.Error != nil {
+		log.Printf("idempotency key update db error %v", result.Error)
+		return errors.New("idempotency key updation failed")
+	}
+
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteKey(id uint) error {
+
+	result := r.db.Delete(&domain.IdempotencyKey{}, id)
+	if result.Error != nil {
+		log.Printf("delete idempotency key db error %v", result.Error)
+		return errors.New("idempotency key deletion failed")
+	}
+
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteExpiredKeys(now time.Time) (int64, error) {
+
+	result := r.db.Where("expires_at<?", now).Delete(&domain.IdempotencyKey{})
+	if result.Error != nil {
+		log.Printf("delete expired idempotency keys db error %v", result.Error)
+		return 0, errors.New("idempotency key deletion failed")
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type InvoiceRepository interface {
+	NextInvoiceNumber() (string, error)
+	CreateInvoice(invoice *domain.Invoice) error
+	FindInvoice(orderId uint, fulfilmentId uint) (*domain.Invoice, error)
+}
+
+type invoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &invoiceRepository{
+		db: db,
+	}
+}
+
+// NextInvoiceNumber takes the next number from the invoice sequence.
+// Numbers of rolled back transactions are not reused.
+func (r *invoiceRepository) NextInvoiceNumber() (string, error) {
+
+	var next int64
+	if err := r.db.Raw("SELECT nextval('invoice_number_seq')").Scan(&next).Error; err != nil {
+		log.Printf("next invoice number db error %v", err)
+		return "", errors.New("invoice numbering failed")
+	}
+
+	return fmt.Sprintf("INV-%06d", next), nil
+}
+
+func (r *invoiceRepository) CreateInvoice(invoice *domain.Invoice) error {
+
+	result := r.db.Create(invoice)
+	if result.Error != nil {
+		log.Printf("invoice creation db error %v", result.Error)
+		return errors.New("invoice creation failed")
+	}
+
+	return nil
+}
+
+// FindInvoice returns nil when the invoice was not issued yet
+func (r *invoiceRepository) FindInvoice(orderId uint, fulfilmentId uint) (*domain.Invoice, error) {
+
+	var invoice domain.Invoice
+	result := r.db.Where("order_id=? AND fulfilment_id=?", orderId, fulfilmentId).Limit(1).Find(&invoice)
+	if result.Error != nil {
+		log.Printf("find invoice db error %v", result.Error)
+		return nil, errors.New("invoice search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &invoice, nil
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LedgerRepository interface {
+	CreateEntry(entry *domain.LedgerEntry) (bool, error)
+	FindEntry(reference string) (*domain.LedgerEntry, error)
+	FindSellerBalances(sellerId int, cutoff time.Time) ([]domain.SellerBalance, error)
+	FindPayableBalances(cutoff time.Time) ([]domain.SellerBalance, error)
+	FindCommissionSummaries(from time.Time, to time.Time) ([]domain.CommissionSummary, error)
+
+	FindBankAccountForUpdate(sellerId int) (*domain.BankAccount, error)
+	FindBankAccount(id int) (*domain.BankAccount, error)
+
+	CreatePayout(payout *domain.Payout) error
+	UpdatePayout(payout *domain.Payout) error
+	FindPayouts(sellerId int) ([]*domain.Payout, error)
+	FindPendingPayouts() ([]*domain.Payout, error)
+	FindPayoutForUpdate(id uint) (*domain.Payout, error)
+}
+
+type ledgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) LedgerRepository {
+	return &ledgerRepository{
+		db: db,
+	}
+}
+
+// CreateEntry books the entry with its postings, it reports false when an
+// entry with the same reference was booked already
+func (r *ledgerRepository) CreateEntry(entry *domain.LedgerEntry) (bool, error) {
+
+	var total int64
+	for _, p := range entry.Postings {
+		total += p.Amount.Amount
+	}
+	if total != 0 {
+		log.Printf("ledger entry %s is unbalanced by %d", entry.Reference, total)
+		return false, errors.New("ledger entry does not balance")
+	}
+
+	postings := entry.Postings
+	result := r.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "reference"}}, DoNothing: true}).Omit("Postings").Create(entry)
+	if result.Error != nil {
+		log.Printf("ledger entry creation db error %v", result.Error)
+		return false, errors.New("ledger entry creation failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	for i := range postings {
+		postings[i].EntryId = entry.ID
+	}
+
+	if len(postings) > 0 {
+		if err := r.db.Create(&postings).Error; err != nil {
+			log.Printf("ledger postings creation db error %v", err)
+			return false, errors.New("ledger entry creation failed")
+		}
+	}
+
+	return true, nil
+}
+
+// FindEntry returns nil when nothing was booked under the reference
+func (r *ledgerRepository) FindEntry(reference string) (*domain.LedgerEntry, error) {
+
+	var entry domain.LedgerEntry
+	result := r.db.Preload("Postings").Where("reference=?", reference).Limit(1).Find(&entry)
+	if result.Error != nil {
+		log.Printf("find ledger entry db error %v", result.Error)
+		return nil, errors.New("ledger entry search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+type balanceRow struct {
+	SellerId  int
+	Currency  string
+	Balance   int64
+	Available int64
+}
+
+// sellerBalances sums the seller accounts per currency, sales booked after
+// cutoff are not available yet
+func (r *ledgerRepository) sellerBalances(cutoff time.Time) *gorm.DB {
+	return r.db.Table("ledger_postings p").
+		Select(`p.owner_id AS seller_id, p.amount_currency AS currency, SUM(p.amount_minor) AS balance,
+			SUM(CASE WHEN e.kind = ? AND p.created_at > ? THEN 0 ELSE p.amount_minor END) AS available`, domain.ENTRY_SALE, cutoff).
+		Joins("JOIN ledger_entries e ON e.id = p.entry_id").
+		Where("p.account=?", domain.ACCOUNT_SELLER).
+		Group("p.owner_id, p.amount_currency")
+}
+
+func toSellerBalances(rows []balanceRow) []domain.SellerBalance {
+	balances := []domain.SellerBalance{}
+	for _, row := range rows {
+		balances = append(balances, domain.SellerBalance{
+			SellerId:  row.SellerId,
+			Balance:   domain.NewMoney(row.Balance, row.Currency),
+			Available: domain.NewMoney(row.Available, row.Currency),
+		})
+	}
+	return balances
+}
+
+func (r *ledgerRepository) FindSellerBalances(sellerId int, cutoff time.Time) ([]domain.SellerBalance, error) {
+
+	var rows []balanceRow
+	err := r.sellerBalances(cutoff).Where("p.owner_id=?", sellerId).Order("p.amount_currency").Scan(&rows).Error
+	if err != nil {
+		log.Printf("seller balance db error %v", err)
+		return nil, errors.New("balance search failed")
+	}
+
+	return toSellerBalances(rows), nil
+}
+
+// FindPayableBalances returns the balances of every seller with money
+// available to pay out
+func (r *ledgerRepository) FindPayableBalances(cutoff time.Time) ([]domain.SellerBalance, error) {
+
+	var rows []balanceRow
+	err := r.sellerBalances(cutoff).
+		Having("SUM(CASE WHEN e.kind = ? AND p.created_at > ? THEN 0 ELSE p.amount_minor END) > 0", domain.ENTRY_SALE, cutoff).
+		Order("p.owner_id").Scan(&rows).Error
+	if err != nil {
+		log.Printf("payable balances db error %v", err)
+		return nil, errors.New("balance search failed")
+	}
+
+	return toSellerBalances(rows), nil
+}
+
+type commissionRow struct {
+	SellerId int
+	Currency string
+	Earned   int64
+	Reversed int64
+}
+
+// FindCommissionSummaries sums the commission booked from from up to, not
+// including, to per seller and currency
+func (r *ledgerRepository) FindCommissionSummaries(from time.Time, to time.Time) ([]domain.CommissionSummary, error) {
+
+	var rows []commissionRow
+	err := r.db.Table("ledger_postings").
+		Select(`owner_id AS seller_id, amount_currency AS currency,
+			SUM(CASE WHEN amount_minor > 0 THEN amount_minor ELSE 0 END) AS earned,
+			SUM(CASE WHEN amount_minor < 0 THEN -amount_minor ELSE 0 END) AS reversed`).
+		Where("account=? AND created_at >= ? AND created_at < ?", domain.ACCOUNT_COMMISSION, from, to).
+		Group("owner_id, amount_currency").
+		Order("owner_id, amount_currency").
+		Scan(&rows).Error
+	if err != nil {
+		log.Printf("commission summary db error %v", err)
+		return nil, errors.New("commission report failed")
+	}
+
+	summaries := []domain.CommissionSummary{}
+	for _, row := range rows {
+		earned := domain.NewMoney(row.Earned, row.Currency)
+		reversed := domain.NewMoney(row.Reversed, row.Currency)
+		summaries = append(summaries, domain.CommissionSummary{
+			SellerId: row.SellerId,
+			Earned:   earned,
+			Reversed: reversed,
+			Net:      earned.Sub(reversed),
+		})
+	}
+
+	return summaries, nil
+}
+
+// FindBankAccountForUpdate returns nil when the seller has no bank account
+func (r *ledgerRepository) FindBankAccountForUpdate(sellerId int) (*domain.BankAccount, error) {
+
+	var account domain.BankAccount
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id=?", sellerId).Order("id DESC").Limit(1).Find(&account)
+	if result.Error != nil {
+		log.Printf("find bank account db error %v", result.Error)
+		return nil, errors.New("bank account search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &account, nil
+}
+
+func (r *ledgerRepository) FindBankAccount(id int) (*domain.BankAccount, error) {
+
+	var account domain.BankAccount
+	result := r.db.Where("id=?", id).First(&account)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bank account not found")
+		}
+		log.Printf("find bank account db error %v", result.Error)
+		return nil, errors.New("bank account search failed")
+	}
+
+	return &account, nil
+}
+
+func (r *ledgerRepository) CreatePayout(payout *domain.Payout) error {
+
+	result := r.db.Create(payout)
+	if result.Error != nil {
+		log.Printf("payout creation db error %v", result.Error)
+		return errors.New("payout creation failed")
+	}
+
+	return nil
+}
+
+func (r *ledgerRepository) UpdatePayout(payout *domain.Payout) error {
+
+	result := r.db.Save(payout)
+	if result.Error != nil {
+		log.Printf("payout update db error %v", result.Error)
+		return errors.New("payout update failed")
+	}
+
+	return nil
+}
+
+func (r *ledgerRepository) FindPayouts(sellerId int) ([]*domain.Payout, error) {
+
+	var payouts []*domain.Payout
+	result := r.db.Where("seller_id=?", sellerId).Order("created_at DESC").Find(&payouts)
+	if result.Error != nil {
+		log.Printf("find payouts db error %v", result.Error)
+		return nil, errors.New("payout search failed")
+	}
+
+	return payouts, nil
+}
+
+func (r *ledgerRepository) FindPendingPayouts() ([]*domain.Payout, error) {
+
+	var payouts []*domain.Payout
+	result := r.db.Where("status=?", domain.PAYOUT_PENDING).Order("id").Find(&payouts)
+	if result.Error != nil {
+		log.Printf("find pending payouts db error %v", result.Error)
+		return nil, errors.New("payout search failed")
+	}
+
+	return payouts, nil
+}
+
+func (r *ledgerRepository) FindPayoutForUpdate(id uint) (*domain.Payout, error) {
+
+	var payout domain.Payout
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id=?", id).First(&payout)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payout not found")
+		}
+		log.Printf("find payout for update db error %v", result.Error)
+		return nil, errors.New("payout search failed")
+	}
+
+	return &payout, nil
+}
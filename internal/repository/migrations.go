@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// dataMigrations fix up existing rows after AutoMigrate has changed the
+// schema. Each statement must be safe to run on every start.
+var dataMigrations = []string{
+	//orders placed before the lifecycle states existed
+	`UPDATE orders SET status = 'pending_payment' WHERE status IS NULL OR status = ''`,
+	`UPDATE order_items SET status = 'active' WHERE status IS NULL OR status = ''`,
+	//one fulfilment per seller for orders placed before fulfilments existed
+	`INSERT INTO fulfilments (order_id, seller_id, status, created_at, updated_at)
+	SELECT oi.order_id, oi.seller_id,
+		CASE
+			WHEN bool_and(oi.status = 'cancelled') THEN 'cancelled'
+			WHEN o.status IN ('processing', 'shipped', 'delivered') THEN o.status
+			WHEN o.status = 'refunded' THEN 'delivered'
+			ELSE 'pending'
+		END,
+		o.created_at, o.created_at
+	FROM order_items oi
+	JOIN orders o ON o.id = oi.order_id
+	WHERE COALESCE(oi.fulfilment_id, 0) = 0
+		AND NOT EXISTS (SELECT 1 FROM fulfilments f WHERE f.order_id = oi.order_id AND f.seller_id = oi.seller_id)
+	GROUP BY oi.order_id, oi.seller_id, o.status, o.created_at`,
+	`UPDATE order_items oi SET fulfilment_id = f.id FROM fulfilments f
+	WHERE f.order_id = oi.order_id AND f.seller_id = oi.seller_id AND COALESCE(oi.fulfilment_id, 0) = 0`,
+	//invoice numbers
+	`CREATE SEQUENCE IF NOT EXISTS invoice_number_seq`,
+	//last line of defence for concurrent wallet spending
+	`ALTER TABLE wallets DROP CONSTRAINT IF EXISTS chk_wallet_balance, ADD CONSTRAINT chk_wallet_balance CHECK (balance_minor >= 0)`,
+	`ALTER TABLE gift_cards DROP CONSTRAINT IF EXISTS chk_gift_card_balance, ADD CONSTRAINT chk_gift_card_balance CHECK (balance_minor >= 0)`,
+	`UPDATE products SET type = 'standard' WHERE type IS NULL OR type = ''`,
+	`UPDATE order_items SET gift_card = false WHERE gift_card IS NULL`,
+	//cart lines and items from before variants
+	`UPDATE carts SET variant_id = 0, sku = '' WHERE variant_id IS NULL`,
+	`UPDATE order_items SET variant_id = 0, sku = '' WHERE variant_id IS NULL`,
+	//product search, see ProductSearch
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(description, '')), 'B')) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_products_search ON products USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops)`,
+}
+
+// floatMoneyColumns are the float64 amount columns replaced by the
+// <column>_minor and <column>_currency pair of domain.Money
+var floatMoneyColumns = []struct {
+	table  string
+	column string
+}{
+	{"products", "price"},
+	{"carts", "price"},
+	{"order_items", "price"},
+	{"orders", "amount"},
+	{"payments", "amount"},
+}
+
+// moneyDataMigrations run once the money columns are in minor units
+var moneyDataMigrations = []string{
+	//orders placed before shipping was charged
+	`UPDATE orders SET subtotal_minor = amount_minor, subtotal_currency = amount_currency,
+		shipping_minor = 0, shipping_currency = amount_currency
+	WHERE subtotal_currency IS NULL AND amount_currency IS NOT NULL`,
+	//orders placed before tax was charged
+	`UPDATE orders SET tax_minor = 0, tax_currency = amount_currency
+	WHERE tax_currency IS NULL AND amount_currency IS NOT NULL`,
+	//orders placed before coupons
+	`UPDATE orders SET discount_minor = 0, discount_currency = amount_currency
+	WHERE discount_currency IS NULL AND amount_currency IS NOT NULL`,
+	`UPDATE order_items SET discount_minor = 0, discount_currency = price_currency
+	WHERE discount_currency IS NULL AND price_currency IS NOT NULL`,
+	//items sold before commission was taken
+	`UPDATE order_items SET commission_minor = 0, commission_currency = price_currency
+	WHERE commission_currency IS NULL AND price_currency IS NOT NULL`,
+	`UPDATE order_items SET commission_rate = 0 WHERE commission_rate IS NULL`,
+	//orders placed before wallets
+	`UPDATE orders SET wallet_minor = 0, wallet_currency = amount_currency,
+		walletrefunded_minor = 0, walletrefunded_currency = amount_currency
+	WHERE wallet_currency IS NULL AND amount_currency IS NOT NULL`,
+	`UPDATE refunds SET wallet_minor = 0, wallet_currency = amount_currency
+	WHERE wallet_currency IS NULL AND amount_currency IS NOT NULL`,
+	//orders placed before gift cards
+	`UPDATE orders SET giftcard_minor = 0, giftcard_currency = amount_currency,
+		giftcardrefunded_minor = 0, giftcardrefunded_currency = amount_currency
+	WHERE giftcard_currency IS NULL AND amount_currency IS NOT NULL`,
+	`UPDATE refunds SET giftcard_minor = 0, giftcard_currency = amount_currency
+	WHERE giftcard_currency IS NULL AND amount_currency IS NOT NULL`,
+	//orders placed before prices were listed in other currencies
+	`UPDATE order_items SET listprice_minor = price_minor, listprice_currency = price_currency
+	WHERE listprice_currency IS NULL AND price_currency IS NOT NULL`,
+	`UPDATE orders SET display_currency = amount_currency, displaytotal_minor = amount_minor,
+		displaytotal_currency = amount_currency
+	WHERE displaytotal_currency IS NULL AND amount_currency IS NOT NULL`,
+}
+
+func DataMigrations(db *gorm.DB, currency string) error {
+	for _, stmt := range dataMigrations {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("data migration failed %w", err)
+		}
+	}
+
+	if err := migrateMoneyColumns(db, currency); err != nil {
+		return err
+	}
+
+	for _, stmt := range moneyDataMigrations {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("data migration failed %w", err)
+		}
+	}
+
+	return nil
+}
+
+// migrateMoneyColumns converts the old float amounts into minor units of
+// the configured currency and drops the float column, so it only does work
+// on the first start after the upgrade.
+func migrateMoneyColumns(db *gorm.DB, currency string) error {
+	factor := 1
+	for i := 0; i < domain.CurrencyDecimals(currency); i++ {
+		factor *= 10
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, c := range floatMoneyColumns {
+			if !tx.Migrator().HasColumn(c.table, c.column) {
+				continue
+			}
+
+			stmt := fmt.Sprintf(`UPDATE %s SET %s_minor = ROUND(%s * %d)::bigint, %s_currency = ? WHERE %s IS NOT NULL`,
+				c.table, c.column, c.column, factor, c.column, c.column)
+			if err := tx.Exec(stmt, currency).Error; err != nil {
+				return fmt.Errorf("converting %s.%s failed %w", c.table, c.column, err)
+			}
+
+			if err := tx.Migrator().DropColumn(c.table, c.column); err != nil {
+				return fmt.Errorf("dropping %s.%s failed %w", c.table, c.column, err)
+			}
+		}
+		return nil
+	})
+}
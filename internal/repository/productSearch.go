@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// SIMILARITY_THRESHOLD is how close, by trigrams, a query has to be to a
+// product name to match it without a full-text hit, so typos still find it.
+const SIMILARITY_THRESHOLD = 0.35
+
+// highlighted matches in names and snippets
+const (
+	HIGHLIGHT_START = "<mark>"
+	HIGHLIGHT_STOP  = "</mark>"
+)
+
+type SearchQuery struct {
+	Text       string
+	CategoryId uint
+	Limit      int
+	Offset     int
+}
+
+// SearchHit is one matching product, best first. Name and Snippet are HTML
+// escaped and carry the matched words between HIGHLIGHT_START and
+// HIGHLIGHT_STOP.
+type SearchHit struct {
+	ProductId uint
+	Rank      float64
+	Name      string
+	Snippet   string
+}
+
+type SearchResult struct {
+	Hits  []SearchHit
+	Total int64
+}
+
+// ProductSearch finds products by free text. Postgres full-text search
+// backs it, a dedicated search engine can take its place by implementing
+// it.
+type ProductSearch interface {
+	Search(query SearchQuery) (*SearchResult, error)
+}
+
+type postgresProductSearch struct {
+	db *gorm.DB
+}
+
+func NewProductSearch(db *gorm.DB) ProductSearch {
+	return &postgresProductSearch{
+		db: db,
+	}
+}
+
+// rankDocument is what matches are ranked on: the indexed name and
+// description, and the category name weighted lowest.
+const rankDocument = `(p.search_vector || setweight(to_tsvector('english', coalesce(c.name, '')), 'C'))`
+
+// escapeHTML escapes seller text in SQL before ts_headline marks it up, so
+// only the highlight tags reach the page as markup
+func escapeHTML(column string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&quot;"}, {"''", "&#39;"}} {
+		column = fmt.Sprintf("replace(%s, '%s', '%s')", column, r[0], r[1])
+	}
+	return column
+}
+
+// Search ranks full-text matches over name, description and category
+// name, the last word matching as a prefix for autocomplete, and names
+// close enough by trigram similarity.
+func (r *postgresProductSearch) Search(query SearchQuery) (*SearchResult, error) {
+
+	tsQuery := prefixQuery(query.Text)
+	headline := "StartSel=" + HIGHLIGHT_START + ", StopSel=" + HIGHLIGHT_STOP
+
+	sql := `WITH q AS (SELECT to_tsquery('english', @tsquery) AS query)
+	SELECT p.id AS product_id,
+		ts_rank_cd(` + rankDocument + `, q.query) * 2 + word_similarity(@text, p.name) AS rank,
+		ts_headline('english', ` + escapeHTML("p.name") + `, q.query, @namehl) AS name,
+		ts_headline('english', ` + escapeHTML("coalesce(p.description, '')") + `, q.query, @snippethl) AS snippet,
+		count(*) OVER () AS total
+	FROM products p
+	CROSS JOIN q
+	LEFT JOIN categories c ON c.id = p.category_id
+	WHERE (p.search_vector @@ q.query
+		OR to_tsvector('english', coalesce(c.name, '')) @@ q.query
+		OR @text <% p.name)`
+
+	args := map[string]interface{}{
+		"tsquery":   tsQuery,
+		"text":      query.Text,
+		"namehl":    headline + ", HighlightAll=true",
+		"snippethl": headline + ", MaxWords=25, MinWords=10, MaxFragments=2",
+		"limit":     query.Limit,
+		"offset":    query.Offset,
+	}
+
+	if query.CategoryId > 0 {
+		sql += ` AND p.category_id = @category`
+		args["category"] = query.CategoryId
+	}
+
+	sql += ` ORDER BY rank DESC, p.id LIMIT @limit OFFSET @offset`
+
+	var rows []struct {
+		SearchHit
+		Total int64
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		//<% matches on this threshold and can use the trigram index
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL pg_trgm.word_similarity_threshold = %g", SIMILARITY_THRESHOLD)).Error; err != nil {
+			return err
+		}
+		return tx.Raw(sql, args).Scan(&rows).Error
+	})
+	if err != nil {
+		log.Printf("product search db error %v", err)
+		return nil, errors.New("product search failed")
+	}
+
+	result := &SearchResult{Hits: []SearchHit{}}
+	for _, row := range rows {
+		result.Hits = append(result.Hits, row.SearchHit)
+		result.Total = row.Total
+	}
+
+	return result, nil
+}
+
+// prefixQuery turns free text into a tsquery matching all of its words,
+// the last one as a prefix. Only letters and digits are kept so the text
+// can't break the tsquery syntax.
+func prefixQuery(text string) string {
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	words[len(words)-1] += ":*"
+	return strings.Join(words, " & ")
+}
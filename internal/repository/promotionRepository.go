@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PromotionRepository interface {
+	CreatePromotion(promotion *domain.Promotion) error
+	UpdatePromotion(promotion *domain.Promotion) error
+	DeletePromotion(promotionId uint, sellerId int) error
+	FindPromotion(promotionId uint, sellerId int) (*domain.Promotion, error)
+	FindPromotions(sellerId int) ([]*domain.Promotion, error)
+	FindLivePromotions(productIds []uint, now time.Time) ([]*domain.Promotion, error)
+}
+
+type promotionRepository struct {
+	db *gorm.DB
+}
+
+func NewPromotionRepository(db *gorm.DB) PromotionRepository {
+	return &promotionRepository{
+		db: db,
+	}
+}
+
+func (r *promotionRepository) CreatePromotion(promotion *domain.Promotion) error {
+
+	result := r.db.Create(promotion)
+	if result.Error != nil {
+		log.Printf("promotion creation db error %v", result.Error)
+		return errors.New("promotion creation failed")
+	}
+
+	return nil
+}
+
+// UpdatePromotion saves the promotion and replaces its products
+func (r *promotionRepository) UpdatePromotion(promotion *domain.Promotion) error {
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Products").Save(promotion).Error; err != nil {
+			log.Printf("promotion update db error %v", err)
+			return errors.New("promotion updation failed")
+		}
+
+		if err := tx.Where("promotion_id=?", promotion.ID).Delete(&domain.PromotionProduct{}).Error; err != nil {
+			log.Printf("promotion products delete db error %v", err)
+			return errors.New("promotion updation failed")
+		}
+
+		for i := range promotion.Products {
+			promotion.Products[i].ID = 0
+			promotion.Products[i].PromotionId = promotion.ID
+		}
+
+		if len(promotion.Products) > 0 {
+			if err := tx.Create(&promotion.Products).Error; err != nil {
+				log.Printf("promotion products creation db error %v", err)
+				return errors.New("promotion updation failed")
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *promotionRepository) DeletePromotion(promotionId uint, sellerId int) error {
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id=? AND seller_id=?", promotionId, sellerId).Delete(&domain.Promotion{})
+		if result.Error != nil {
+			log.Printf("promotion delete db error %v", result.Error)
+			return errors.New("promotion deletion failed")
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("promotion not found")
+		}
+
+		if err := tx.Where("promotion_id=?", promotionId).Delete(&domain.PromotionProduct{}).Error; err != nil {
+			log.Printf("promotion products delete db error %v", err)
+			return errors.New("promotion deletion failed")
+		}
+
+		return nil
+	})
+}
+
+func (r *promotionRepository) FindPromotion(promotionId uint, sellerId int) (*domain.Promotion, error) {
+
+	var promotion domain.Promotion
+	result := r.db.Preload("Products").Where("id=? AND seller_id=?", promotionId, sellerId).First(&promotion)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("promotion not found")
+		}
+		log.Printf("find promotion db error %v", result.Error)
+		return nil, errors.New("promotion search failed")
+	}
+
+	return &promotion, nil
+}
+
+func (r *promotionRepository) FindPromotions(sellerId int) ([]*domain.Promotion, error) {
+
+	var promotions []*domain.Promotion
+	result := r.db.Preload("Products").Where("seller_id=?", sellerId).Order("id desc").Find(&promotions)
+	if result.Error != nil {
+		log.Printf("find promotions db error %v", result.Error)
+		return nil, errors.New("promotions search failed")
+	}
+
+	return promotions, nil
+}
+
+// FindLivePromotions returns the promotions running at now that cover any
+// of the products.
+func (r *promotionRepository) FindLivePromotions(productIds []uint, now time.Time) ([]*domain.Promotion, error) {
+
+	var promotions []*domain.Promotion
+	if len(productIds) == 0 {
+		return promotions, nil
+	}
+
+	result := r.db.Preload("Products").
+		Where("id IN (?)", r.db.Model(&domain.PromotionProduct{}).Select("promotion_id").Where("product_id IN ?", productIds)).
+		Where("active = ? AND starts_at <= ? AND (ends_at IS NULL OR ends_at > ?)", true, now, now).
+		Order("id").Find(&promotions)
+	if result.Error != nil {
+		log.Printf("find live promotions db error %v", result.Error)
+		return nil, errors.New("promotions search failed")
+	}
+
+	return promotions, nil
+}
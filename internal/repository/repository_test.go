@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"os"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testDB connects to the database in TEST_DSN, tests running SQL against
+// it are skipped without one
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DSN not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+
+	err = db.AutoMigrate(&domain.Product{}, &domain.ProductOption{}, &domain.Variant{}, &domain.VariantOption{})
+	if err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+
+	return db
+}
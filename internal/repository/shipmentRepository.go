@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShipmentRepository interface {
+	CreateShipment(s *domain.Shipment) error
+	UpdateShipment(s *domain.Shipment) error
+	FindShipment(shipmentId uint, sellerId int) (*domain.Shipment, error)
+	FindShipmentForUpdate(shipmentId uint) (*domain.Shipment, error)
+	FindShipmentsToTrack(orderId uint, checkedBefore time.Time) ([]*domain.Shipment, error)
+	FindFulfilmentShipments(fulfilmentId uint) ([]*domain.Shipment, error)
+	ShippedItemIds(fulfilmentId uint) ([]int, error)
+	AddShipmentEvents(events []domain.ShipmentEvent) error
+}
+
+type shipmentRepository struct {
+	db *gorm.DB
+}
+
+func NewShipmentRepository(db *gorm.DB) ShipmentRepository {
+	return &shipmentRepository{
+		db: db,
+	}
+}
+
+func (r *shipmentRepository) CreateShipment(s *domain.Shipment) error {
+
+	result := r.db.Create(s)
+	if result.Error != nil {
+		log.Printf("shipment creation db error %v", result.Error)
+		return errors.New("shipment creation failed")
+	}
+
+	return nil
+}
+
+func (r *shipmentRepository) UpdateShipment(s *domain.Shipment) error {
+
+	result := r.db.Omit("Items", "Events").Save(s)
+	if result.Error != nil {
+		log.Printf("shipment update db error %v", result.Error)
+		return errors.New("shipment updation failed")
+	}
+
+	return nil
+}
+
+func (r *shipmentRepository) FindShipment(shipmentId uint, sellerId int) (*domain.Shipment, error) {
+
+	var s domain.Shipment
+	result := r.db.Preload("Items").
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("occurred_at") }).
+		Where("id=? AND seller_id=?", shipmentId, sellerId).First(&s)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("shipment not found")
+		}
+		log.Printf("find shipment db error %v", result.Error)
+		return nil, errors.New("shipment search failed")
+	}
+
+	return &s, nil
+}
+
+func (r *shipmentRepository) FindShipmentForUpdate(shipmentId uint) (*domain.Shipment, error) {
+
+	var s domain.Shipment
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id=?", shipmentId).First(&s)
+	if result.Error != nil {
+		log.Printf("find shipment for update db error %v", result.Error)
+		return nil, errors.New("shipment search failed")
+	}
+
+	return &s, nil
+}
+
+// FindShipmentsToTrack returns the order's parcels still on their way that
+// were not checked with the carrier since checkedBefore.
+func (r *shipmentRepository) FindShipmentsToTrack(orderId uint, checkedBefore time.Time) ([]*domain.Shipment, error) {
+
+	var shipments []*domain.Shipment
+	result := r.db.Where("order_id=? AND status NOT IN ?", orderId, []string{domain.SHIPMENT_DELIVERED, domain.SHIPMENT_EXCEPTION}).
+		Where("last_checked_at IS NULL OR last_checked_at < ?", checkedBefore).
+		Find(&shipments)
+	if result.Error != nil {
+		log.Printf("find shipments to track db error %v", result.Error)
+		return nil, errors.New("shipment search failed")
+	}
+
+	return shipments, nil
+}
+
+func (r *shipmentRepository) FindFulfilmentShipments(fulfilmentId uint) ([]*domain.Shipment, error) {
+
+	var shipments []*domain.Shipment
+	result := r.db.Where("fulfilment_id=?", fulfilmentId).Find(&shipments)
+	if result.Error != nil {
+		log.Printf("find fulfilment shipments db error %v", result.Error)
+		return nil, errors.New("shipment search failed")
+	}
+
+	return shipments, nil
+}
+
+func (r *shipmentRepository) ShippedItemIds(fulfilmentId uint) ([]int, error) {
+
+	var ids []int
+	result := r.db.Model(&domain.ShipmentItem{}).
+		Joins("JOIN shipments ON shipments.id = shipment_items.shipment_id").
+		Where("shipments.fulfilment_id=?", fulfilmentId).
+		Pluck("shipment_items.order_item_id", &ids)
+	if result.Error != nil {
+		log.Printf("shipped items db error %v", result.Error)
+		return nil, errors.New("shipment search failed")
+	}
+
+	return ids, nil
+}
+
+// AddShipmentEvents stores tracking events, the ones already stored for the
+// shipment are skipped.
+func (r *shipmentRepository) AddShipmentEvents(events []domain.ShipmentEvent) error {
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&events)
+	if result.Error != nil {
+		log.Printf("shipment events db error %v", result.Error)
+		return errors.New("shipment tracking update failed")
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShippingRepository interface {
+	CreateZone(zone *domain.ShippingZone) error
+	UpdateZone(zone *domain.ShippingZone) error
+	DeleteZone(zoneId uint, sellerId int) error
+	FindZone(zoneId uint, sellerId int) (*domain.ShippingZone, error)
+	FindZones(sellerId int) ([]*domain.ShippingZone, error)
+	FindSellerZones(sellerIds []int) ([]*domain.ShippingZone, error)
+	CreateMethod(method *domain.ShippingMethod) error
+	UpdateMethod(method *domain.ShippingMethod) error
+	DeleteMethod(methodId uint, sellerId int) error
+	FindMethod(methodId uint, sellerId int) (*domain.ShippingMethod, error)
+	FindSelections(userId int) ([]*domain.ShippingSelection, error)
+	SaveSelection(selection *domain.ShippingSelection) error
+	DeleteSelections(userId int) error
+}
+
+type shippingRepository struct {
+	db *gorm.DB
+}
+
+func NewShippingRepository(db *gorm.DB) ShippingRepository {
+	return &shippingRepository{
+		db: db,
+	}
+}
+
+func (r *shippingRepository) CreateZone(zone *domain.ShippingZone) error {
+
+	result := r.db.Omit("Methods").Create(zone)
+	if result.Error != nil {
+		log.Printf("shipping zone creation db error %v", result.Error)
+		return errors.New("shipping zone creation failed")
+	}
+
+	return nil
+}
+
+func (r *shippingRepository) UpdateZone(zone *domain.ShippingZone) error {
+
+	result := r.db.Omit("Methods").Save(zone)
+	if result.Error != nil {
+		log.Printf("shipping zone update db error %v", result.Error)
+		return errors.New("shipping zone updation failed")
+	}
+
+	return nil
+}
+
+func (r *shippingRepository) DeleteZone(zoneId uint, sellerId int) error {
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id=? AND seller_id=?", zoneId, sellerId).Delete(&domain.ShippingZone{})
+		if result.Error != nil {
+			log.Printf("shipping zone delete db error %v", result.Error)
+			return errors.New("shipping zone deletion failed")
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("shipping zone not found")
+		}
+
+		if err := tx.Where("zone_id=?", zoneId).Delete(&domain.ShippingMethod{}).Error; err != nil {
+			log.Printf("shipping methods delete db error %v", err)
+			return errors.New("shipping zone deletion failed")
+		}
+
+		return nil
+	})
+}
+
+func (r *shippingRepository) FindZone(zoneId uint, sellerId int) (*domain.ShippingZone, error) {
+
+	var zone domain.ShippingZone
+	result := r.db.Preload("Methods").Where("id=? AND seller_id=?", zoneId, sellerId).First(&zone)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("shipping zone not found")
+		}
+		log.Printf("find shipping zone db error %v", result.Error)
+		return nil, errors.New("shipping zone search failed")
+	}
+
+	return &zone, nil
+}
+
+func (r *shippingRepository) FindZones(sellerId int) ([]*domain.ShippingZone, error) {
+
+	var zones []*domain.ShippingZone
+	result := r.db.Preload("Methods").Where("seller_id=?", sellerId).Order("id").Find(&zones)
+	if result.Error != nil {
+		log.Printf("find shipping zones db error %v", result.Error)
+		return nil, errors.New("shipping zones search failed")
+	}
+
+	return zones, nil
+}
+
+// FindSellerZones returns the zones of every given seller with their active
+// methods.
+func (r *shippingRepository) FindSellerZones(sellerIds []int) ([]*domain.ShippingZone, error) {
+
+	var zones []*domain.ShippingZone
+	result := r.db.Preload("Methods", "active = ?", true).
+		Where("seller_id IN ?", sellerIds).Order("id").Find(&zones)
+	if result.Error != nil {
+		log.Printf("find seller zones db error %v", result.Error)
+		return nil, errors.New("shipping zones search failed")
+	}
+
+	return zones, nil
+}
+
+func (r *shippingRepository) CreateMethod(method *domain.ShippingMethod) error {
+
+	result := r.db.Create(method)
+	if result.Error != nil {
+		log.Printf("shipping method creation db error %v", result.Error)
+		return errors.New("shipping method creation failed")
+	}
+
+	return nil
+}
+
+func (r *shippingRepository) UpdateMethod(method *domain.ShippingMethod) error {
+
+	result := r.db.Save(method)
+	if result.Error != nil {
+		log.Printf("shipping method update db error %v", result.Error)
+		return errors.New("shipping method updation failed")
+	}
+
+	return nil
+}
+
+func (r *shippingRepository) DeleteMethod(methodId uint, sellerId int) error {
+
+	result := r.db.Where("id=? AND seller_id=?", methodId, sellerId).Delete(&domain.ShippingMethod{})
+	if result.Error != nil {
+		log.Printf("shipping method delete db error %v", result.Error)
+		return errors.New("shipping method deletion failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("shipping method not found")
+	}
+
+	return nil
+}
+
+func (r *shippingRepository) FindMethod(methodId uint, sellerId int) (*domain.ShippingMethod, error) {
+
+	var method domain.ShippingMethod
+	result := r.db.Where("id=? AND seller_id=?", methodId, sellerId).First(&method)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("shipping method not found")
+		}
+		log.Printf("find shipping method db error %v", result.Error)
+		return nil, errors.New("shipping method search failed")
+	}
+
+	return &method, nil
+}
+
+func (r *shippingRepository) FindSelections(userId int) ([]*domain.ShippingSelection, error) {
+
+	var selections []*domain.ShippingSelection
+	result := r.db.Where("user_id=?", userId).Find(&selections)
+	if result.Error != nil {
+		log.Printf("find shipping selections db error %v", result.Error)
+		return nil, errors.New("shipping selection search failed")
+	}
+
+	return selections, nil
+}
+
+func (r *shippingRepository) SaveSelection(selection *domain.ShippingSelection) error {
+
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "seller_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"method_id"}),
+	}).Create(selection)
+	if result.Error != nil {
+		log.Printf("save shipping selection db error %v", result.Error)
+		return errors.New("shipping selection failed")
+	}
+
+	return nil
+}
+
+func (r *shippingRepository) DeleteSelections(userId int) error {
+
+	result := r.db.Where("user_id=?", userId).Delete(&domain.ShippingSelection{})
+	if result.Error != nil {
+		log.Printf("delete shipping selections db error %v", result.Error)
+		return errors.New("shipping selection deletion failed")
+	}
+
+	return nil
+}
@@ -1,15 +1,54 @@
 package repository
 
 import (
+	"errors"
 	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"log"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TransactionRepo interface {
 	CreatePayment(payment *domain.Payment) error
-	FindOrders(userId int) ([]*domain.OrderItem, error)
-	FindOrderById(orderI int, userId int) (*domain.Order, error)
+	FindOpenPayment(userId int) (*domain.Payment, error)
+	UpdatePayment(payment *domain.Payment) error
+	FindPaymentByPaymentId(paymentId string) (*domain.Payment, error)
+	FindPaymentByOrderId(orderId uint) (*domain.Payment, error)
+	CreatePaymentEvent(event *domain.PaymentEvent) (bool, error)
+	UpdateOrderTransaction(orderId uint, txnId string) error
+	UpdateOrderWalletRefunded(orderId uint, refunded domain.Money) error
+	UpdateOrderGiftCardRefunded(orderId uint, refunded domain.Money) error
+	FindUnpaidTransfers(before time.Time) ([]*domain.Payment, error)
+
+	//Order lifecycle
+	FindOrderForUpdate(orderId uint) (*domain.Order, error)
+	SellerHasOrderItems(orderId uint, sellerId int) (bool, error)
+	UpdateOrderStatus(orderId uint, status string) error
+	CreateOrderHistory(h *domain.OrderHistory) error
+
+	//Cancellation and refunds
+	FindOrderItemsForUpdate(orderId uint) ([]*domain.OrderItem, error)
+	UpdateOrderItem(item *domain.OrderItem) error
+	CreateRefund(r *domain.Refund) error
+	CreateReturn(r *domain.ReturnRequest) error
+	UpdateReturn(r *domain.ReturnRequest) error
+	FindReturnForUpdate(returnId uint, sellerId int) (*domain.ReturnRequest, error)
+	FindSellerReturns(sellerId int, statuses []string) ([]*domain.ReturnRequest, error)
+	FindUserReturns(userId uint) ([]*domain.ReturnRequest, error)
+	ReturnedQty(orderItemId int) (int, error)
+	RefundedQty(orderItemId int) (int, error)
+	GatewayRefunded(orderId uint) (int64, error)
+	CreateFulfilment(f *domain.Fulfilment) error
+	AssignFulfilment(orderId uint, sellerId int, fulfilmentId uint) error
+	FindFulfilment(fulfilmentId uint) (*domain.Fulfilment, error)
+	FindOrderFulfilmentsForUpdate(orderId uint) ([]*domain.Fulfilment, error)
+	UpdateFulfilment(f *domain.Fulfilment) error
+	DeliverFulfilments(orderId uint) error
+	FindOrders(sellerId int) ([]*dto.SellerOrderDetails, error)
+	FindOrderById(fulfilmentId uint, sellerId int) (*dto.SellerOrderDetails, error)
 }
 
 type transactionRepo struct {
@@ -22,14 +61,489 @@ func NewTransactionRepo(db *gorm.DB) TransactionRepo {
 	}
 }
 
+// sellerOrderQuery joins every fulfilment with its order, the buyer and
+// their address, column aliases follow dto.SellerOrderDetails field names
+const sellerOrderQuery = `SELECT f.id AS fulfilment_id,
+	f.order_id,
+	o.order_ref_number AS orderr_ref_number,
+	o.status AS order_status,
+	f.status,
+	f.carrier,
+	f.tracking_number,
+	f.shipped_at,
+	f.delivered_at,
+	o.created_at,
+	CONCAT_WS(' ', u.first_name, u.last_name) AS customer_name,
+	u.email AS customer_email,
+	u.phone AS customer_phone,
+	CONCAT_WS(', ', a.address_line1, NULLIF(a.address_line2, ''), a.city, a.post_code, a.country) AS customer_address
+FROM fulfilments f
+JOIN orders o ON o.id = f.order_id
+JOIN users u ON u.id = o.user_id
+LEFT JOIN addresses a ON a.user_id = u.id
+`
+
 func (t *transactionRepo) CreatePayment(payment *domain.Payment) error {
-	panic("unimplemented")
+
+	result := t.db.Create(payment)
+	if result.Error != nil {
+		log.Printf("payment creation db error %v", result.Error)
+		return errors.New("payment creation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) FindOpenPayment(userId int) (*domain.Payment, error) {
+
+	var payment domain.Payment
+	result := t.db.Where("user_id=? AND order_id=0 AND status IN ?", userId, []string{domain.PAYMENT_INITIAL, domain.PAYMENT_SUCCESS}).
+		Order("created_at desc").First(&payment)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Printf("find open payment db error %v", result.Error)
+		return nil, errors.New("payment search failed")
+	}
+
+	return &payment, nil
+}
+
+func (t *transactionRepo) UpdatePayment(payment *domain.Payment) error {
+
+	result := t.db.Save(payment)
+	if result.Error != nil {
+		log.Printf("payment update db error %v", result.Error)
+		return errors.New("payment updation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) FindPaymentByPaymentId(paymentId string) (*domain.Payment, error) {
+
+	var payment domain.Payment
+	result := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("payment_id=?", paymentId).First(&payment)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Printf("find payment db error %v", result.Error)
+		return nil, errors.New("payment search failed")
+	}
+
+	return &payment, nil
+}
+
+func (t *transactionRepo) FindPaymentByOrderId(orderId uint) (*domain.Payment, error) {
+
+	var payment domain.Payment
+	result := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id=?", orderId).First(&payment)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		log.Printf("find order payment db error %v", result.Error)
+		return nil, errors.New("payment search failed")
+	}
+
+	return &payment, nil
+}
+
+// CreatePaymentEvent reports false when the event id was already stored
+func (t *transactionRepo) CreatePaymentEvent(event *domain.PaymentEvent) (bool, error) {
+
+	result := t.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_id"}}, DoNothing: true}).Create(event)
+	if result.Error != nil {
+		log.Printf("payment event creation db error %v", result.Error)
+		return false, errors.New("payment event creation failed")
+	}
+
+	return result.RowsAffected > 0, nil
 }
 
-func (t *transactionRepo) FindOrderById(orderI int, userId int) (*domain.Order, error) {
-	panic("unimplemented")
+func (t *transactionRepo) UpdateOrderTransaction(orderId uint, txnId string) error {
+
+	result := t.db.Model(&domain.Order{}).Where("id=?", orderId).Update("transaction_id", txnId)
+	if result.Error != nil {
+		log.Printf("order transaction update db error %v", result.Error)
+		return errors.New("order updation failed")
+	}
+
+	return nil
 }
 
-func (t *transactionRepo) FindOrders(userId int) ([]*domain.OrderItem, error) {
-	panic("unimplemented")
+func (t *transactionRepo) UpdateOrderWalletRefunded(orderId uint, refunded domain.Money) error {
+
+	result := t.db.Model(&domain.Order{}).Where("id=?", orderId).Updates(map[string]interface{}{
+		"walletrefunded_minor":    refunded.Amount,
+		"walletrefunded_currency": refunded.Currency,
+	})
+	if result.Error != nil {
+		log.Printf("order wallet refund update db error %v", result.Error)
+		return errors.New("order updation failed")
+	}
+
+	return nil
+}
+
+// FindUnpaidTransfers returns the bank transfers of placed orders still
+// waiting for the money, started before the given time
+func (t *transactionRepo) FindUnpaidTransfers(before time.Time) ([]*domain.Payment, error) {
+
+	var payments []*domain.Payment
+	result := t.db.Where("capture_method=? AND status=? AND order_id<>0 AND created_at<?", domain.CAPTURE_BANK_TRANSFER, domain.PAYMENT_INITIAL, before).
+		Order("created_at").Find(&payments)
+	if result.Error != nil {
+		log.Printf("find unpaid transfers db error %v", result.Error)
+		return nil, errors.New("payment search failed")
+	}
+
+	return payments, nil
+}
+
+func (t *transactionRepo) UpdateOrderGiftCardRefunded(orderId uint, refunded domain.Money) error {
+
+	result := t.db.Model(&domain.Order{}).Where("id=?", orderId).Updates(map[string]interface{}{
+		"giftcardrefunded_minor":    refunded.Amount,
+		"giftcardrefunded_currency": refunded.Currency,
+	})
+	if result.Error != nil {
+		log.Printf("order gift card refund update db error %v", result.Error)
+		return errors.New("order updation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) FindOrderForUpdate(orderId uint) (*domain.Order, error) {
+
+	var order domain.Order
+	result := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id=?", orderId).First(&order)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("order not found")
+		}
+		log.Printf("find order for update db error %v", result.Error)
+		return nil, errors.New("order search failed")
+	}
+
+	return &order, nil
+}
+
+func (t *transactionRepo) SellerHasOrderItems(orderId uint, sellerId int) (bool, error) {
+
+	var count int64
+	result := t.db.Model(&domain.OrderItem{}).Where("order_id=? AND seller_id=?", orderId, sellerId).Count(&count)
+	if result.Error != nil {
+		log.Printf("seller order items count db error %v", result.Error)
+		return false, errors.New("order search failed")
+	}
+
+	return count > 0, nil
+}
+
+func (t *transactionRepo) UpdateOrderStatus(orderId uint, status string) error {
+
+	result := t.db.Model(&domain.Order{}).Where("id=?", orderId).Update("status", status)
+	if result.Error != nil {
+		log.Printf("order status update db error %v", result.Error)
+		return errors.New("order status updation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) CreateOrderHistory(h *domain.OrderHistory) error {
+
+	result := t.db.Create(h)
+	if result.Error != nil {
+		log.Printf("order history creation db error %v", result.Error)
+		return errors.New("order history creation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) FindOrderItemsForUpdate(orderId uint) ([]*domain.OrderItem, error) {
+
+	var items []*domain.OrderItem
+	result := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Taxes").Where("order_id=?", orderId).Order("id").Find(&items)
+	if result.Error != nil {
+		log.Printf("find order items db error %v", result.Error)
+		return nil, errors.New("order items search failed")
+	}
+
+	return items, nil
+}
+
+func (t *transactionRepo) UpdateOrderItem(item *domain.OrderItem) error {
+
+	result := t.db.Save(item)
+	if result.Error != nil {
+		log.Printf("order item update db error %v", result.Error)
+		return errors.New("order item updation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) CreateRefund(r *domain.Refund) error {
+
+	result := t.db.Create(r)
+	if result.Error != nil {
+		log.Printf("refund creation db error %v", result.Error)
+		return errors.New("refund creation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) CreateReturn(r *domain.ReturnRequest) error {
+
+	result := t.db.Omit("Item").Create(r)
+	if result.Error != nil {
+		log.Printf("return creation db error %v", result.Error)
+		return errors.New("return request creation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) UpdateReturn(r *domain.ReturnRequest) error {
+
+	result := t.db.Omit("Item").Save(r)
+	if result.Error != nil {
+		log.Printf("return update db error %v", result.Error)
+		return errors.New("return request updation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) FindReturnForUpdate(returnId uint, sellerId int) (*domain.ReturnRequest, error) {
+
+	var r domain.ReturnRequest
+	result := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id=? AND seller_id=?", returnId, sellerId).First(&r)
+	if result.Error != nil {
+		log.Printf("find return db error %v", result.Error)
+		return nil, errors.New("return request not found")
+	}
+
+	return &r, nil
+}
+
+func (t *transactionRepo) FindSellerReturns(sellerId int, statuses []string) ([]*domain.ReturnRequest, error) {
+
+	var returns []*domain.ReturnRequest
+	query := t.db.Preload("Item").Where("seller_id=?", sellerId)
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+
+	result := query.Order("created_at").Find(&returns)
+	if result.Error != nil {
+		log.Printf("find seller returns db error %v", result.Error)
+		return nil, errors.New("return requests search failed")
+	}
+
+	return returns, nil
+}
+
+func (t *transactionRepo) FindUserReturns(userId uint) ([]*domain.ReturnRequest, error) {
+
+	var returns []*domain.ReturnRequest
+	result := t.db.Preload("Item").Where("user_id=?", userId).Order("created_at desc").Find(&returns)
+	if result.Error != nil {
+		log.Printf("find user returns db error %v", result.Error)
+		return nil, errors.New("return requests search failed")
+	}
+
+	return returns, nil
+}
+
+// ReturnedQty is how many units of an order item are already claimed by
+// returns that were not rejected.
+func (t *transactionRepo) ReturnedQty(orderItemId int) (int, error) {
+
+	var qty int
+	result := t.db.Model(&domain.ReturnRequest{}).Select("COALESCE(SUM(qty), 0)").
+		Where("order_item_id=? AND status<>?", orderItemId, domain.RETURN_REJECTED).Scan(&qty)
+	if result.Error != nil {
+		log.Printf("returned qty db error %v", result.Error)
+		return 0, errors.New("return requests search failed")
+	}
+
+	return qty, nil
+}
+
+// RefundedQty is how many units of an order item have been refunded so far.
+func (t *transactionRepo) RefundedQty(orderItemId int) (int, error) {
+
+	var qty int
+	result := t.db.Model(&domain.RefundItem{}).Select("COALESCE(SUM(qty), 0)").
+		Where("order_item_id=?", orderItemId).Scan(&qty)
+	if result.Error != nil {
+		log.Printf("refunded qty db error %v", result.Error)
+		return 0, errors.New("refunds search failed")
+	}
+
+	return qty, nil
+}
+
+// GatewayRefunded is how much of an order's refunds went back to the card
+// through the gateway, in minor units. Parts credited to the wallet or put
+// back on gift cards never reached the gateway.
+func (t *transactionRepo) GatewayRefunded(orderId uint) (int64, error) {
+
+	var amount int64
+	result := t.db.Model(&domain.Refund{}).Select("COALESCE(SUM(amount_minor - wallet_minor - giftcard_minor), 0)").
+		Where("order_id=? AND provider_refund_id <> ''", orderId).Scan(&amount)
+	if result.Error != nil {
+		log.Printf("gateway refunded db error %v", result.Error)
+		return 0, errors.New("refunds search failed")
+	}
+
+	return amount, nil
+}
+
+func (t *transactionRepo) CreateFulfilment(f *domain.Fulfilment) error {
+
+	result := t.db.Omit("Items").Create(f)
+	if result.Error != nil {
+		log.Printf("fulfilment creation db error %v", result.Error)
+		return errors.New("fulfilment creation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) AssignFulfilment(orderId uint, sellerId int, fulfilmentId uint) error {
+
+	result := t.db.Model(&domain.OrderItem{}).Where("order_id=? AND seller_id=?", orderId, sellerId).Update("fulfilment_id", fulfilmentId)
+	if result.Error != nil {
+		log.Printf("assign fulfilment db error %v", result.Error)
+		return errors.New("fulfilment creation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) FindFulfilment(fulfilmentId uint) (*domain.Fulfilment, error) {
+
+	var f domain.Fulfilment
+	result := t.db.Where("id=?", fulfilmentId).First(&f)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("fulfilment not found")
+		}
+		log.Printf("find fulfilment db error %v", result.Error)
+		return nil, errors.New("fulfilment search failed")
+	}
+
+	return &f, nil
+}
+
+func (t *transactionRepo) FindOrderFulfilmentsForUpdate(orderId uint) ([]*domain.Fulfilment, error) {
+
+	var fulfilments []*domain.Fulfilment
+	result := t.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id=?", orderId).Order("id").Find(&fulfilments)
+	if result.Error != nil {
+		log.Printf("find order fulfilments db error %v", result.Error)
+		return nil, errors.New("fulfilment search failed")
+	}
+
+	return fulfilments, nil
+}
+
+func (t *transactionRepo) UpdateFulfilment(f *domain.Fulfilment) error {
+
+	result := t.db.Omit("Items").Save(f)
+	if result.Error != nil {
+		log.Printf("fulfilment update db error %v", result.Error)
+		return errors.New("fulfilment updation failed")
+	}
+
+	return nil
+}
+
+// DeliverFulfilments marks the shipped fulfilments of an order delivered,
+// for when the order as a whole is confirmed delivered.
+func (t *transactionRepo) DeliverFulfilments(orderId uint) error {
+
+	result := t.db.Model(&domain.Fulfilment{}).
+		Where("order_id=? AND status=?", orderId, domain.FULFILMENT_SHIPPED).
+		Updates(map[string]interface{}{"status": domain.FULFILMENT_DELIVERED, "delivered_at": time.Now()})
+	if result.Error != nil {
+		log.Printf("deliver fulfilments db error %v", result.Error)
+		return errors.New("fulfilment updation failed")
+	}
+
+	return nil
+}
+
+func (t *transactionRepo) FindOrderById(fulfilmentId uint, sellerId int) (*dto.SellerOrderDetails, error) {
+
+	var details dto.SellerOrderDetails
+	result := t.db.Raw(sellerOrderQuery+"WHERE f.id = ? AND f.seller_id = ?", fulfilmentId, sellerId).Scan(&details)
+	if result.Error != nil {
+		log.Printf("seller order details db error %v", result.Error)
+		return nil, errors.New("order search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, errors.New("order not found")
+	}
+
+	if err := t.attachFulfilmentItems([]*dto.SellerOrderDetails{&details}); err != nil {
+		return nil, err
+	}
+
+	return &details, nil
+}
+
+func (t *transactionRepo) FindOrders(sellerId int) ([]*dto.SellerOrderDetails, error) {
+
+	var orders []*dto.SellerOrderDetails
+	result := t.db.Raw(sellerOrderQuery+"WHERE f.seller_id = ? ORDER BY o.created_at DESC", sellerId).Scan(&orders)
+	if result.Error != nil {
+		log.Printf("seller orders db error %v", result.Error)
+		return nil, errors.New("orders search failed")
+	}
+
+	if err := t.attachFulfilmentItems(orders); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// attachFulfilmentItems loads the items of every fulfilment in one query
+func (t *transactionRepo) attachFulfilmentItems(orders []*dto.SellerOrderDetails) error {
+
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byId := map[uint]*dto.SellerOrderDetails{}
+	ids := make([]uint, 0, len(orders))
+	for _, o := range orders {
+		byId[o.FulfilmentId] = o
+		ids = append(ids, o.FulfilmentId)
+	}
+
+	var items []domain.OrderItem
+	result := t.db.Preload("Taxes").Where("fulfilment_id IN ?", ids).Order("id").Find(&items)
+	if result.Error != nil {
+		log.Printf("fulfilment items db error %v", result.Error)
+		return errors.New("orders search failed")
+	}
+
+	for _, item := range items {
+		o := byId[item.FulfilmentId]
+		o.Items = append(o.Items, item)
+	}
+
+	return nil
 }
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"testing"
+)
+
+// Only what went back through the gateway counts, wallet and gift card
+// parts and refunds the gateway never saw are left out.
+func TestGatewayRefunded(t *testing.T) {
+
+	db := testDB(t)
+	if err := db.AutoMigrate(&domain.Refund{}, &domain.RefundItem{}); err != nil {
+		t.Fatalf("migrating refunds: %v", err)
+	}
+
+	const orderId = 1 << 30
+	refunds := []domain.Refund{
+		{OrderId: orderId, ProviderRefundId: "re_test_1", Amount: domain.NewMoney(1000, "USD"), WalletAmount: domain.NewMoney(300, "USD"), GiftCardAmount: domain.NewMoney(200, "USD")},
+		{OrderId: orderId, ProviderRefundId: "re_test_2", Amount: domain.NewMoney(250, "USD"), WalletAmount: domain.NewMoney(0, "USD"), GiftCardAmount: domain.NewMoney(0, "USD")},
+		{OrderId: orderId, Amount: domain.NewMoney(400, "USD"), WalletAmount: domain.NewMoney(400, "USD"), GiftCardAmount: domain.NewMoney(0, "USD")},
+	}
+	if err := db.Create(&refunds).Error; err != nil {
+		t.Fatalf("creating refunds: %v", err)
+	}
+	t.Cleanup(func() { db.Where("order_id=?", orderId).Delete(&domain.Refund{}) })
+
+	amount, err := NewTransactionRepo(db).GatewayRefunded(orderId)
+	if err != nil {
+		t.Fatalf("summing gateway refunds: %v", err)
+	}
+	if amount != 750 {
+		t.Fatalf("gateway refunded %d, want 750", amount)
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Repositories bound to a single database transaction.
+type Repositories struct {
+	User        UserRepository
+	Catalog     CatalogRepository
+	Transaction TransactionRepo
+	Shipment    ShipmentRepository
+	Shipping    ShippingRepository
+	Coupon      CouponRepository
+	Promotion   PromotionRepository
+	Invoice     InvoiceRepository
+	Ledger      LedgerRepository
+	Commission  CommissionRepository
+	Wallet      WalletRepository
+	GiftCard    GiftCardRepository
+}
+
+type TxManager interface {
+	// WithTx commits when fn returns nil and rolls back otherwise.
+	WithTx(fn func(repos Repositories) error) error
+	// Repositories are bound to the plain connection, for code shared
+	// between reads and transactions.
+	Repositories() Repositories
+}
+
+type txManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) TxManager {
+	return &txManager{
+		db: db,
+	}
+}
+
+func (m *txManager) WithTx(fn func(repos Repositories) error) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		return fn(newRepositories(tx))
+	})
+}
+
+func (m *txManager) Repositories() Repositories {
+	return newRepositories(m.db)
+}
+
+func newRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		User:        NewUserRepository(db),
+		Catalog:     NewCatalogRepository(db),
+		Transaction: NewTransactionRepo(db),
+		Shipment:    NewShipmentRepository(db),
+		Shipping:    NewShippingRepository(db),
+		Coupon:      NewCouponRepository(db),
+		Promotion:   NewPromotionRepository(db),
+		Invoice:     NewInvoiceRepository(db),
+		Ledger:      NewLedgerRepository(db),
+		Commission:  NewCommissionRepository(db),
+		Wallet:      NewWalletRepository(db),
+		GiftCard:    NewGiftCardRepository(db),
+	}
+}
@@ -20,18 +20,20 @@ type UserRepository interface {
 	//Cart
 	CreateCart(input domain.Cart) error
 	FindCartItems(userId int) ([]*domain.Cart, error)
-	FindCartItem(userid int, prdctId int) (*domain.Cart, error)
+	LockCartItems(userId int) ([]*domain.Cart, error)
+	FindCartItem(userid int, prdctId int, variantId uint) (*domain.Cart, error)
 	UpdateCart(input domain.Cart) error
 	DeleteCartItemByid(Id int) error
 	DeleteCartItems(userId int) error
 
 	//Order
-	CreateOrder(domain.Order) error
+	CreateOrder(order *domain.Order) error
 	FindOrders(userId int) ([]*domain.Order, error)
 	FindOrderById(orderId int, userId int) (*domain.Order, error)
 
 	//Profile
 	CreateProfile(input domain.Address) error
+	FindAddress(userId int) (domain.Address, error)
 	UpdateProfile(input *domain.Address) error
 }
 
@@ -142,14 +144,14 @@ func (r *userRepository) CreateCart(input domain.Cart) error {
 	return nil
 }
 
-func (r *userRepository) FindCartItem(userid int, prdctId int) (*domain.Cart, error) {
-	var cartItem *domain.Cart
-	result := r.db.Where("user_id=? AND product_id=?", userid, prdctId).First(cartItem)
+func (r *userRepository) FindCartItem(userid int, prdctId int, variantId uint) (*domain.Cart, error) {
+	var cartItem domain.Cart
+	result := r.db.Where("user_id=? AND product_id=? AND variant_id=?", userid, prdctId, variantId).First(&cartItem)
 	if result.Error != nil {
 		return &domain.Cart{}, fmt.Errorf("finding cart failed due to %v", result.Error)
 	}
 
-	return cartItem, nil
+	return &cartItem, nil
 }
 
 func (r *userRepository) FindCartItems(userId int) ([]*domain.Cart, error) {
@@ -163,6 +165,17 @@ func (r *userRepository) FindCartItems(userId int) ([]*domain.Cart, error) {
 	return allCartItems, nil
 }
 
+func (r *userRepository) LockCartItems(userId int) ([]*domain.Cart, error) {
+	var allCartItems []*domain.Cart
+
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id=?", userId).Order("id").Find(&allCartItems)
+	if result.Error != nil {
+		return nil, fmt.Errorf("locking cart failed due to %v", result.Error)
+	}
+
+	return allCartItems, nil
+}
+
 func (r *userRepository) UpdateCart(input domain.Cart) error {
 	var cart domain.Cart
 	result := r.db.Model(&cart).Clauses(clause.Returning{}).Where("id=?", input.ID).Updates(input)
@@ -197,9 +210,9 @@ func (r *userRepository) AddBankAccount(e domain.BankAccount) error {
 	return r.db.Create(&e).Error
 }
 
-func (r *userRepository) CreateOrder(order domain.Order) error {
+func (r *userRepository) CreateOrder(order *domain.Order) error {
 
-	result := r.db.Create(&order)
+	result := r.db.Create(order)
 	if result.Error != nil {
 		log.Printf("order creation db error %v", result.Error)
 		return errors.New("order placing failed")
@@ -211,7 +224,16 @@ func (r *userRepository) CreateOrder(order domain.Order) error {
 func (r *userRepository) FindOrderById(orderId int, userId int) (*domain.Order, error) {
 
 	var order domain.Order
-	result := r.db.Preload("Items").Where("id=? AND user_id=?", orderId, userId).First(&order)
+	result := r.db.Preload("Items.Taxes").
+		Preload("History", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") }).
+		Preload("Fulfilments", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Shipments", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Shipments.Items").
+		Preload("Shipments.Events", func(db *gorm.DB) *gorm.DB { return db.Order("occurred_at") }).
+		Preload("Refunds.Items").
+		Preload("Returns").
+		Preload("ExchangeRates").
+		Where("id=? AND user_id=?", orderId, userId).First(&order)
 	if result.Error != nil {
 		log.Printf("db error findorderbyid %v", result.Error)
 		return nil, errors.New("order search failed")
@@ -233,6 +255,18 @@ func (r *userRepository) FindOrders(userId int) ([]*domain.Order, error) {
 }
 
 // Profile Section
+func (r *userRepository) FindAddress(userId int) (domain.Address, error) {
+
+	var address domain.Address
+	result := r.db.Where("user_id=?", userId).Limit(1).Find(&address)
+	if result.Error != nil {
+		log.Printf("find address db error %v", result.Error)
+		return domain.Address{}, errors.New("address search failed")
+	}
+
+	return address, nil
+}
+
 func (r *userRepository) CreateProfile(input domain.Address) error {
 
 	result := r.db.Create(&input)
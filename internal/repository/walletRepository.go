@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WalletRepository interface {
+	LockWallet(userId int, currency string) (*domain.Wallet, error)
+	FindWallet(userId int) (*domain.Wallet, error)
+	UpdateWallet(wallet *domain.Wallet) error
+	CreateTransaction(txn *domain.WalletTransaction) error
+	FindTransactions(userId int) ([]*domain.WalletTransaction, error)
+
+	FindCartWallet(userId int) (*domain.CartWallet, error)
+	SaveCartWallet(cartWallet *domain.CartWallet) error
+	DeleteCartWallet(userId int) error
+}
+
+type walletRepository struct {
+	db *gorm.DB
+}
+
+func NewWalletRepository(db *gorm.DB) WalletRepository {
+	return &walletRepository{
+		db: db,
+	}
+}
+
+// LockWallet locks the user's wallet row, opening an empty wallet in
+// currency first when they have none
+func (r *walletRepository) LockWallet(userId int, currency string) (*domain.Wallet, error) {
+
+	opened := &domain.Wallet{UserId: userId, Balance: domain.NewMoney(0, currency)}
+	result := r.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).Create(opened)
+	if result.Error != nil {
+		log.Printf("wallet creation db error %v", result.Error)
+		return nil, errors.New("wallet creation failed")
+	}
+
+	var wallet domain.Wallet
+	result = r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id=?", userId).First(&wallet)
+	if result.Error != nil {
+		log.Printf("lock wallet db error %v", result.Error)
+		return nil, errors.New("wallet search failed")
+	}
+
+	return &wallet, nil
+}
+
+// FindWallet returns nil when the user has no wallet yet
+func (r *walletRepository) FindWallet(userId int) (*domain.Wallet, error) {
+
+	var wallet domain.Wallet
+	result := r.db.Where("user_id=?", userId).Limit(1).Find(&wallet)
+	if result.Error != nil {
+		log.Printf("find wallet db error %v", result.Error)
+		return nil, errors.New("wallet search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &wallet, nil
+}
+
+func (r *walletRepository) UpdateWallet(wallet *domain.Wallet) error {
+
+	result := r.db.Save(wallet)
+	if result.Error != nil {
+		log.Printf("wallet update db error %v", result.Error)
+		return errors.New("wallet updation failed")
+	}
+
+	return nil
+}
+
+func (r *walletRepository) CreateTransaction(txn *domain.WalletTransaction) error {
+
+	result := r.db.Create(txn)
+	if result.Error != nil {
+		log.Printf("wallet transaction creation db error %v", result.Error)
+		return errors.New("wallet transaction creation failed")
+	}
+
+	return nil
+}
+
+func (r *walletRepository) FindTransactions(userId int) ([]*domain.WalletTransaction, error) {
+
+	var txns []*domain.WalletTransaction
+	result := r.db.Where("user_id=?", userId).Order("id DESC").Find(&txns)
+	if result.Error != nil {
+		log.Printf("find wallet transactions db error %v", result.Error)
+		return nil, errors.New("wallet transaction search failed")
+	}
+
+	return txns, nil
+}
+
+// FindCartWallet returns nil when the buyer doesn't pay with their wallet
+func (r *walletRepository) FindCartWallet(userId int) (*domain.CartWallet, error) {
+
+	var cartWallet domain.CartWallet
+	result := r.db.Where("user_id=?", userId).Limit(1).Find(&cartWallet)
+	if result.Error != nil {
+		log.Printf("find cart wallet db error %v", result.Error)
+		return nil, errors.New("cart wallet search failed")
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &cartWallet, nil
+}
+
+func (r *walletRepository) SaveCartWallet(cartWallet *domain.CartWallet) error {
+
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"amount_minor", "amount_currency"}),
+	}).Create(cartWallet)
+	if result.Error != nil {
+		log.Printf("save cart wallet db error %v", result.Error)
+		return errors.New("paying with the wallet failed")
+	}
+
+	return nil
+}
+
+func (r *walletRepository) DeleteCartWallet(userId int) error {
+
+	result := r.db.Where("user_id=?", userId).Delete(&domain.CartWallet{})
+	if result.Error != nil {
+		log.Printf("delete cart wallet db error %v", result.Error)
+		return errors.New("removing the wallet from the cart failed")
+	}
+
+	return nil
+}
@@ -8,13 +8,19 @@ import (
 	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/helper"
 	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/fx"
 	"log"
+	"time"
 )
 
 type CatalogService struct {
-	Repo   repository.CatalogRepository
-	Auth   helper.Auth
-	Config configs.AppConfig
+	Repo      repository.CatalogRepository
+	PromoRepo repository.PromotionRepository
+	UserRepo  repository.UserRepository
+	Search    repository.ProductSearch
+	Auth      helper.Auth
+	Config    configs.AppConfig
+	Rates     fx.Source
 }
 
 // Category Implementation
@@ -120,14 +126,23 @@ func (s *CatalogService) DeleteCategories(id int) error {
 // Product Implementation
 func (s *CatalogService) CreateProduct(id int, input *dto.CreateProductRequest) (*domain.Product, error) {
 
+	currency, err := s.listingCurrency(id, input.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	prdct, err := s.Repo.CreateProduct(&domain.Product{
 		Name:        input.Name,
-		Price:       input.Price,
+		Price:       domain.NewMoney(input.Price, currency),
 		Description: input.Description,
 		UserId:      id,
 		ImageUrl:    input.ImageUrl,
 		CategoryID:  input.CategoryID,
 		Stock:       input.Stock,
+		Weight:      input.Weight,
+		Length:      input.Length,
+		Width:       input.Width,
+		Height:      input.Height,
 	})
 	if err != nil {
 		log.Println("product creation service layer error", err)
@@ -136,7 +151,86 @@ func (s *CatalogService) CreateProduct(id int, input *dto.CreateProductRequest)
 	return prdct, nil
 }
 
-func (s *CatalogService) GetAllProducts() ([]*domain.Product, error) {
+// listingCurrency is the currency a seller prices a product in: the one
+// asked for, their own, or the platform's when they haven't set one.
+func (s *CatalogService) listingCurrency(sellerId int, currency string) (string, error) {
+
+	if currency == "" {
+		seller, err := s.UserRepo.FindUserbyID(sellerId)
+		if err != nil {
+			return "", err
+		}
+		currency = seller.Currency
+	}
+
+	if currency == "" {
+		return s.Config.Currency, nil
+	}
+
+	rates, err := s.Rates.Latest()
+	if err != nil {
+		return "", err
+	}
+
+	return currencyCode(rates, currency)
+}
+
+// priceProducts puts the price buyers pay next to the seller's list price,
+// sale prices included, and in the buyer's display currency when one is
+// asked for.
+func (s *CatalogService) priceProducts(products []*domain.Product, display string) error {
+
+	rates, err := s.Rates.Latest()
+	if err != nil {
+		return err
+	}
+
+	if display != "" {
+		display, err = currencyCode(rates, display)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := localizePrices(rates, s.Config.Currency, products); err != nil {
+		return err
+	}
+
+	//list and sale price side by side
+	if err := attachPromotions(s.PromoRepo, rates, products, time.Now()); err != nil {
+		return err
+	}
+
+	return displayPrices(rates, s.Config.Currency, display, products)
+}
+
+// GetCurrencies lists the currencies products can be listed and shown in
+func (s *CatalogService) GetCurrencies() (*dto.CurrenciesResponse, error) {
+
+	rates, err := s.Rates.Latest()
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.CurrenciesResponse{
+		Currency: s.Config.Currency,
+		Base:     rates.Base,
+		Source:   rates.Source,
+		AsOf:     rates.AsOf,
+		Rates:    map[string]string{},
+	}
+	for _, code := range rates.Currencies() {
+		rate, err := rates.Rate(rates.Base, code)
+		if err != nil {
+			return nil, err
+		}
+		response.Rates[code] = rate.String()
+	}
+
+	return response, nil
+}
+
+func (s *CatalogService) GetAllProducts(display string) ([]*domain.Product, error) {
 
 	allProducts, err := s.Repo.FindProduct()
 	if err != nil {
@@ -144,6 +238,10 @@ func (s *CatalogService) GetAllProducts() ([]*domain.Product, error) {
 		return nil, err
 	}
 
+	if err := s.priceProducts(allProducts, display); err != nil {
+		return nil, err
+	}
+
 	// var allProducts []*domain.Product
 	// for _, product := range result {
 	// 	allProducts = append(allProducts, &domain.Product{
@@ -159,7 +257,7 @@ func (s *CatalogService) GetAllProducts() ([]*domain.Product, error) {
 	return allProducts, nil
 }
 
-func (s *CatalogService) FindProductById(id int) (*domain.Product, error) {
+func (s *CatalogService) FindProductById(id int, display string) (*domain.Product, error) {
 
 	prdct, err := s.Repo.FindProductById(id)
 	if err != nil {
@@ -167,6 +265,10 @@ func (s *CatalogService) FindProductById(id int) (*domain.Product, error) {
 		return nil, err
 	}
 
+	if err := s.priceProducts([]*domain.Product{prdct}, display); err != nil {
+		return nil, err
+	}
+
 	return prdct, err
 }
 
@@ -178,6 +280,15 @@ func (s *CatalogService) FindSellerProducts(id int) ([]*domain.Product, error) {
 		return nil, err
 	}
 
+	rates, err := s.Rates.Latest()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := attachPromotions(s.PromoRepo, rates, sellersProducts, time.Now()); err != nil {
+		return nil, err
+	}
+
 	// var sellerProducts []*domain.Product
 	// for _, product := range result {
 	// 	sellerProducts = append(sellerProducts, &domain.Product{
@@ -212,8 +323,20 @@ func (s *CatalogService) UpdateProduct(id int, input *dto.CreateProductRequest,
 		currentPrdct.Name = input.Name
 	}
 
+	//a new currency needs the price in it
+	if len(input.Currency) > 0 && input.Price < 1 {
+		return &domain.Product{}, errors.New("a new currency needs a new price")
+	}
+
 	if input.Price > 0 {
-		currentPrdct.Price = input.Price
+		currency := currentPrdct.Price.Currency
+		if len(input.Currency) > 0 {
+			currency, err = s.listingCurrency(user.ID, input.Currency)
+			if err != nil {
+				return &domain.Product{}, err
+			}
+		}
+		currentPrdct.Price = domain.NewMoney(input.Price, currency)
 	}
 
 	if len(input.Description) > 0 {
@@ -229,9 +352,22 @@ func (s *CatalogService) UpdateProduct(id int, input *dto.CreateProductRequest,
 	}
 
 	if input.Stock > 0 {
+		if len(currentPrdct.Variants) > 0 {
+			return &domain.Product{}, ErrVariantStock
+		}
 		currentPrdct.Stock = input.Stock
 	}
 
+	if input.Weight > 0 {
+		currentPrdct.Weight = input.Weight
+	}
+
+	if input.Length > 0 && input.Width > 0 && input.Height > 0 {
+		currentPrdct.Length = input.Length
+		currentPrdct.Width = input.Width
+		currentPrdct.Height = input.Height
+	}
+
 	updatedPrdct, err := s.Repo.UpdateProduct(currentPrdct)
 	if err != nil {
 		log.Println("product updation failed, service layer", err)
@@ -253,6 +389,10 @@ func (s *CatalogService) StockUpdate(id int, input *dto.UpdateStockRequest, user
 		return &domain.Product{}, errors.New("sorry, the product does not belongs to your stock")
 	}
 
+	if len(prdct.Variants) > 0 {
+		return &domain.Product{}, ErrVariantStock
+	}
+
 	if input.Stock == prdct.Stock {
 		return &domain.Product{}, errors.New("same stock quantity exist in storage")
 	} else {
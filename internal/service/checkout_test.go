@@ -0,0 +1,210 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	payment "go-ecommerce-app/pkg/Payment"
+	"go-ecommerce-app/pkg/fx"
+	"go-ecommerce-app/pkg/tax"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testDB connects to the database in TEST_DSN and migrates it like the
+// server does, checkout needs real row locks so it is skipped without one
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DSN not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("connecting to test database: %v", err)
+	}
+
+	err = db.AutoMigrate(&domain.User{},
+		&domain.Category{},
+		&domain.Product{},
+		&domain.Cart{},
+		&domain.Address{},
+		&domain.Order{},
+		&domain.OrderItem{},
+		&domain.OrderItemTax{},
+		&domain.Fulfilment{},
+		&domain.OrderHistory{},
+		&domain.Payment{},
+		&domain.ShippingZone{},
+		&domain.ShippingMethod{},
+		&domain.ShippingSelection{},
+		&domain.Coupon{},
+		&domain.CouponScope{},
+		&domain.CouponRedemption{},
+		&domain.CartCoupon{},
+		&domain.Promotion{},
+		&domain.PromotionProduct{},
+		&domain.LedgerEntry{},
+		&domain.LedgerPosting{},
+		&domain.CommissionRule{},
+		&domain.Wallet{},
+		&domain.WalletTransaction{},
+		&domain.CartWallet{},
+		&domain.GiftCard{},
+		&domain.GiftCardTransaction{},
+		&domain.CartGiftCard{},
+		&domain.ProductOption{},
+		&domain.Variant{},
+		&domain.VariantOption{},
+	)
+	if err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+
+	return db
+}
+
+func newCheckoutService(t *testing.T, db *gorm.DB) *UserService {
+	t.Helper()
+
+	rules, err := tax.LoadRules("")
+	if err != nil {
+		t.Fatalf("loading tax rules: %v", err)
+	}
+	rates, err := fx.NewStaticSource("")
+	if err != nil {
+		t.Fatalf("loading exchange rates: %v", err)
+	}
+
+	config := configs.AppConfig{Currency: "USD"}
+	return &UserService{
+		Repo:    repository.NewUserRepository(db),
+		CRepo:   repository.NewCatalogRepository(db),
+		TRepo:   repository.NewTransactionRepo(db),
+		Tx:      repository.NewTxManager(db),
+		Config:  config,
+		Payment: payment.NewFakePaymentClient(config),
+		Tax:     rules,
+		Rates:   rates,
+	}
+}
+
+// Two buyers with the last unit in their carts place their orders at the
+// same time, one of them gets it and the other is told it sold out.
+func TestCreateOrderDoesNotOversell(t *testing.T) {
+
+	db := testDB(t)
+	svc := newCheckoutService(t, db)
+
+	suffix := time.Now().UnixNano()
+	seller := domain.User{Email: fmt.Sprintf("seller-%d@test.local", suffix), UserType: domain.SELLER}
+	if err := db.Create(&seller).Error; err != nil {
+		t.Fatalf("creating seller: %v", err)
+	}
+
+	product := &domain.Product{Name: "last unit", Price: domain.NewMoney(1000, "USD"), Stock: 1, UserId: seller.ID}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatalf("creating product: %v", err)
+	}
+
+	buyers := make([]domain.User, 2)
+	for i := range buyers {
+		buyers[i] = domain.User{Email: fmt.Sprintf("buyer-%d-%d@test.local", i, suffix)}
+		if err := db.Create(&buyers[i]).Error; err != nil {
+			t.Fatalf("creating buyer: %v", err)
+		}
+
+		_, err := svc.CreateCart(&dto.CreateCartRequest{ProductId: product.ID, Qty: 1}, buyers[i])
+		if err != nil {
+			t.Fatalf("adding the last unit to a cart: %v", err)
+		}
+	}
+
+	buyerIds := []int{buyers[0].ID, buyers[1].ID}
+	t.Cleanup(func() {
+		orderIds := db.Model(&domain.Order{}).Select("id").Where("user_id IN ?", buyerIds)
+		db.Where("order_id IN (?)", orderIds).Delete(&domain.OrderHistory{})
+		db.Where("order_id IN (?)", orderIds).Delete(&domain.Fulfilment{})
+		db.Where("order_id IN (?)", orderIds).Delete(&domain.Payment{})
+		db.Where("order_id IN (?)", orderIds).Delete(&domain.OrderItem{})
+		db.Where("user_id IN ?", buyerIds).Delete(&domain.Order{})
+		db.Where("user_id IN ?", buyerIds).Delete(&domain.Cart{})
+		db.Delete(&domain.Product{}, product.ID)
+		db.Delete(&domain.User{}, append(buyerIds, seller.ID))
+	})
+
+	start := make(chan struct{})
+	results := make(chan error, len(buyers))
+	var wg sync.WaitGroup
+	for _, buyer := range buyers {
+		wg.Add(1)
+		go func(buyer domain.User) {
+			defer wg.Done()
+			<-start
+			_, err := svc.CreateOrder(buyer, dto.CreateOrderRequest{PaymentMethod: domain.CAPTURE_COD})
+			results <- err
+		}(buyer)
+	}
+	close(start)
+	wg.Wait()
+	close(results)
+
+	placed := 0
+	for err := range results {
+		if err == nil {
+			placed++
+			continue
+		}
+		if !soldOut(err, product.ID) {
+			t.Fatalf("got error %v, want the last unit reported out of stock", err)
+		}
+	}
+	if placed != 1 {
+		t.Fatalf("%d checkouts went through, want exactly one", placed)
+	}
+
+	var orders int64
+	if err := db.Model(&domain.Order{}).Where("user_id IN ?", buyerIds).Count(&orders).Error; err != nil {
+		t.Fatalf("counting orders: %v", err)
+	}
+	if orders != 1 {
+		t.Fatalf("%d orders created for the last unit, want 1", orders)
+	}
+
+	var stock uint
+	if err := db.Model(&domain.Product{}).Select("stock").Where("id=?", product.ID).Scan(&stock).Error; err != nil {
+		t.Fatalf("reading stock: %v", err)
+	}
+	if stock != 0 {
+		t.Fatalf("stock is %d after selling out, want 0", stock)
+	}
+}
+
+// soldOut tells whether checkout refused the product for lack of stock,
+// either found under the stock lock or in the cart sent back for review
+func soldOut(err error, productId uint) bool {
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		return true
+	}
+
+	var changed *CartChangedError
+	if !errors.As(err, &changed) {
+		return false
+	}
+	for _, line := range changed.Cart.Items {
+		if line.ProductId == int(productId) && line.InsufficientStock && line.Available == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+	"time"
+)
+
+var ErrReportRange = errors.New("report dates should be YYYY-MM-DD with from not after to")
+
+// CommissionService manages the commission rules and reports on the
+// commission earned. Rules only apply to orders placed after they change.
+type CommissionService struct {
+	Repo        repository.CommissionRepository
+	LedgerRepo  repository.LedgerRepository
+	CatalogRepo repository.CatalogRepository
+	Auth        helper.Auth
+	Config      configs.AppConfig
+}
+
+func (s *CommissionService) GetRules() ([]*domain.CommissionRule, error) {
+	return s.Repo.FindRules()
+}
+
+func (s *CommissionService) CreateRule(u domain.User, input dto.CommissionRuleRequest) (*domain.CommissionRule, error) {
+
+	rule := &domain.CommissionRule{CreatedBy: u.ID, Active: true}
+	if err := s.applyRuleInput(rule, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.CreateRule(rule); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// UpdateRule changes the rate or the active flag, what a rule covers stays
+// the same once created.
+func (s *CommissionService) UpdateRule(ruleId uint, input dto.CommissionRuleRequest) (*domain.CommissionRule, error) {
+
+	rule, err := s.Repo.FindRule(ruleId)
+	if err != nil {
+		return nil, err
+	}
+
+	input.Scope, input.CategoryId, input.Tier = rule.Scope, rule.CategoryId, rule.Tier
+	if err := s.applyRuleInput(rule, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.UpdateRule(rule); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (s *CommissionService) DeleteRule(ruleId uint) error {
+	return s.Repo.DeleteRule(ruleId)
+}
+
+func (s *CommissionService) applyRuleInput(rule *domain.CommissionRule, input dto.CommissionRuleRequest) error {
+
+	if input.Rate < 0 || input.Rate > 10000 {
+		return errors.New("rate should be basis points between 0 and 10000")
+	}
+
+	tier := strings.ToLower(strings.TrimSpace(input.Tier))
+	switch input.Scope {
+	case domain.COMMISSION_GLOBAL:
+		input.CategoryId, tier = 0, ""
+	case domain.COMMISSION_CATEGORY:
+		if _, err := s.CatalogRepo.FindCategoryById(int(input.CategoryId)); err != nil {
+			return errors.New("category not found")
+		}
+		tier = ""
+	case domain.COMMISSION_TIER:
+		if tier == "" {
+			return errors.New("tier rules need a tier")
+		}
+		input.CategoryId = 0
+	default:
+		return errors.New("scope should be global, category or tier")
+	}
+
+	//a new rule must not shadow an existing one
+	if rule.ID == 0 {
+		rules, err := s.Repo.FindRules()
+		if err != nil {
+			return err
+		}
+		for _, r := range rules {
+			if r.Scope == input.Scope && r.CategoryId == input.CategoryId && r.Tier == tier {
+				return errors.New("a rule for this scope already exists, update it instead")
+			}
+		}
+	}
+
+	rule.Scope = input.Scope
+	rule.CategoryId = input.CategoryId
+	rule.Tier = tier
+	rule.Rate = input.Rate
+	if input.Active != nil {
+		rule.Active = *input.Active
+	}
+
+	return nil
+}
+
+// SetSellerTier moves the seller to a tier, an empty tier takes them out
+// of every tier rule
+func (s *CommissionService) SetSellerTier(sellerId int, input dto.SellerTierRequest) error {
+	return s.Repo.UpdateSellerTier(sellerId, strings.ToLower(strings.TrimSpace(input.Tier)))
+}
+
+// GetReport sums the commission booked between the two days, the last 30
+// days when they are left out. Refunds count on the day they were made.
+func (s *CommissionService) GetReport(from string, to string) (*dto.CommissionReport, error) {
+
+	const layout = "2006-01-02"
+
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	if to != "" {
+		var err error
+		if end, err = time.Parse(layout, to); err != nil {
+			return nil, ErrReportRange
+		}
+	}
+
+	start := end.AddDate(0, 0, -29)
+	if from != "" {
+		var err error
+		if start, err = time.Parse(layout, from); err != nil {
+			return nil, ErrReportRange
+		}
+	}
+
+	if start.After(end) {
+		return nil, ErrReportRange
+	}
+
+	sellers, err := s.LedgerRepo.FindCommissionSummaries(start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dto.CommissionReport{
+		From:    start.Format(layout),
+		To:      end.Format(layout),
+		Totals:  []domain.CommissionSummary{},
+		Sellers: sellers,
+	}
+
+	totals := map[string]int{}
+	for _, seller := range sellers {
+		currency := seller.Net.Currency
+		i, ok := totals[currency]
+		if !ok {
+			i = len(report.Totals)
+			totals[currency] = i
+			report.Totals = append(report.Totals, domain.CommissionSummary{
+				Earned:   domain.Money{Currency: currency},
+				Reversed: domain.Money{Currency: currency},
+				Net:      domain.Money{Currency: currency},
+			})
+		}
+
+		total := &report.Totals[i]
+		total.Earned = total.Earned.Add(seller.Earned)
+		total.Reversed = total.Reversed.Add(seller.Reversed)
+		total.Net = total.Net.Add(seller.Net)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/repository"
+)
+
+// commissionRate is the rate of the most specific active rule covering a
+// sale in the category by a seller of the tier, fallback when none does
+func commissionRate(rules []*domain.CommissionRule, categoryId uint, tier string, fallback int64) int64 {
+
+	rate, rank := fallback, 0
+	for _, rule := range rules {
+		specificity := 0
+		switch {
+		case rule.Scope == domain.COMMISSION_CATEGORY && rule.CategoryId == categoryId:
+			specificity = 3
+		case rule.Scope == domain.COMMISSION_TIER && tier != "" && rule.Tier == tier:
+			specificity = 2
+		case rule.Scope == domain.COMMISSION_GLOBAL:
+			specificity = 1
+		}
+
+		if specificity > rank {
+			rate, rank = rule.Rate, specificity
+		}
+	}
+
+	return rate
+}
+
+// setCommission fixes the commission of the items of an order being
+// placed, so later rule changes leave it alone
+func setCommission(repos repository.Repositories, items []domain.OrderItem, products []*domain.Product, fallback int64) error {
+
+	rules, err := repos.Commission.FindActiveRules()
+	if err != nil {
+		return err
+	}
+
+	categories := map[int]uint{}
+	for _, product := range products {
+		categories[int(product.ID)] = product.CategoryID
+	}
+
+	tiers := map[int]string{}
+	for i := range items {
+		item := &items[i]
+
+		//gift cards are sold by the platform itself
+		if item.GiftCard {
+			item.CommissionRate = 0
+			item.Commission = domain.Money{Currency: item.Price.Currency}
+			continue
+		}
+
+		tier, ok := tiers[item.SellerId]
+		if !ok {
+			seller, err := repos.User.FindUserbyID(item.SellerId)
+			if err != nil {
+				return err
+			}
+			tier = seller.SellerTier
+			tiers[item.SellerId] = tier
+		}
+
+		item.CommissionRate = commissionRate(rules, categories[item.ProductId], tier, fallback)
+		item.Commission = item.Price.Mul(int64(item.Qty)).Sub(item.Discount).Percent(item.CommissionRate)
+	}
+
+	return nil
+}
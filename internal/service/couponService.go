@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+)
+
+// CouponService manages coupons for admins and sellers. Coupons a seller
+// creates only ever discount that seller's own items.
+type CouponService struct {
+	Repo   repository.CouponRepository
+	Auth   helper.Auth
+	Config configs.AppConfig
+}
+
+func (s *CouponService) GetCoupons(u domain.User) ([]*domain.Coupon, error) {
+	return s.Repo.FindCoupons(u.ID)
+}
+
+func (s *CouponService) GetCoupon(u domain.User, couponId uint) (*domain.Coupon, error) {
+	return s.Repo.FindCoupon(couponId, u.ID)
+}
+
+func (s *CouponService) CreateCoupon(u domain.User, input dto.CouponRequest) (*domain.Coupon, error) {
+
+	coupon := &domain.Coupon{CreatedBy: u.ID, Active: true}
+	if err := s.applyCouponInput(u, coupon, input); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.Repo.FindCouponByCode(coupon.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, errors.New("coupon code is already taken")
+	}
+
+	if err := s.Repo.CreateCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+// UpdateCoupon changes the coupon terms, the code of a coupon stays the
+// same once created.
+func (s *CouponService) UpdateCoupon(u domain.User, couponId uint, input dto.CouponRequest) (*domain.Coupon, error) {
+
+	coupon, err := s.Repo.FindCoupon(couponId, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	input.Code = coupon.Code
+	if err := s.applyCouponInput(u, coupon, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.UpdateCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+func (s *CouponService) applyCouponInput(u domain.User, coupon *domain.Coupon, input dto.CouponRequest) error {
+
+	code := strings.ToUpper(strings.TrimSpace(input.Code))
+	if len(code) < 3 {
+		return errors.New("coupon code should be at least 3 characters long")
+	}
+
+	switch input.Type {
+	case domain.COUPON_FIXED:
+		if input.Amount <= 0 {
+			return errors.New("fixed coupons need an amount")
+		}
+	case domain.COUPON_PERCENT:
+		if input.Percent <= 0 || input.Percent > 10000 {
+			return errors.New("percent should be between 1 and 10000 basis points")
+		}
+	default:
+		return errors.New("coupon type should be fixed or percent")
+	}
+
+	if input.MinSpend < 0 || input.MaxUses < 0 || input.MaxUsesPerUser < 0 {
+		return errors.New("coupon limits cannot be negative")
+	}
+
+	if input.StartsAt != nil && input.EndsAt != nil && input.EndsAt.Before(*input.StartsAt) {
+		return errors.New("coupon ends before it starts")
+	}
+
+	coupon.Code = code
+	coupon.Description = input.Description
+	coupon.Type = input.Type
+	coupon.Amount = domain.NewMoney(0, s.Config.Currency)
+	coupon.Percent = 0
+	if input.Type == domain.COUPON_FIXED {
+		coupon.Amount = domain.NewMoney(input.Amount, s.Config.Currency)
+	} else {
+		coupon.Percent = input.Percent
+	}
+	coupon.MinSpend = domain.NewMoney(input.MinSpend, s.Config.Currency)
+	coupon.MaxUses = input.MaxUses
+	coupon.MaxUsesPerUser = input.MaxUsesPerUser
+	coupon.StartsAt = input.StartsAt
+	coupon.EndsAt = input.EndsAt
+	if input.Active != nil {
+		coupon.Active = *input.Active
+	}
+
+	sellerIds := input.SellerIds
+	if u.UserType == domain.SELLER {
+		sellerIds = []uint{uint(u.ID)}
+	}
+
+	coupon.Scopes = []domain.CouponScope{}
+	for _, id := range input.CategoryIds {
+		coupon.Scopes = append(coupon.Scopes, domain.CouponScope{Kind: domain.SCOPE_CATEGORY, TargetId: id})
+	}
+	for _, id := range input.ProductIds {
+		coupon.Scopes = append(coupon.Scopes, domain.CouponScope{Kind: domain.SCOPE_PRODUCT, TargetId: id})
+	}
+	for _, id := range sellerIds {
+		coupon.Scopes = append(coupon.Scopes, domain.CouponScope{Kind: domain.SCOPE_SELLER, TargetId: id})
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"time"
+)
+
+var (
+	ErrCouponNotFound = errors.New("coupon code not found")
+	ErrCouponInvalid  = errors.New("coupon can't be applied")
+)
+
+// applyCoupon prices the coupon on the buyer's cart and spreads the discount
+// over the eligible lines, on top of any promotion discounts. A coupon that
+// stopped applying stays on the cart with the reason, the buyer pays full
+// price until they remove it or the cart qualifies again.
+func applyCoupon(repos repository.Repositories, userId int, cart *dto.CartResponse, products []*domain.Product) error {
+
+	cartCoupon, err := repos.Coupon.FindCartCoupon(userId)
+	if err != nil || cartCoupon == nil {
+		return err
+	}
+
+	coupon, err := repos.Coupon.FindCouponById(cartCoupon.CouponId)
+	if err != nil {
+		return err
+	}
+
+	used, err := repos.Coupon.UserRedemptions(coupon.ID, userId)
+	if err != nil {
+		return err
+	}
+
+	cart.Coupon = &dto.AppliedCoupon{
+		CouponId:    coupon.ID,
+		Code:        coupon.Code,
+		Description: coupon.Description,
+		Discount:    domain.Money{Currency: cart.Subtotal.Currency},
+	}
+
+	if err := checkCouponUsable(coupon, used, time.Now()); err != nil {
+		cart.Coupon.Invalid = err.Error()
+		return nil
+	}
+
+	categories := map[int]uint{}
+	for _, product := range products {
+		categories[int(product.ID)] = product.CategoryID
+	}
+
+	//eligible lines and what the buyer pays for them
+	var eligible []int
+	var weights []int64
+	eligibleTotal := domain.Money{Currency: cart.Subtotal.Currency}
+	for i, line := range cart.Items {
+		qty := lineQty(line)
+		if qty == 0 || !couponCovers(coupon, line, categories[line.ProductId]) {
+			continue
+		}
+
+		amount := line.CurrentPrice.Mul(int64(qty)).Sub(line.Discount)
+		eligible = append(eligible, i)
+		weights = append(weights, amount.Amount)
+		eligibleTotal = eligibleTotal.Add(amount)
+	}
+
+	if len(eligible) == 0 {
+		cart.Coupon.Invalid = "coupon doesn't apply to any item in the cart"
+		return nil
+	}
+
+	if eligibleTotal.LessThan(coupon.MinSpend) {
+		cart.Coupon.Invalid = fmt.Sprintf("coupon needs a minimum spend of %s", coupon.MinSpend)
+		return nil
+	}
+
+	var discount domain.Money
+	if coupon.Type == domain.COUPON_PERCENT {
+		discount = eligibleTotal.Percent(coupon.Percent)
+	} else {
+		discount = coupon.Amount.Min(eligibleTotal)
+	}
+
+	for k, part := range discount.Allocate(weights) {
+		cart.Items[eligible[k]].Discount = cart.Items[eligible[k]].Discount.Add(part)
+	}
+
+	cart.Coupon.Discount = discount
+	cart.Discount = cart.Discount.Add(discount)
+	return nil
+}
+
+// checkCouponUsable checks the coupon is live and not used up, used is how
+// many times the buyer redeemed it already.
+func checkCouponUsable(coupon *domain.Coupon, used int64, now time.Time) error {
+
+	switch {
+	case !coupon.Active:
+		return errors.New("coupon is no longer active")
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt):
+		return errors.New("coupon is not valid yet")
+	case coupon.EndsAt != nil && now.After(*coupon.EndsAt):
+		return errors.New("coupon has expired")
+	case coupon.MaxUses > 0 && coupon.Used >= coupon.MaxUses:
+		return errors.New("coupon usage limit reached")
+	case coupon.MaxUsesPerUser > 0 && used >= int64(coupon.MaxUsesPerUser):
+		return errors.New("you have already used this coupon")
+	}
+
+	return nil
+}
+
+// couponCovers reports whether the line matches one target of every scope
+// kind the coupon has.
+func couponCovers(coupon *domain.Coupon, line dto.CartItemResponse, category uint) bool {
+
+	matched := map[string]bool{}
+	for _, scope := range coupon.Scopes {
+		if matched[scope.Kind] {
+			continue
+		}
+
+		switch scope.Kind {
+		case domain.SCOPE_CATEGORY:
+			matched[scope.Kind] = scope.TargetId == category
+		case domain.SCOPE_PRODUCT:
+			matched[scope.Kind] = scope.TargetId == uint(line.ProductId)
+		case domain.SCOPE_SELLER:
+			matched[scope.Kind] = scope.TargetId == uint(line.SellerId)
+		}
+	}
+
+	for _, ok := range matched {
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// redeemCoupon records the coupon priced into the order. The coupon row is
+// locked so concurrent checkouts can't go over the usage limits.
+func redeemCoupon(repos repository.Repositories, userId int, order *domain.Order, applied *dto.AppliedCoupon) error {
+
+	coupon, err := repos.Coupon.FindCouponForUpdate(applied.CouponId)
+	if err != nil {
+		return err
+	}
+
+	used, err := repos.Coupon.UserRedemptions(coupon.ID, userId)
+	if err != nil {
+		return err
+	}
+
+	if err := checkCouponUsable(coupon, used, time.Now()); err != nil {
+		return ErrCartChanged
+	}
+
+	coupon.Used++
+	if err := repos.Coupon.UpdateUsage(coupon); err != nil {
+		return err
+	}
+
+	return repos.Coupon.CreateRedemption(&domain.CouponRedemption{
+		CouponId: coupon.ID,
+		UserId:   userId,
+		OrderId:  order.ID,
+		Discount: applied.Discount,
+	})
+}
+
+// releaseCoupon gives the coupon use of a cancelled order back
+func releaseCoupon(repos repository.Repositories, orderId uint) error {
+
+	redemption, err := repos.Coupon.FindOrderRedemption(orderId)
+	if err != nil || redemption == nil {
+		return err
+	}
+
+	coupon, err := repos.Coupon.FindCouponForUpdate(redemption.CouponId)
+	if err != nil {
+		return err
+	}
+
+	if coupon.Used > 0 {
+		coupon.Used--
+	}
+
+	if err := repos.Coupon.UpdateUsage(coupon); err != nil {
+		return err
+	}
+
+	return repos.Coupon.DeleteRedemption(redemption.ID)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/pkg/fx"
+	"strings"
+)
+
+var ErrCurrency = errors.New("currency is not supported")
+
+// currencyCode checks a currency picked by a buyer or seller against the
+// rate table, prices in it have to be convertible.
+func currencyCode(rates *fx.Table, code string) (string, error) {
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !rates.Supports(code) {
+		return "", fmt.Errorf("%w %s", ErrCurrency, code)
+	}
+
+	return code, nil
+}
+
+// localizePrices converts product prices from the seller's currency into
+// the one orders are paid in, before promotions and the cart see them.
+// ListPrice keeps the seller's price. Products already converted are left
+// alone.
+func localizePrices(rates *fx.Table, currency string, products []*domain.Product) error {
+
+	for _, product := range products {
+		if product.ListPrice != nil {
+			continue
+		}
+
+		rate, err := rates.Rate(product.Price.Currency, currency)
+		if err != nil {
+			return err
+		}
+
+		listPrice := product.Price
+		product.ListPrice = &listPrice
+		product.Price = listPrice.Convert(currency, rate.Value)
+
+		for i := range product.Variants {
+			variant := &product.Variants[i]
+			variant.ListPrice = &listPrice
+			if !variant.HasPrice() {
+				continue
+			}
+
+			rate, err := rates.Rate(variant.Price.Currency, currency)
+			if err != nil {
+				return err
+			}
+
+			variantPrice := variant.Price
+			variant.ListPrice = &variantPrice
+			variant.Price = variantPrice.Convert(currency, rate.Value)
+		}
+	}
+
+	return nil
+}
+
+// displayPrices shows the selling price of converted products in the
+// buyer's currency as well, nothing is charged in it.
+func displayPrices(rates *fx.Table, currency string, display string, products []*domain.Product) error {
+
+	if display == "" {
+		return nil
+	}
+
+	rate, err := rates.Rate(currency, display)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		price := displayAmount(product.SellingPrice(), rate)
+		product.DisplayPrice = &price
+
+		for i := range product.Variants {
+			variant := &product.Variants[i]
+			price := displayAmount(product.VariantPrice(variant), rate)
+			variant.DisplayPrice = &price
+		}
+	}
+
+	return nil
+}
+
+// displayCart adds the cart totals in the buyer's display currency
+func displayCart(rates *fx.Table, currency string, display string, cart *dto.CartResponse) error {
+
+	if display == "" || display == currency {
+		return nil
+	}
+
+	rate, err := rates.Rate(currency, display)
+	if err != nil {
+		return err
+	}
+
+	cart.Display = &dto.CartDisplay{
+		Currency:  rate.To,
+		Rate:      rate.String(),
+		Subtotal:  displayAmount(cart.Subtotal, rate),
+		Total:     displayAmount(cart.Total, rate),
+		AmountDue: displayAmount(cart.AmountDue, rate),
+	}
+
+	return nil
+}
+
+// displayAmount converts at rate, a zero amount may not carry a currency yet
+func displayAmount(m domain.Money, rate fx.Rate) domain.Money {
+	m.Currency = rate.From
+	return m.Convert(rate.To, rate.Value)
+}
+
+// exchangeRates snapshots the rates an order is placed at: every seller
+// currency into the order currency, and the order currency into the buyer's
+// display currency. Amounts on the order are never converted again.
+func exchangeRates(rates *fx.Table, currency string, order *domain.Order) ([]domain.ExchangeRate, error) {
+
+	var snapshot []domain.ExchangeRate
+	seen := map[string]bool{}
+
+	add := func(from string, to string) error {
+		if from == to || seen[from+to] {
+			return nil
+		}
+		seen[from+to] = true
+
+		rate, err := rates.Rate(from, to)
+		if err != nil {
+			return err
+		}
+
+		snapshot = append(snapshot, domain.ExchangeRate{
+			FromCurrency: rate.From,
+			ToCurrency:   rate.To,
+			Rate:         rate.String(),
+			Source:       rate.Source,
+			AsOf:         rate.AsOf,
+		})
+		return nil
+	}
+
+	for _, item := range order.Items {
+		if err := add(item.ListPrice.Currency, currency); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := add(currency, order.DisplayCurrency); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPaymentMethod    = errors.New("payment method should be card, cod or bank_transfer")
+	ErrCodGiftCards     = errors.New("gift cards can't be paid on delivery")
+	ErrTransferNotFound = errors.New("no unpaid bank transfer with this reference")
+	ErrCashNotFound     = errors.New("no uncollected cash on delivery payment with this reference")
+)
+
+// deferredReference is what the buyer quotes when paying an order outside
+// the gateway, it stands in for the gateway payment id
+func deferredReference(method string, orderRef int) string {
+	if method == domain.CAPTURE_COD {
+		return fmt.Sprintf("COD-%d", orderRef)
+	}
+	return fmt.Sprintf("BT-%d", orderRef)
+}
+
+// collectOnDelivery settles a cash on delivery order once the courier has
+// handed over every parcel, the sellers are credited from then on. Only the
+// carrier's tracking gets here: a buyer or seller marking the order
+// delivered leaves the cash for an admin to confirm.
+func collectOnDelivery(repos repository.Repositories, orderId uint) error {
+
+	order, err := repos.Transaction.FindOrderForUpdate(orderId)
+	if err != nil {
+		return err
+	}
+
+	if order.Status != domain.ORDER_DELIVERED {
+		return nil
+	}
+
+	p, err := repos.Transaction.FindPaymentByOrderId(orderId)
+	if err != nil {
+		return err
+	}
+
+	if p == nil || p.CaptureMethod != domain.CAPTURE_COD || p.Status != domain.PAYMENT_INITIAL {
+		return nil
+	}
+
+	return settleCash(repos, p, "collected on delivery")
+}
+
+// settleCash marks the cash for a delivered order as collected and credits
+// the sellers
+func settleCash(repos repository.Repositories, p *domain.Payment, response string) error {
+
+	p.Status = domain.PAYMENT_SUCCESS
+	p.Response = response
+	if err := repos.Transaction.UpdatePayment(p); err != nil {
+		return err
+	}
+
+	return recordSale(repos, p.OrderId)
+}
+
+// ConfirmCashCollection marks the cash for a delivered order as collected
+// when the courier hands it over without reporting the delivery through
+// tracking.
+func (s *TransactionService) ConfirmCashCollection(admin domain.User, input dto.ConfirmCashRequest) (*domain.Payment, error) {
+
+	reference := strings.ToUpper(strings.TrimSpace(input.Reference))
+
+	//unlocked read for the order id, the order is locked before its payment
+	p, err := s.Repo.FindPaymentByPaymentId(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if p == nil || p.CaptureMethod != domain.CAPTURE_COD || p.OrderId == 0 {
+		return nil, ErrCashNotFound
+	}
+
+	err = s.Tx.WithTx(func(repos repository.Repositories) error {
+
+		order, err := repos.Transaction.FindOrderForUpdate(p.OrderId)
+		if err != nil {
+			return err
+		}
+
+		p, err = repos.Transaction.FindPaymentByPaymentId(reference)
+		if err != nil {
+			return err
+		}
+
+		if p.Status != domain.PAYMENT_INITIAL {
+			return ErrCashNotFound
+		}
+
+		if order.Status != domain.ORDER_DELIVERED {
+			return fmt.Errorf("%w: order is %s, cash is collected on delivery", ErrInvalidTransition, order.Status)
+		}
+
+		if collected := domain.NewMoney(input.Amount, p.Amount.Currency); !collected.Equal(p.Amount) {
+			return fmt.Errorf("collected %s but %s is due", collected, p.Amount)
+		}
+
+		return settleCash(repos, p, fmt.Sprintf("collection confirmed by admin %d", admin.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetPaymentMethods lists how buyers can pay, with the account bank
+// transfers go to
+func (s *TransactionService) GetPaymentMethods() *dto.PaymentMethodsResponse {
+	return &dto.PaymentMethodsResponse{
+		Methods:             []string{domain.CAPTURE_CARD, domain.CAPTURE_COD, domain.CAPTURE_BANK_TRANSFER},
+		BankTransferDetails: s.Config.BankTransferDetails,
+		BankTransferWindow:  s.Config.BankTransferWindow.String(),
+	}
+}
+
+func (s *TransactionService) GetPendingTransfers() ([]*domain.Payment, error) {
+	return s.Repo.FindUnpaidTransfers(time.Now())
+}
+
+// ReconcileBankTransfer marks the bank transfer for an order as received
+// once it shows up on the platform account. The order is paid from then
+// on, like after a card payment.
+func (s *TransactionService) ReconcileBankTransfer(admin domain.User, input dto.ReconcileTransferRequest) (*domain.Payment, error) {
+
+	reference := strings.ToUpper(strings.TrimSpace(input.Reference))
+
+	//unlocked read for the order id, the order is locked before its payment
+	p, err := s.Repo.FindPaymentByPaymentId(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if p == nil || p.CaptureMethod != domain.CAPTURE_BANK_TRANSFER || p.OrderId == 0 {
+		return nil, ErrTransferNotFound
+	}
+
+	err = s.Tx.WithTx(func(repos repository.Repositories) error {
+
+		order, err := repos.Transaction.FindOrderForUpdate(p.OrderId)
+		if err != nil {
+			return err
+		}
+
+		p, err = repos.Transaction.FindPaymentByPaymentId(reference)
+		if err != nil {
+			return err
+		}
+
+		if p.Status != domain.PAYMENT_INITIAL {
+			return ErrTransferNotFound
+		}
+
+		//the order may have expired waiting for the money
+		if order.Status != domain.ORDER_PENDING_PAYMENT {
+			return fmt.Errorf("%w: order is %s, refund the transfer to the buyer", ErrInvalidTransition, order.Status)
+		}
+
+		if received := domain.NewMoney(input.Amount, p.Amount.Currency); !received.Equal(p.Amount) {
+			return fmt.Errorf("received %s but %s is due, the buyer has to send the exact amount", received, p.Amount)
+		}
+
+		p.Status = domain.PAYMENT_SUCCESS
+		p.TransactionId = input.BankReference
+		p.Response = fmt.Sprintf("reconciled by admin %d", admin.ID)
+		if err := repos.Transaction.UpdatePayment(p); err != nil {
+			return err
+		}
+
+		if err := repos.Transaction.UpdateOrderTransaction(order.ID, p.TransactionId); err != nil {
+			return err
+		}
+
+		if _, err := transitionOrder(repos.Transaction, order.ID, domain.ORDER_PAID, systemActor, "bank transfer received"); err != nil {
+			return err
+		}
+
+		if err := recordSale(repos, order.ID); err != nil {
+			return err
+		}
+
+		return issueGiftCards(repos, order.ID, s.Config.GiftCardValidity)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// ExpireBankTransfers cancels the orders whose bank transfer did not arrive
+// in time, so the stock they hold goes back on sale
+func (s *TransactionService) ExpireBankTransfers() (int, error) {
+
+	overdue, err := s.Repo.FindUnpaidTransfers(time.Now().Add(-s.Config.BankTransferWindow))
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, p := range overdue {
+		cancelled := false
+		err := s.Tx.WithTx(func(repos repository.Repositories) error {
+
+			order, err := repos.Transaction.FindOrderForUpdate(p.OrderId)
+			if err != nil {
+				return err
+			}
+
+			//reconciled or cancelled in the meantime
+			if order.Status != domain.ORDER_PENDING_PAYMENT {
+				return nil
+			}
+
+			_, err = cancelOrderItems(repos, s.Payment, p.OrderId, nil, false, "bank transfer not received in time", systemActor)
+			cancelled = err == nil
+			return err
+		})
+		if err != nil {
+			log.Printf("expiring bank transfer %s failed %v", p.PaymentId, err)
+			continue
+		}
+		if cancelled {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// RunTransferExpiry checks for overdue bank transfers every interval
+func (s *TransactionService) RunTransferExpiry(interval time.Duration) {
+
+	if interval <= 0 || s.Config.BankTransferWindow <= 0 {
+		log.Println("bank transfer expiry is off")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		expired, err := s.ExpireBankTransfers()
+		if err != nil {
+			log.Printf("bank transfer expiry failed %v", err)
+			continue
+		}
+		if expired > 0 {
+			log.Printf("cancelled %d orders with overdue bank transfers", expired)
+		}
+	}
+}
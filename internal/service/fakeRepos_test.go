@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/repository"
+	"time"
+)
+
+// In-memory repositories for the payment flows. Only the methods those
+// flows use are implemented, the embedded interfaces panic on anything
+// else. Rows are copied in and out like a database would.
+
+// fakeTx runs transactions straight against the fakes, nothing is rolled
+// back on errors.
+type fakeTx struct {
+	repos repository.Repositories
+}
+
+func (f *fakeTx) WithTx(fn func(repos repository.Repositories) error) error {
+	return fn(f.repos)
+}
+
+func (f *fakeTx) Repositories() repository.Repositories {
+	return f.repos
+}
+
+type fakeTransactionRepo struct {
+	repository.TransactionRepo
+
+	orders      map[uint]domain.Order
+	items       []domain.OrderItem
+	fulfilments []domain.Fulfilment
+	payments    []domain.Payment
+	events      map[string]domain.PaymentEvent
+	history     []domain.OrderHistory
+	refunds     []domain.Refund
+}
+
+func newFakeTransactionRepo() *fakeTransactionRepo {
+	return &fakeTransactionRepo{
+		orders: map[uint]domain.Order{},
+		events: map[string]domain.PaymentEvent{},
+	}
+}
+
+func (r *fakeTransactionRepo) CreatePaymentEvent(event *domain.PaymentEvent) (bool, error) {
+	if _, ok := r.events[event.EventId]; ok {
+		return false, nil
+	}
+	r.events[event.EventId] = *event
+	return true, nil
+}
+
+func (r *fakeTransactionRepo) findPayment(match func(p domain.Payment) bool) (*domain.Payment, error) {
+	for _, p := range r.payments {
+		if match(p) {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeTransactionRepo) FindPaymentByPaymentId(paymentId string) (*domain.Payment, error) {
+	return r.findPayment(func(p domain.Payment) bool { return p.PaymentId == paymentId })
+}
+
+func (r *fakeTransactionRepo) FindPaymentByOrderId(orderId uint) (*domain.Payment, error) {
+	return r.findPayment(func(p domain.Payment) bool { return p.OrderId == orderId })
+}
+
+func (r *fakeTransactionRepo) UpdatePayment(payment *domain.Payment) error {
+	for i := range r.payments {
+		if r.payments[i].ID == payment.ID {
+			r.payments[i] = *payment
+			return nil
+		}
+	}
+	return errors.New("payment not found")
+}
+
+func (r *fakeTransactionRepo) UpdateOrderTransaction(orderId uint, txnId string) error {
+	order := r.orders[orderId]
+	order.TransactionId = txnId
+	r.orders[orderId] = order
+	return nil
+}
+
+func (r *fakeTransactionRepo) FindOrderForUpdate(orderId uint) (*domain.Order, error) {
+	order, ok := r.orders[orderId]
+	if !ok {
+		return nil, errors.New("order not found")
+	}
+	return &order, nil
+}
+
+func (r *fakeTransactionRepo) UpdateOrderStatus(orderId uint, status string) error {
+	order := r.orders[orderId]
+	order.Status = status
+	r.orders[orderId] = order
+	return nil
+}
+
+func (r *fakeTransactionRepo) CreateOrderHistory(h *domain.OrderHistory) error {
+	r.history = append(r.history, *h)
+	return nil
+}
+
+func (r *fakeTransactionRepo) FindOrderItemsForUpdate(orderId uint) ([]*domain.OrderItem, error) {
+	var items []*domain.OrderItem
+	for _, item := range r.items {
+		if item.OrderId == int(orderId) {
+			item := item
+			items = append(items, &item)
+		}
+	}
+	return items, nil
+}
+
+func (r *fakeTransactionRepo) UpdateOrderItem(item *domain.OrderItem) error {
+	for i := range r.items {
+		if r.items[i].ID == item.ID {
+			r.items[i] = *item
+			return nil
+		}
+	}
+	return errors.New("order item not found")
+}
+
+func (r *fakeTransactionRepo) FindOrderFulfilmentsForUpdate(orderId uint) ([]*domain.Fulfilment, error) {
+	var fulfilments []*domain.Fulfilment
+	for _, f := range r.fulfilments {
+		if f.OrderId == orderId {
+			f := f
+			fulfilments = append(fulfilments, &f)
+		}
+	}
+	return fulfilments, nil
+}
+
+func (r *fakeTransactionRepo) UpdateFulfilment(f *domain.Fulfilment) error {
+	for i := range r.fulfilments {
+		if r.fulfilments[i].ID == f.ID {
+			r.fulfilments[i] = *f
+			return nil
+		}
+	}
+	return errors.New("fulfilment not found")
+}
+
+func (r *fakeTransactionRepo) CreateRefund(refund *domain.Refund) error {
+	refund.ID = uint(len(r.refunds) + 1)
+	r.refunds = append(r.refunds, *refund)
+	return nil
+}
+
+func (r *fakeTransactionRepo) GatewayRefunded(orderId uint) (int64, error) {
+	var amount int64
+	for _, refund := range r.refunds {
+		if refund.OrderId == orderId && refund.ProviderRefundId != "" {
+			amount += refund.Amount.Amount - refund.WalletAmount.Amount - refund.GiftCardAmount.Amount
+		}
+	}
+	return amount, nil
+}
+
+type fakeLedgerRepo struct {
+	repository.LedgerRepository
+
+	entries []domain.LedgerEntry
+}
+
+func (r *fakeLedgerRepo) CreateEntry(entry *domain.LedgerEntry) (bool, error) {
+
+	var total int64
+	for _, p := range entry.Postings {
+		total += p.Amount.Amount
+	}
+	if total != 0 {
+		return false, errors.New("ledger entry does not balance")
+	}
+
+	for _, e := range r.entries {
+		if e.Reference == entry.Reference {
+			return false, nil
+		}
+	}
+
+	entry.ID = uint(len(r.entries) + 1)
+	entry.CreatedAt = time.Now()
+	r.entries = append(r.entries, *entry)
+	return true, nil
+}
+
+func (r *fakeLedgerRepo) FindEntry(reference string) (*domain.LedgerEntry, error) {
+	for _, e := range r.entries {
+		if e.Reference == reference {
+			return &e, nil
+		}
+	}
+	return nil, nil
+}
+
+type fakeCatalogRepo struct {
+	repository.CatalogRepository
+
+	restocked map[int]uint
+}
+
+func (r *fakeCatalogRepo) IncrementStock(id int, qty uint) error {
+	r.restocked[id] += qty
+	return nil
+}
+
+type fakeCouponRepo struct {
+	repository.CouponRepository
+}
+
+func (r *fakeCouponRepo) FindOrderRedemption(orderId uint) (*domain.CouponRedemption, error) {
+	return nil, nil
+}
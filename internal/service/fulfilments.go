@@ -0,0 +1,188 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"time"
+)
+
+// createFulfilments groups the items of a new order by seller, one
+// fulfilment per seller shipped with the method the buyer picked.
+func createFulfilments(repo repository.TransactionRepo, order *domain.Order, shipping []dto.SellerShipping) error {
+
+	picked := map[int]*dto.ShippingOption{}
+	for _, parcel := range shipping {
+		picked[parcel.SellerId] = parcel.Selected
+	}
+
+	created := map[int]bool{}
+	for _, item := range order.Items {
+		if created[item.SellerId] {
+			continue
+		}
+		created[item.SellerId] = true
+
+		f := &domain.Fulfilment{
+			OrderId:  order.ID,
+			SellerId: item.SellerId,
+			Status:   domain.FULFILMENT_PENDING,
+		}
+		if option := picked[item.SellerId]; option != nil {
+			f.ShippingMethodId = option.MethodId
+			f.ShippingMethod = option.Name
+			f.ShippingCost = option.Price
+		}
+		if err := repo.CreateFulfilment(f); err != nil {
+			return err
+		}
+
+		if err := repo.AssignFulfilment(order.ID, item.SellerId, f.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transitionFulfilment moves a seller's fulfilment along an allowed edge and
+// brings the order status in line with its fulfilments.
+func transitionFulfilment(repo repository.TransactionRepo, fulfilmentId uint, input dto.UpdateOrderStatusRequest, actor OrderActor) (*domain.Fulfilment, error) {
+
+	//unlocked read for the order id, the order is locked before its
+	//fulfilments like everywhere else
+	f, err := repo.FindFulfilment(fulfilmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := repo.FindOrderForUpdate(f.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	fulfilments, err := repo.FindOrderFulfilmentsForUpdate(f.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, locked := range fulfilments {
+		if locked.ID == fulfilmentId {
+			f = locked
+		}
+	}
+
+	switch actor.Role {
+	case domain.BUYER:
+		if order.UserId != uint(actor.Id) {
+			return nil, errors.New("order not found")
+		}
+	case domain.SELLER:
+		if f.SellerId != actor.Id {
+			return nil, errors.New("order not found")
+		}
+	}
+
+	if !domain.CanTransitionFulfilment(f.Status, input.Status, actor.Role) {
+		return nil, fmt.Errorf("%w: fulfilment is %s, cannot move to %s", ErrInvalidTransition, f.Status, input.Status)
+	}
+
+	if order.Status == domain.ORDER_PENDING_PAYMENT {
+		return nil, fmt.Errorf("%w: order is not paid yet", ErrInvalidTransition)
+	}
+
+	now := time.Now()
+	switch input.Status {
+	case domain.FULFILMENT_SHIPPED:
+		if len(input.TrackingNumber) < 1 {
+			return nil, errors.New("a tracking number is required to ship")
+		}
+		f.Carrier = input.Carrier
+		f.TrackingNumber = input.TrackingNumber
+		f.ShippedAt = &now
+	case domain.FULFILMENT_DELIVERED:
+		f.DeliveredAt = &now
+	case domain.FULFILMENT_CANCELLED:
+		return nil, errors.New("cancel the fulfilment items instead")
+	}
+
+	f.Status = input.Status
+	if err := repo.UpdateFulfilment(f); err != nil {
+		return nil, err
+	}
+
+	if err := syncOrderStatus(repo, order, fulfilments, input.Note); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// fulfilmentProgress ranks fulfilment states against the order states they
+// move the order to.
+var fulfilmentProgress = map[string]int{
+	domain.FULFILMENT_PENDING:    0,
+	domain.FULFILMENT_PROCESSING: 1,
+	domain.FULFILMENT_SHIPPED:    2,
+	domain.FULFILMENT_DELIVERED:  3,
+}
+
+var orderProgress = []string{domain.ORDER_PAID, domain.ORDER_PROCESSING, domain.ORDER_SHIPPED, domain.ORDER_DELIVERED}
+
+// syncOrderStatus moves a paid order forward to follow its fulfilments. The
+// order is processing as soon as one seller starts on it, and shipped or
+// delivered only once every fulfilment that is not cancelled is.
+func syncOrderStatus(repo repository.TransactionRepo, order *domain.Order, fulfilments []*domain.Fulfilment, note string) error {
+
+	target := -1
+	started := false
+	for _, f := range fulfilments {
+		rank, ok := fulfilmentProgress[f.Status]
+		if !ok {
+			continue
+		}
+		if target == -1 || rank < target {
+			target = rank
+		}
+		if rank > 0 {
+			started = true
+		}
+	}
+
+	//every fulfilment cancelled, cancellation takes care of the order
+	if target == -1 {
+		return nil
+	}
+
+	if target == 0 && started {
+		target = 1
+	}
+
+	current := -1
+	for i, status := range orderProgress {
+		if status == order.Status {
+			current = i
+		}
+	}
+	//cash on delivery orders move on from confirmed like paid ones
+	if order.Status == domain.ORDER_CONFIRMED {
+		current = 0
+	}
+
+	//not paid yet, or already cancelled or refunded
+	if current == -1 {
+		return nil
+	}
+
+	for step := current + 1; step <= target; step++ {
+		updated, err := transitionOrder(repo, order.ID, orderProgress[step], systemActor, note)
+		if err != nil {
+			return err
+		}
+		order.Status = updated.Status
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+	"time"
+)
+
+// MAX_GIFT_CARD_BATCH is how many cards an admin can issue in one go
+const MAX_GIFT_CARD_BATCH = 1000
+
+// GiftCardService lets admins issue gift cards in bulk and sell them as
+// products, buyers redeem them through the cart
+type GiftCardService struct {
+	Tx     repository.TxManager
+	Auth   helper.Auth
+	Config configs.AppConfig
+}
+
+// IssueGiftCards creates count cards worth amount each under one batch
+// name, so they can be looked up or handed out together
+func (s *GiftCardService) IssueGiftCards(admin domain.User, input dto.IssueGiftCardsRequest) (*dto.IssueGiftCardsResponse, error) {
+
+	if input.Count < 1 || input.Count > MAX_GIFT_CARD_BATCH {
+		return nil, errors.New("count should be between 1 and 1000")
+	}
+
+	if input.Amount <= 0 {
+		return nil, errors.New("amount should be positive")
+	}
+
+	expiresAt := time.Now().Add(s.Config.GiftCardValidity)
+	if len(input.ExpiresAt) > 0 {
+		day, err := time.Parse("2006-01-02", input.ExpiresAt)
+		if err != nil {
+			return nil, errors.New("expiry date should be YYYY-MM-DD")
+		}
+		//cards can be spent through the whole expiry day
+		expiresAt = day.AddDate(0, 0, 1)
+	}
+
+	if !expiresAt.After(time.Now()) {
+		return nil, errors.New("expiry date should be in the future")
+	}
+
+	batch := strings.TrimSpace(input.Batch)
+	if len(batch) < 1 {
+		code, err := helper.RandomCode(8)
+		if err != nil {
+			return nil, errors.New("batch name generation failed")
+		}
+		batch = code
+	}
+
+	response := &dto.IssueGiftCardsResponse{Batch: batch}
+	err := s.Tx.WithTx(func(repos repository.Repositories) error {
+		for i := 0; i < input.Count; i++ {
+			card, err := newGiftCard(domain.NewMoney(input.Amount, s.Config.Currency), expiresAt)
+			if err != nil {
+				return err
+			}
+			card.IssuedBy = admin.ID
+			card.Batch = batch
+
+			if err := createGiftCard(repos, card, OrderActor{Id: admin.ID, Role: domain.ADMIN}); err != nil {
+				return err
+			}
+			response.Cards = append(response.Cards, card)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (s *GiftCardService) GetGiftCards(batch string) ([]*domain.GiftCard, error) {
+	return s.Tx.Repositories().GiftCard.FindGiftCards(batch)
+}
+
+func (s *GiftCardService) GetGiftCard(code string) (*dto.GiftCardDetails, error) {
+
+	repos := s.Tx.Repositories()
+
+	card, err := repos.GiftCard.FindGiftCardByCode(normalizeGiftCardCode(code))
+	if err != nil {
+		return nil, err
+	}
+
+	if card == nil {
+		return nil, ErrGiftCardNotFound
+	}
+
+	txns, err := repos.GiftCard.FindTransactions(card.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.GiftCardDetails{Card: card, Transactions: txns}, nil
+}
+
+// UpdateGiftCard disables a card, for example one reported stolen, or
+// enables it again. Carts holding a disabled card simply stop using it.
+func (s *GiftCardService) UpdateGiftCard(id uint, input dto.UpdateGiftCardRequest) (*domain.GiftCard, error) {
+
+	var card *domain.GiftCard
+	err := s.Tx.WithTx(func(repos repository.Repositories) error {
+		var err error
+		card, err = repos.GiftCard.FindGiftCardForUpdate(id)
+		if err != nil {
+			return ErrGiftCardNotFound
+		}
+
+		card.Disabled = input.Disabled
+		return repos.GiftCard.UpdateGiftCard(card)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+// CreateGiftCardProduct lists a gift card buyers can purchase, the price is
+// the value of the card issued for every unit sold. Stock caps how many can
+// be sold.
+func (s *GiftCardService) CreateGiftCardProduct(admin domain.User, input dto.CreateProductRequest) (*domain.Product, error) {
+
+	if len(strings.TrimSpace(input.Name)) < 1 {
+		return nil, errors.New("name is required")
+	}
+
+	if input.Price <= 0 {
+		return nil, errors.New("price should be positive")
+	}
+
+	return s.Tx.Repositories().Catalog.CreateProduct(&domain.Product{
+		Name:        input.Name,
+		Price:       domain.NewMoney(input.Price, s.Config.Currency),
+		Description: input.Description,
+		UserId:      admin.ID,
+		ImageUrl:    input.ImageUrl,
+		CategoryID:  input.CategoryID,
+		Type:        domain.PRODUCT_GIFT_CARD,
+		Stock:       input.Stock,
+	})
+}
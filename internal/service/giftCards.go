@@ -0,0 +1,318 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+	"time"
+)
+
+// GIFT_CARD_CODE_LENGTH characters of a 32 letter alphabet, 80 random bits
+const GIFT_CARD_CODE_LENGTH = 16
+
+var (
+	ErrGiftCardNotFound = errors.New("gift card not found")
+	ErrGiftCardInvalid  = errors.New("gift card can't be used")
+	ErrGiftCardBalance  = errors.New("not enough gift card balance")
+)
+
+// normalizeGiftCardCode lets buyers type codes in lower case and with the
+// dashes or spaces they were printed with
+func normalizeGiftCardCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// giftCardProblem says why the card can't pay in currency right now, empty
+// when it can
+func giftCardProblem(card *domain.GiftCard, currency string, now time.Time) string {
+	switch {
+	case card.Disabled:
+		return "gift card is disabled"
+	case card.Expired(now):
+		return "gift card expired"
+	case currency != "" && card.Balance.Currency != currency:
+		return "gift card is in a different currency"
+	case !card.Balance.GreaterThan(domain.Money{}):
+		return "gift card has no balance left"
+	}
+	return ""
+}
+
+// postGiftCard changes the balance of a locked card by txn.Amount and
+// records the transaction
+func postGiftCard(repos repository.Repositories, card *domain.GiftCard, txn *domain.GiftCardTransaction) error {
+
+	balance := card.Balance.Add(txn.Amount)
+	if balance.IsNegative() {
+		return ErrGiftCardBalance
+	}
+
+	card.Balance = balance
+	if err := repos.GiftCard.UpdateGiftCard(card); err != nil {
+		return err
+	}
+
+	txn.GiftCardId = card.ID
+	txn.BalanceAfter = balance
+	return repos.GiftCard.CreateTransaction(txn)
+}
+
+// applyGiftCards pays what is due on the cart with the buyer's redeemed
+// gift cards, in the order they were added
+func applyGiftCards(repos repository.Repositories, userId int, cart *dto.CartResponse) error {
+
+	cart.GiftCards = []dto.AppliedGiftCard{}
+	cart.GiftCardCredit = domain.Money{Currency: cart.Total.Currency}
+
+	cards, err := repos.GiftCard.FindCartGiftCards(userId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, card := range cards {
+		applied := dto.AppliedGiftCard{
+			GiftCardId: card.ID,
+			Code:       card.MaskedCode(),
+			Balance:    card.Balance,
+			Amount:     domain.Money{Currency: card.Balance.Currency},
+			Invalid:    giftCardProblem(card, cart.Total.Currency, now),
+		}
+
+		if applied.Invalid == "" && cart.AmountDue.GreaterThan(domain.Money{}) {
+			applied.Amount = cart.AmountDue.Min(card.Balance)
+			cart.GiftCardCredit = cart.GiftCardCredit.Add(applied.Amount)
+			cart.AmountDue = cart.AmountDue.Sub(applied.Amount)
+		}
+
+		cart.GiftCards = append(cart.GiftCards, applied)
+	}
+
+	return nil
+}
+
+// redeemGiftCards takes what the cart applied from each gift card off its
+// balance for the order just placed
+func redeemGiftCards(repos repository.Repositories, orderId uint, applied []dto.AppliedGiftCard, actor OrderActor) error {
+
+	now := time.Now()
+	for _, a := range applied {
+		if !a.Amount.GreaterThan(domain.Money{}) {
+			continue
+		}
+
+		card, err := repos.GiftCard.FindGiftCardForUpdate(a.GiftCardId)
+		if err != nil {
+			return err
+		}
+
+		if giftCardProblem(card, a.Amount.Currency, now) != "" {
+			return ErrCartChanged
+		}
+
+		err = postGiftCard(repos, card, &domain.GiftCardTransaction{
+			Kind:      domain.GIFT_CARD_REDEEM,
+			Amount:    domain.Money{}.Sub(a.Amount),
+			OrderId:   orderId,
+			ActorId:   actor.Id,
+			ActorRole: actor.Role,
+		})
+		if errors.Is(err, ErrGiftCardBalance) {
+			return ErrCartChanged
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// giftCardRestore is part of a refund going back on a gift card
+type giftCardRestore struct {
+	card   *domain.GiftCard
+	amount domain.Money
+}
+
+// planGiftCardRestores spreads amount of a refund over the gift cards the
+// order was paid with, as far as each card paid for it. Cards that have
+// expired or were disabled since can't take it back, what is left over is
+// returned for the wallet.
+func planGiftCardRestores(repos repository.Repositories, orderId uint, amount domain.Money) ([]giftCardRestore, domain.Money, error) {
+
+	txns, err := repos.GiftCard.FindOrderTransactions(orderId)
+	if err != nil {
+		return nil, domain.Money{}, err
+	}
+
+	//what each card paid and has not had back yet
+	var cardIds []uint
+	owed := map[uint]domain.Money{}
+	for _, txn := range txns {
+		if _, ok := owed[txn.GiftCardId]; !ok {
+			cardIds = append(cardIds, txn.GiftCardId)
+		}
+		owed[txn.GiftCardId] = owed[txn.GiftCardId].Sub(txn.Amount)
+	}
+
+	var restores []giftCardRestore
+	left := amount
+	now := time.Now()
+	for _, id := range cardIds {
+		back := left.Min(owed[id])
+		if !back.GreaterThan(domain.Money{}) {
+			continue
+		}
+
+		card, err := repos.GiftCard.FindGiftCardForUpdate(id)
+		if err != nil {
+			return nil, domain.Money{}, err
+		}
+
+		if card.Disabled || card.Expired(now) {
+			continue
+		}
+
+		restores = append(restores, giftCardRestore{card: card, amount: back})
+		left = left.Sub(back)
+	}
+
+	return restores, left, nil
+}
+
+func restoreGiftCards(repos repository.Repositories, restores []giftCardRestore, refund *domain.Refund, actor OrderActor) error {
+
+	for _, r := range restores {
+		err := postGiftCard(repos, r.card, &domain.GiftCardTransaction{
+			Kind:      domain.GIFT_CARD_REFUND,
+			Amount:    r.amount,
+			OrderId:   refund.OrderId,
+			RefundId:  refund.ID,
+			ActorId:   actor.Id,
+			ActorRole: actor.Role,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newGiftCard opens a card worth value with a fresh code
+func newGiftCard(value domain.Money, expiresAt time.Time) (*domain.GiftCard, error) {
+
+	code, err := helper.RandomCode(GIFT_CARD_CODE_LENGTH)
+	if err != nil {
+		return nil, errors.New("gift card code generation failed")
+	}
+
+	return &domain.GiftCard{
+		Code:         code,
+		InitialValue: value,
+		Balance:      value,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// createGiftCard saves a new card along with the transaction issuing its
+// balance
+func createGiftCard(repos repository.Repositories, card *domain.GiftCard, actor OrderActor) error {
+
+	if err := repos.GiftCard.CreateGiftCard(card); err != nil {
+		return err
+	}
+
+	return repos.GiftCard.CreateTransaction(&domain.GiftCardTransaction{
+		GiftCardId:   card.ID,
+		Kind:         domain.GIFT_CARD_ISSUE,
+		Amount:       card.InitialValue,
+		BalanceAfter: card.Balance,
+		OrderId:      card.OrderId,
+		ActorId:      actor.Id,
+		ActorRole:    actor.Role,
+	})
+}
+
+// issueGiftCards issues a card for every gift card unit of a paid order,
+// worth the face value of the product. There is nothing to ship, so the
+// parcels holding only gift cards are delivered right away. Safe to call
+// again, cards are issued once.
+func issueGiftCards(repos repository.Repositories, orderId uint, validity time.Duration) error {
+
+	items, err := repos.Transaction.FindOrderItemsForUpdate(orderId)
+	if err != nil {
+		return err
+	}
+
+	var giftCards []*domain.OrderItem
+	for _, item := range items {
+		if item.GiftCard && item.Status == domain.ITEM_ACTIVE {
+			giftCards = append(giftCards, item)
+		}
+	}
+
+	if len(giftCards) == 0 {
+		return nil
+	}
+
+	issued, err := repos.GiftCard.FindOrderGiftCards(orderId)
+	if err != nil || len(issued) > 0 {
+		return err
+	}
+
+	order, err := repos.Transaction.FindOrderForUpdate(orderId)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(validity)
+	count := 0
+	for _, item := range giftCards {
+		for i := 0; i < item.Qty; i++ {
+			card, err := newGiftCard(item.Price, expiresAt)
+			if err != nil {
+				return err
+			}
+			card.OrderId = orderId
+			card.OrderItemId = item.ID
+			card.PurchaserId = int(order.UserId)
+
+			if err := createGiftCard(repos, card, systemActor); err != nil {
+				return err
+			}
+			count++
+		}
+	}
+
+	fulfilments, err := repos.Transaction.FindOrderFulfilmentsForUpdate(orderId)
+	if err != nil {
+		return err
+	}
+
+	physical := map[uint]bool{}
+	for _, item := range items {
+		if !item.GiftCard && item.Status == domain.ITEM_ACTIVE {
+			physical[item.FulfilmentId] = true
+		}
+	}
+
+	now := time.Now()
+	for _, f := range fulfilments {
+		if physical[f.ID] || f.Status != domain.FULFILMENT_PENDING {
+			continue
+		}
+
+		f.Status = domain.FULFILMENT_DELIVERED
+		f.DeliveredAt = &now
+		if err := repos.Transaction.UpdateFulfilment(f); err != nil {
+			return err
+		}
+	}
+
+	return syncOrderStatus(repos.Transaction, order, fulfilments, fmt.Sprintf("%d gift cards issued", count))
+}
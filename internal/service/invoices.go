@@ -0,0 +1,307 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/invoice"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Invoice formats
+const (
+	INVOICE_PDF  = "pdf"
+	INVOICE_HTML = "html"
+)
+
+var (
+	ErrInvoiceNotReady = errors.New("the invoice is available once the order is paid")
+	ErrInvoiceFormat   = errors.New("invoice format should be pdf or html")
+)
+
+// issueInvoice returns the invoice of the order, or of one fulfilment of it
+// when fulfilmentId is set, issuing it on the first request. Buyers get the
+// invoice of their whole order, sellers the one of their fulfilment.
+func issueInvoice(tx repository.TxManager, orderId uint, fulfilmentId uint, actor OrderActor) (*domain.Invoice, error) {
+
+	var issued *domain.Invoice
+	err := tx.WithTx(func(repos repository.Repositories) error {
+
+		//the order lock keeps concurrent first downloads from issuing twice
+		order, err := repos.Transaction.FindOrderForUpdate(orderId)
+		if err != nil {
+			return err
+		}
+
+		if actor.Role == domain.BUYER && order.UserId != uint(actor.Id) {
+			return errors.New("order not found")
+		}
+
+		fulfilments, err := repos.Transaction.FindOrderFulfilmentsForUpdate(orderId)
+		if err != nil {
+			return err
+		}
+
+		var fulfilment *domain.Fulfilment
+		for _, f := range fulfilments {
+			if f.ID == fulfilmentId {
+				fulfilment = f
+			}
+		}
+
+		if fulfilmentId > 0 && (fulfilment == nil || actor.Role == domain.SELLER && fulfilment.SellerId != actor.Id) {
+			return errors.New("order not found")
+		}
+
+		issued, err = repos.Invoice.FindInvoice(orderId, fulfilmentId)
+		if err != nil || issued != nil {
+			return err
+		}
+
+		items, err := repos.Transaction.FindOrderItemsForUpdate(orderId)
+		if err != nil {
+			return err
+		}
+
+		p, err := repos.Transaction.FindPaymentByOrderId(orderId)
+		if err != nil {
+			return err
+		}
+
+		//refunds later on don't undo the sale the invoice is for, and orders
+		//gift cards or the wallet paid for in full have no card payment
+		if p != nil && p.Status != domain.PAYMENT_SUCCESS && p.Status != domain.PAYMENT_PARTIALLY_REFUNDED && p.Status != domain.PAYMENT_REFUNDED {
+			return ErrInvoiceNotReady
+		}
+
+		number, err := repos.Invoice.NextInvoiceNumber()
+		if err != nil {
+			return err
+		}
+
+		doc, total, err := buildInvoice(repos, number, order, fulfilments, fulfilment, items)
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("invoice encoding failed %w", err)
+		}
+
+		issued = &domain.Invoice{
+			Number:       number,
+			OrderId:      order.ID,
+			FulfilmentId: fulfilmentId,
+			UserId:       order.UserId,
+			Total:        total,
+			Document:     string(data),
+			IssuedAt:     doc.IssuedAt,
+		}
+
+		return repos.Invoice.CreateInvoice(issued)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return issued, nil
+}
+
+// buildInvoice lays out the items, shipping, discounts and taxes of the
+// order, or of the fulfilment when one is given. Cancelled items were
+// never sold and are left out.
+func buildInvoice(repos repository.Repositories, number string, order *domain.Order, fulfilments []*domain.Fulfilment, fulfilment *domain.Fulfilment, items []*domain.OrderItem) (*invoice.Document, domain.Money, error) {
+
+	currency := order.Amount.Currency
+	subtotal := domain.Money{Currency: currency}
+	discount := domain.Money{Currency: currency}
+	shipping := domain.Money{Currency: currency}
+	exclusive := domain.Money{Currency: currency}
+
+	buyer, err := repos.User.FindUserbyID(int(order.UserId))
+	if err != nil {
+		return nil, domain.Money{}, err
+	}
+
+	doc := &invoice.Document{
+		Number:   number,
+		IssuedAt: time.Now(),
+		OrderRef: strconv.Itoa(order.OrderRefNumber),
+		BillTo:   invoiceParty(buyer),
+		Lines:    []invoice.Line{},
+		TaxLines: []invoice.TaxLine{},
+	}
+
+	sellers := map[int]string{}
+	sellerName := func(id int) (string, error) {
+		if name, ok := sellers[id]; ok {
+			return name, nil
+		}
+		seller, err := repos.User.FindUserbyID(id)
+		if err != nil {
+			return "", err
+		}
+		sellers[id] = invoiceParty(seller).Name
+		return sellers[id], nil
+	}
+
+	if fulfilment != nil {
+		seller, err := repos.User.FindUserbyID(fulfilment.SellerId)
+		if err != nil {
+			return nil, domain.Money{}, err
+		}
+		doc.Seller = invoiceParty(seller)
+	}
+
+	//tax summed up per tax name and rate
+	type taxKey struct {
+		name      string
+		rate      int64
+		inclusive bool
+	}
+	taxes := map[taxKey]domain.Money{}
+	var taxOrder []taxKey
+
+	for _, item := range items {
+		if item.Status == domain.ITEM_CANCELLED || (fulfilment != nil && item.FulfilmentId != fulfilment.ID) {
+			continue
+		}
+
+		gross := item.Price.Mul(int64(item.Qty))
+		itemTax := domain.Money{Currency: currency}
+		for _, t := range item.Taxes {
+			key := taxKey{t.Name, t.Rate, t.Inclusive}
+			if _, ok := taxes[key]; !ok {
+				taxOrder = append(taxOrder, key)
+			}
+			taxes[key] = taxes[key].Add(t.Amount)
+			itemTax = itemTax.Add(t.Amount)
+			if !t.Inclusive {
+				exclusive = exclusive.Add(t.Amount)
+			}
+		}
+
+		line := invoice.Line{
+			Description: item.Name,
+			Qty:         item.Qty,
+			UnitPrice:   item.Price.String(),
+			Discount:    negative(item.Discount),
+			Tax:         itemTax.String(),
+			Amount:      gross.Sub(item.Discount).String(),
+		}
+
+		if fulfilment == nil {
+			if line.SoldBy, err = sellerName(item.SellerId); err != nil {
+				return nil, domain.Money{}, err
+			}
+		}
+
+		subtotal = subtotal.Add(gross)
+		discount = discount.Add(item.Discount)
+		doc.Lines = append(doc.Lines, line)
+	}
+
+	for _, f := range fulfilments {
+		if f.Status == domain.FULFILMENT_CANCELLED || (fulfilment != nil && f.ID != fulfilment.ID) {
+			continue
+		}
+		shipping = shipping.Add(f.ShippingCost)
+	}
+
+	for _, key := range taxOrder {
+		label := fmt.Sprintf("%s %s%%", key.name, formatRate(key.rate))
+		if key.inclusive {
+			label += " (included)"
+		}
+		doc.TaxLines = append(doc.TaxLines, invoice.TaxLine{Label: label, Amount: taxes[key].String()})
+	}
+
+	total := subtotal.Sub(discount).Add(shipping).Add(exclusive)
+
+	doc.Subtotal = subtotal.String()
+	doc.Discount = negative(discount)
+	doc.Shipping = shipping.String()
+	doc.Total = total.String()
+	doc.Notes = []string{"Refunds are issued separately and are not shown on this invoice."}
+	if fulfilment == nil && order.GiftCardAmount.GreaterThan(domain.Money{}) {
+		doc.Notes = append(doc.Notes, fmt.Sprintf("%s was paid with gift cards.", order.GiftCardAmount))
+	}
+	if fulfilment == nil && order.WalletAmount.GreaterThan(domain.Money{}) {
+		doc.Notes = append(doc.Notes, fmt.Sprintf("%s was paid from your wallet.", order.WalletAmount))
+	}
+
+	return doc, total, nil
+}
+
+// renderInvoice prints the stored invoice document
+func renderInvoice(issued *domain.Invoice, format string) (*dto.InvoiceFile, error) {
+
+	var doc invoice.Document
+	if err := json.Unmarshal([]byte(issued.Document), &doc); err != nil {
+		return nil, fmt.Errorf("invoice decoding failed %w", err)
+	}
+
+	file := &dto.InvoiceFile{Number: issued.Number}
+
+	var err error
+	switch format {
+	case "", INVOICE_PDF:
+		file.ContentType, file.Extension = "application/pdf", INVOICE_PDF
+		file.Data, err = invoice.RenderPDF(&doc)
+	case INVOICE_HTML:
+		file.ContentType, file.Extension = "text/html; charset=utf-8", INVOICE_HTML
+		file.Data, err = invoice.RenderHTML(&doc)
+	default:
+		return nil, ErrInvoiceFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func invoiceParty(u domain.User) invoice.Party {
+
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" {
+		name = u.Email
+	}
+
+	party := invoice.Party{Name: name}
+	a := u.Address
+	for _, line := range []string{a.AddressLine1, a.AddressLine2, strings.TrimSpace(fmt.Sprintf("%s %s", a.City, postCode(a.PostCode))), a.Country} {
+		if line != "" {
+			party.Lines = append(party.Lines, line)
+		}
+	}
+	party.Lines = append(party.Lines, u.Email)
+
+	return party
+}
+
+func postCode(code uint) string {
+	if code == 0 {
+		return ""
+	}
+	return strconv.Itoa(int(code))
+}
+
+func negative(m domain.Money) string {
+	if m.IsZero() {
+		return m.String()
+	}
+	return "-" + m.String()
+}
+
+// formatRate prints basis points as a percentage, 1950 is 19.5
+func formatRate(bps int64) string {
+	rate := strconv.FormatFloat(float64(bps)/100, 'f', 2, 64)
+	return strings.TrimRight(strings.TrimRight(rate, "0"), ".")
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/repository"
+)
+
+func saleReference(orderId uint) string {
+	return fmt.Sprintf("order:%d:sale", orderId)
+}
+
+// sellerPostings adds up amounts per seller on an account, keeping the
+// order sellers were first seen in
+type sellerPostings struct {
+	account string
+	sellers []int
+	amounts map[int]domain.Money
+}
+
+func (s *sellerPostings) add(sellerId int, amount domain.Money) {
+	if s.amounts == nil {
+		s.amounts = map[int]domain.Money{}
+	}
+	if _, ok := s.amounts[sellerId]; !ok {
+		s.sellers = append(s.sellers, sellerId)
+	}
+	s.amounts[sellerId] = s.amounts[sellerId].Add(amount)
+}
+
+func (s *sellerPostings) postings() []domain.LedgerPosting {
+	var postings []domain.LedgerPosting
+	for _, id := range s.sellers {
+		if s.amounts[id].IsZero() {
+			continue
+		}
+		postings = append(postings, domain.LedgerPosting{Account: s.account, OwnerId: id, Amount: s.amounts[id]})
+	}
+	return postings
+}
+
+// recordSale books a paid order. Every seller is credited with what the
+// buyer paid for their items and shipping, less the commission fixed on
+// the items when the order was placed.
+func recordSale(repos repository.Repositories, orderId uint) error {
+
+	fulfilments, err := repos.Transaction.FindOrderFulfilmentsForUpdate(orderId)
+	if err != nil {
+		return err
+	}
+
+	items, err := repos.Transaction.FindOrderItemsForUpdate(orderId)
+	if err != nil {
+		return err
+	}
+
+	var paid domain.Money
+	credits := sellerPostings{account: domain.ACCOUNT_SELLER}
+	commission := sellerPostings{account: domain.ACCOUNT_COMMISSION}
+	giftCards := sellerPostings{account: domain.ACCOUNT_GIFT_CARDS}
+	for _, item := range items {
+		if item.Status != domain.ITEM_ACTIVE {
+			continue
+		}
+
+		gross := refundableAmount(item, item.Qty)
+		paid = paid.Add(gross)
+		//no seller earns a gift card sale, the platform owes it until spent
+		if item.GiftCard {
+			giftCards.add(0, gross)
+			continue
+		}
+		credits.add(item.SellerId, gross.Sub(item.Commission))
+		commission.add(item.SellerId, item.Commission)
+	}
+
+	for _, f := range fulfilments {
+		if f.Status == domain.FULFILMENT_CANCELLED || f.ShippingCost.IsZero() {
+			continue
+		}
+		paid = paid.Add(f.ShippingCost)
+		credits.add(f.SellerId, f.ShippingCost)
+	}
+
+	if paid.IsZero() {
+		return nil
+	}
+
+	entry := &domain.LedgerEntry{
+		Kind:      domain.ENTRY_SALE,
+		Reference: saleReference(orderId),
+		OrderId:   orderId,
+		Postings:  []domain.LedgerPosting{{Account: domain.ACCOUNT_CLEARING, Amount: domain.Money{}.Sub(paid)}},
+	}
+	entry.Postings = append(entry.Postings, credits.postings()...)
+	entry.Postings = append(entry.Postings, commission.postings()...)
+	entry.Postings = append(entry.Postings, giftCards.postings()...)
+
+	_, err = repos.Ledger.CreateEntry(entry)
+	return err
+}
+
+// recordRefund debits the sellers for a refund. The platform gives back
+// its commission on the refunded units, the seller bears the rest. Orders
+// sold before the ledger existed have nothing to reverse.
+func recordRefund(repos repository.Repositories, refund *domain.Refund, lines []refundLine, shipping []*domain.Fulfilment) error {
+
+	sale, err := repos.Ledger.FindEntry(saleReference(refund.OrderId))
+	if err != nil || sale == nil {
+		return err
+	}
+
+	//the refund may have been capped to what was left on the payment, so
+	//it is spread over the lines and parcels it was for
+	var weights []int64
+	for _, line := range lines {
+		weights = append(weights, refundableAmount(line.item, line.qty).Amount)
+	}
+	for _, f := range shipping {
+		weights = append(weights, f.ShippingCost.Amount)
+	}
+	shares := refund.Amount.Allocate(weights)
+
+	debits := sellerPostings{account: domain.ACCOUNT_SELLER}
+	commission := sellerPostings{account: domain.ACCOUNT_COMMISSION}
+	giftCards := sellerPostings{account: domain.ACCOUNT_GIFT_CARDS}
+	for i, line := range lines {
+		if line.item.GiftCard {
+			giftCards.add(0, domain.Money{}.Sub(shares[i]))
+			continue
+		}
+		back := line.item.Commission.Allocate([]int64{int64(line.qty), int64(line.item.Qty - line.qty)})[0].Min(shares[i])
+		commission.add(line.item.SellerId, domain.Money{}.Sub(back))
+		debits.add(line.item.SellerId, back.Sub(shares[i]))
+	}
+	for i, f := range shipping {
+		debits.add(f.SellerId, domain.Money{}.Sub(shares[len(lines)+i]))
+	}
+
+	entry := &domain.LedgerEntry{
+		Kind:      domain.ENTRY_REFUND,
+		Reference: fmt.Sprintf("refund:%d", refund.ID),
+		OrderId:   refund.OrderId,
+		Memo:      refund.Reason,
+		Postings:  []domain.LedgerPosting{{Account: domain.ACCOUNT_CLEARING, Amount: refund.Amount}},
+	}
+	entry.Postings = append(entry.Postings, debits.postings()...)
+	entry.Postings = append(entry.Postings, commission.postings()...)
+	entry.Postings = append(entry.Postings, giftCards.postings()...)
+
+	_, err = repos.Ledger.CreateEntry(entry)
+	return err
+}
@@ -0,0 +1,535 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	payment "go-ecommerce-app/pkg/Payment"
+	"log"
+	"time"
+)
+
+// refundLine is a quantity of one order item to give money back for
+type refundLine struct {
+	item *domain.OrderItem
+	qty  int
+}
+
+// refundableAmount is what the buyer paid for qty units of item: the price
+// less their share of the discount, plus tax charged on top of the price
+func refundableAmount(item *domain.OrderItem, qty int) domain.Money {
+
+	amount := item.Price.Mul(int64(qty))
+	if !item.Discount.IsZero() && item.Qty > 0 {
+		amount = amount.Sub(item.Discount.Allocate([]int64{int64(qty), int64(item.Qty - qty)})[0])
+	}
+
+	return amount.Add(exclusiveTax(item, qty))
+}
+
+// issueRefund gives back what was paid for the lines, plus the shipping
+// charged for the given fulfilments, and records the refund against the
+// order payment and in the ledger. Money goes back the way it came: the
+// card part through the gateway, the gift card part onto the gift cards and
+// the wallet part to the wallet, in that order. Gift cards that can no
+// longer be spent are credited to the wallet instead. With toWallet the
+// card part is credited to the wallet as well, as is cash collected on
+// delivery or a bank transfer received, which have no gateway to go back
+// through. Cash still to be collected on delivery is not refunded, the
+// buyer owes that much less instead.
+// Orders that were never paid have nothing to refund and get a nil refund
+// back.
+func issueRefund(repos repository.Repositories, pc payment.PaymentClient, orderId uint, lines []refundLine, shipping []*domain.Fulfilment, toWallet bool, reason string, actor OrderActor) (*domain.Refund, bool, error) {
+
+	repo := repos.Transaction
+	order, err := repo.FindOrderForUpdate(orderId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p, err := repo.FindPaymentByOrderId(orderId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var cardLeft domain.Money
+	if p != nil && (p.Status == domain.PAYMENT_SUCCESS || p.Status == domain.PAYMENT_PARTIALLY_REFUNDED) {
+		cardLeft = p.Amount.Sub(p.Refunded)
+	}
+	if p != nil && p.Deferred() {
+		toWallet = true
+	}
+	var cashOwed domain.Money
+	if p != nil && p.CaptureMethod == domain.CAPTURE_COD && p.Status == domain.PAYMENT_INITIAL && order.Status != domain.ORDER_DELIVERED {
+		cashOwed = p.Amount
+	}
+	giftCardLeft := order.GiftCardAmount.Sub(order.GiftCardRefunded)
+	walletLeft := order.WalletAmount.Sub(order.WalletRefunded)
+
+	refund := &domain.Refund{
+		OrderId:   orderId,
+		Reason:    reason,
+		ActorId:   actor.Id,
+		ActorRole: actor.Role,
+		Status:    payment.StatusSucceeded,
+	}
+	if p != nil {
+		refund.PaymentId = p.PaymentId
+	}
+	for _, f := range shipping {
+		refund.Amount = refund.Amount.Add(f.ShippingCost)
+	}
+	for _, line := range lines {
+		amount := refundableAmount(line.item, line.qty)
+		refund.Amount = refund.Amount.Add(amount)
+		refund.Items = append(refund.Items, domain.RefundItem{
+			OrderItemId: line.item.ID,
+			Qty:         line.qty,
+			Amount:      amount,
+		})
+	}
+
+	//never more than what is left to give back
+	refund.Amount = refund.Amount.Min(cashOwed.Add(cardLeft).Add(giftCardLeft).Add(walletLeft))
+	if !refund.Amount.GreaterThan(domain.Money{}) {
+		return nil, false, nil
+	}
+
+	uncollected := refund.Amount.Min(cashOwed)
+	if uncollected.GreaterThan(domain.Money{}) {
+		p.Amount = p.Amount.Sub(uncollected)
+		if err := repo.UpdatePayment(p); err != nil {
+			return nil, false, err
+		}
+
+		refund.Amount = refund.Amount.Sub(uncollected)
+		if !refund.Amount.GreaterThan(domain.Money{}) {
+			return nil, false, nil
+		}
+	}
+
+	card := refund.Amount.Min(cardLeft)
+	fromGiftCards := refund.Amount.Sub(card).Min(giftCardLeft)
+	fromWallet := refund.Amount.Sub(card).Sub(fromGiftCards)
+
+	restores, unrestorable, err := planGiftCardRestores(repos, orderId, fromGiftCards)
+	if err != nil {
+		return nil, false, err
+	}
+
+	refund.GiftCardAmount = domain.Money{Currency: refund.Amount.Currency}.Add(fromGiftCards).Sub(unrestorable)
+	refund.WalletAmount = domain.Money{Currency: refund.Amount.Currency}.Add(fromWallet).Add(unrestorable)
+	if toWallet {
+		refund.WalletAmount = refund.WalletAmount.Add(card)
+	}
+
+	if card.GreaterThan(domain.Money{}) && !toWallet {
+		r, err := pc.RefundPayment(p.PaymentId, card.Amount)
+		if err != nil {
+			log.Printf("gateway refund for order %d failed %v", orderId, err)
+			return nil, false, errors.New("refund failed at the payment gateway")
+		}
+		refund.ProviderRefundId = r.ID
+		refund.Status = r.Status
+	}
+
+	if err := repo.CreateRefund(refund); err != nil {
+		return nil, false, err
+	}
+
+	if err := restoreGiftCards(repos, restores, refund, actor); err != nil {
+		return nil, false, err
+	}
+
+	if refund.WalletAmount.GreaterThan(domain.Money{}) {
+		err := postWallet(repos, int(order.UserId), &domain.WalletTransaction{
+			Kind:      domain.WALLET_REFUND,
+			Amount:    refund.WalletAmount,
+			OrderId:   orderId,
+			RefundId:  refund.ID,
+			Note:      reason,
+			ActorId:   actor.Id,
+			ActorRole: actor.Role,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := recordRefund(repos, refund, lines, shipping); err != nil {
+		return nil, false, err
+	}
+
+	//store credit for the card part counts as refunded on the payment
+	if card.GreaterThan(domain.Money{}) {
+		p.Refunded = p.Refunded.Add(card)
+		p.Status = domain.PAYMENT_PARTIALLY_REFUNDED
+		if p.Refunded.Equal(p.Amount) {
+			p.Status = domain.PAYMENT_REFUNDED
+		}
+		if err := repo.UpdatePayment(p); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if fromWallet.GreaterThan(domain.Money{}) {
+		if err := repo.UpdateOrderWalletRefunded(orderId, order.WalletRefunded.Add(fromWallet)); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if fromGiftCards.GreaterThan(domain.Money{}) {
+		if err := repo.UpdateOrderGiftCardRefunded(orderId, order.GiftCardRefunded.Add(fromGiftCards)); err != nil {
+			return nil, false, err
+		}
+	}
+
+	fullyRefunded := uncollected.Equal(cashOwed) && card.Equal(cardLeft) && fromGiftCards.Equal(giftCardLeft) && fromWallet.Equal(walletLeft)
+	return refund, fullyRefunded, nil
+}
+
+// bookGatewayRefund books money refunded straight on the gateway, outside
+// of issueRefund: a refund for what the gateway gave back beyond the refunds
+// the platform issued itself, debited from the sellers like any other. Once
+// the whole payment is back the items not shipped yet are cancelled and go
+// back into stock. Replayed events find nothing left to book.
+func bookGatewayRefund(repos repository.Repositories, orderId uint, gatewayRefunded domain.Money, fully bool, note string, reference string) error {
+
+	repo := repos.Transaction
+	order, err := repo.FindOrderForUpdate(orderId)
+	if err != nil {
+		return err
+	}
+
+	fulfilments, err := repo.FindOrderFulfilmentsForUpdate(orderId)
+	if err != nil {
+		return err
+	}
+
+	items, err := repo.FindOrderItemsForUpdate(orderId)
+	if err != nil {
+		return err
+	}
+
+	p, err := repo.FindPaymentByOrderId(orderId)
+	if err != nil || p == nil {
+		return err
+	}
+
+	issued, err := repo.GatewayRefunded(orderId)
+	if err != nil {
+		return err
+	}
+
+	extra := gatewayRefunded.Sub(domain.NewMoney(issued, gatewayRefunded.Currency))
+	if !extra.GreaterThan(domain.Money{}) {
+		return nil
+	}
+
+	//the gateway doesn't say what the money was for, so it is spread over
+	//everything still on the order
+	var lines []refundLine
+	for _, item := range items {
+		if item.Status == domain.ITEM_ACTIVE {
+			lines = append(lines, refundLine{item: item, qty: item.Qty})
+		}
+	}
+	var shipping []*domain.Fulfilment
+	for _, f := range fulfilments {
+		if f.Status != domain.FULFILMENT_CANCELLED && !f.ShippingCost.IsZero() {
+			shipping = append(shipping, f)
+		}
+	}
+
+	refund := &domain.Refund{
+		OrderId:          orderId,
+		PaymentId:        p.PaymentId,
+		ProviderRefundId: reference,
+		Amount:           extra,
+		Reason:           note,
+		ActorRole:        domain.SYSTEM,
+		Status:           payment.StatusSucceeded,
+	}
+	if err := repo.CreateRefund(refund); err != nil {
+		return err
+	}
+
+	if err := recordRefund(repos, refund, lines, shipping); err != nil {
+		return err
+	}
+
+	p.Refunded = p.Refunded.Add(extra).Min(p.Amount)
+	p.Status = domain.PAYMENT_PARTIALLY_REFUNDED
+	if fully || p.Refunded.Equal(p.Amount) {
+		p.Refunded = p.Amount
+		p.Status = domain.PAYMENT_REFUNDED
+	}
+	p.Response = note
+	if err := repo.UpdatePayment(p); err != nil {
+		return err
+	}
+
+	if p.Status != domain.PAYMENT_REFUNDED {
+		return nil
+	}
+
+	//nothing is left to pay for what hasn't shipped yet
+	cancellable := map[uint]bool{}
+	for _, f := range fulfilments {
+		cancellable[f.ID] = domain.FulfilmentCancellable(f.Status)
+	}
+
+	remaining := 0
+	for _, item := range items {
+		if item.Status != domain.ITEM_ACTIVE {
+			continue
+		}
+		if !cancellable[item.FulfilmentId] {
+			remaining++
+			continue
+		}
+
+		item.Status = domain.ITEM_CANCELLED
+		item.CancelReason = note
+		if err := repo.UpdateOrderItem(item); err != nil {
+			return err
+		}
+
+		if err := restock(repos.Catalog, item, item.Qty); err != nil {
+			return err
+		}
+	}
+
+	if _, err := cancelEmptyFulfilments(repo, fulfilments, items); err != nil {
+		return err
+	}
+
+	if remaining == 0 && domain.CanCancelOrder(order.Status, domain.SYSTEM) {
+		if err := recordTransition(repo, order, domain.ORDER_CANCELLED, systemActor, note); err != nil {
+			return err
+		}
+		if err := releaseCoupon(repos, orderId); err != nil {
+			return err
+		}
+	} else if remaining > 0 {
+		if err := syncOrderStatus(repo, order, fulfilments, note); err != nil {
+			return err
+		}
+	}
+
+	_, err = transitionOrder(repo, orderId, domain.ORDER_REFUNDED, systemActor, note)
+	if errors.Is(err, ErrInvalidTransition) {
+		log.Printf("order %d refunded on the gateway but stays %s", orderId, order.Status)
+		return nil
+	}
+	return err
+}
+
+// cancelOrderItems cancels items of an order, every active item the actor
+// may cancel when itemIds is empty. Cancelled items go back into stock and
+// what was paid for them is refunded. Once no active item is left the order
+// itself moves to cancelled, and to refunded when the payment is fully
+// given back.
+func cancelOrderItems(repos repository.Repositories, pc payment.PaymentClient, orderId uint, itemIds []int, toWallet bool, reason string, actor OrderActor) (*dto.CancelOrderResponse, error) {
+
+	order, err := repos.Transaction.FindOrderForUpdate(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if actor.Role == domain.BUYER && order.UserId != uint(actor.Id) {
+		return nil, errors.New("order not found")
+	}
+
+	//partial cancellation is allowed wherever cancelling the whole order is
+	if !domain.CanCancelOrder(order.Status, actor.Role) {
+		return nil, fmt.Errorf("%w: order is %s", ErrInvalidTransition, order.Status)
+	}
+
+	fulfilments, err := repos.Transaction.FindOrderFulfilmentsForUpdate(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	cancellable := map[uint]bool{}
+	for _, f := range fulfilments {
+		cancellable[f.ID] = domain.FulfilmentCancellable(f.Status)
+	}
+
+	items, err := repos.Transaction.FindOrderItemsForUpdate(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := map[int]bool{}
+	for _, id := range itemIds {
+		requested[id] = true
+	}
+
+	var selected []*domain.OrderItem
+	active := 0
+	for _, item := range items {
+		if item.Status != domain.ITEM_ACTIVE {
+			continue
+		}
+		active++
+
+		if actor.Role == domain.SELLER && item.SellerId != actor.Id {
+			continue
+		}
+		if len(requested) > 0 && !requested[item.ID] {
+			continue
+		}
+		//items already handed to the carrier have to be returned instead
+		if !cancellable[item.FulfilmentId] {
+			continue
+		}
+		selected = append(selected, item)
+	}
+
+	if len(selected) == 0 || (len(requested) > 0 && len(selected) != len(requested)) {
+		return nil, errors.New("order items not found, already cancelled or shipped")
+	}
+
+	unpaid := order.Status == domain.ORDER_PENDING_PAYMENT
+
+	remaining := active - len(selected)
+	if unpaid && remaining > 0 {
+		return nil, errors.New("an unpaid order can only be cancelled as a whole")
+	}
+
+	//cash on delivery stays owed while a confirmed order is processed, it
+	//is reduced by the items cancelled and dropped with the last of them
+	p, err := repos.Transaction.FindPaymentByOrderId(orderId)
+	if err != nil {
+		return nil, err
+	}
+	owed := p != nil && p.CaptureMethod == domain.CAPTURE_COD && p.Status == domain.PAYMENT_INITIAL
+
+	var lines []refundLine
+	for _, item := range selected {
+		item.Status = domain.ITEM_CANCELLED
+		item.CancelReason = reason
+		if err := repos.Transaction.UpdateOrderItem(item); err != nil {
+			return nil, err
+		}
+
+		if err := restock(repos.Catalog, item, item.Qty); err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, refundLine{item: item, qty: item.Qty})
+	}
+
+	//shipping is refunded with the last items of a parcel that never left
+	shipping, err := cancelEmptyFulfilments(repos.Transaction, fulfilments, items)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.CancelOrderResponse{OrderId: orderId, Status: order.Status}
+
+	if unpaid || (owed && remaining == 0) {
+		if err := dropOrderPayment(repos.Transaction, pc, orderId); err != nil {
+			return nil, err
+		}
+	}
+
+	//an unpaid order only has what was taken from gift cards and the
+	//wallet to give back
+	var fullyRefunded bool
+	response.Refund, fullyRefunded, err = issueRefund(repos, pc, orderId, lines, shipping, toWallet, reason, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if remaining > 0 {
+		//the sellers left may all have shipped already
+		if err := syncOrderStatus(repos.Transaction, order, fulfilments, reason); err != nil {
+			return nil, err
+		}
+		response.Status = order.Status
+		return response, nil
+	}
+
+	if err := recordTransition(repos.Transaction, order, domain.ORDER_CANCELLED, actor, reason); err != nil {
+		return nil, err
+	}
+	response.Status = domain.ORDER_CANCELLED
+
+	if err := releaseCoupon(repos, orderId); err != nil {
+		return nil, err
+	}
+
+	if fullyRefunded {
+		if _, err := transitionOrder(repos.Transaction, orderId, domain.ORDER_REFUNDED, systemActor, "refund issued"); err != nil {
+			return nil, err
+		}
+		response.Status = domain.ORDER_REFUNDED
+	}
+
+	return response, nil
+}
+
+// dropOrderPayment cancels the still unpaid payment of an order, at the
+// gateway for card payments
+func dropOrderPayment(repo repository.TransactionRepo, pc payment.PaymentClient, orderId uint) error {
+
+	p, err := repo.FindPaymentByOrderId(orderId)
+	if err != nil {
+		return err
+	}
+
+	if p == nil || p.Status != domain.PAYMENT_INITIAL {
+		return nil
+	}
+
+	if !p.Deferred() {
+		if _, err := pc.CancelPayment(p.PaymentId); err != nil {
+			log.Printf("cancelling payment %s failed %v", p.PaymentId, err)
+		}
+	}
+
+	p.Status = domain.PAYMENT_CANCELLED
+	return repo.UpdatePayment(p)
+}
+
+// cancelEmptyFulfilments cancels the fulfilments left without active items
+// and returns them, their shipping is refunded.
+func cancelEmptyFulfilments(repo repository.TransactionRepo, fulfilments []*domain.Fulfilment, items []*domain.OrderItem) ([]*domain.Fulfilment, error) {
+
+	active := map[uint]bool{}
+	for _, item := range items {
+		if item.Status == domain.ITEM_ACTIVE {
+			active[item.FulfilmentId] = true
+		}
+	}
+
+	var cancelled []*domain.Fulfilment
+	now := time.Now()
+	for _, f := range fulfilments {
+		if active[f.ID] || !domain.FulfilmentCancellable(f.Status) {
+			continue
+		}
+
+		f.Status = domain.FULFILMENT_CANCELLED
+		f.CancelledAt = &now
+		if err := repo.UpdateFulfilment(f); err != nil {
+			return nil, err
+		}
+		cancelled = append(cancelled, f)
+	}
+
+	return cancelled, nil
+}
+
+// restock puts units of an order item back on sale, on its variant when it
+// was bought as one.
+func restock(repo repository.CatalogRepository, item *domain.OrderItem, qty int) error {
+	if item.VariantId > 0 {
+		return repo.IncrementVariantStock(item.VariantId, uint(qty))
+	}
+	return repo.IncrementStock(item.ProductId, uint(qty))
+}
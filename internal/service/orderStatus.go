@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/repository"
+)
+
+var ErrInvalidTransition = errors.New("order status change not allowed")
+
+// OrderActor is whoever asks for an order status change. Role is the side
+// the request comes from (buyer, seller or system), not the user type, since
+// sellers can buy too.
+type OrderActor struct {
+	Id   int
+	Role string
+}
+
+var systemActor = OrderActor{Role: domain.SYSTEM}
+
+// transitionOrder moves an order along an allowed edge of the lifecycle and
+// records the change in the order history. Must run inside a transaction,
+// the order row stays locked until it commits.
+func transitionOrder(repo repository.TransactionRepo, orderId uint, to string, actor OrderActor, note string) (*domain.Order, error) {
+
+	order, err := repo.FindOrderForUpdate(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch actor.Role {
+	case domain.BUYER:
+		if order.UserId != uint(actor.Id) {
+			return nil, errors.New("order not found")
+		}
+	case domain.SELLER:
+		owns, err := repo.SellerHasOrderItems(orderId, actor.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !owns {
+			return nil, errors.New("order not found")
+		}
+	}
+
+	if !domain.CanTransitionOrder(order.Status, to, actor.Role) {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, order.Status, to)
+	}
+
+	if err := recordTransition(repo, order, to, actor, note); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// recordTransition moves a locked order to the state and records the
+// change in the order history, callers check the move is allowed
+func recordTransition(repo repository.TransactionRepo, order *domain.Order, to string, actor OrderActor, note string) error {
+
+	if err := repo.UpdateOrderStatus(order.ID, to); err != nil {
+		return err
+	}
+
+	if err := repo.CreateOrderHistory(&domain.OrderHistory{
+		OrderId:    order.ID,
+		FromStatus: order.Status,
+		ToStatus:   to,
+		ActorId:    actor.Id,
+		ActorRole:  actor.Role,
+		Note:       note,
+	}); err != nil {
+		return err
+	}
+
+	order.Status = to
+	return nil
+}
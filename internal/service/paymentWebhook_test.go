@@ -0,0 +1,277 @@
+package service
+
+import (
+	"fmt"
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/repository"
+	payment "go-ecommerce-app/pkg/Payment"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// webhookFixture is an order of two items from two sellers, 1999 USD in
+// all, paid by card with payment pi_test_1
+type webhookFixture struct {
+	svc     *TransactionService
+	repo    *fakeTransactionRepo
+	ledger  *fakeLedgerRepo
+	catalog *fakeCatalogRepo
+}
+
+func newWebhookFixture(t *testing.T) *webhookFixture {
+	t.Helper()
+
+	repo := newFakeTransactionRepo()
+	repo.orders[1] = domain.Order{ID: 1, UserId: 7, Status: domain.ORDER_PENDING_PAYMENT, Amount: domain.NewMoney(1999, "USD")}
+	repo.fulfilments = []domain.Fulfilment{
+		{ID: 1, OrderId: 1, SellerId: 5, Status: domain.FULFILMENT_PENDING},
+		{ID: 2, OrderId: 1, SellerId: 6, Status: domain.FULFILMENT_PENDING},
+	}
+	repo.items = []domain.OrderItem{
+		{ID: 1, OrderId: 1, ProductId: 11, SellerId: 5, FulfilmentId: 1, Price: domain.NewMoney(1499, "USD"), Qty: 1, Status: domain.ITEM_ACTIVE},
+		{ID: 2, OrderId: 1, ProductId: 12, SellerId: 6, FulfilmentId: 2, Price: domain.NewMoney(500, "USD"), Qty: 1, Status: domain.ITEM_ACTIVE},
+	}
+	repo.payments = []domain.Payment{
+		{ID: 1, OrderId: 1, UserId: 7, CaptureMethod: domain.CAPTURE_CARD, Amount: domain.NewMoney(1999, "USD"), PaymentId: "pi_test_1", Status: domain.PAYMENT_INITIAL},
+	}
+
+	f := &webhookFixture{
+		repo:    repo,
+		ledger:  &fakeLedgerRepo{},
+		catalog: &fakeCatalogRepo{restocked: map[int]uint{}},
+	}
+
+	config := configs.AppConfig{WebhookSecret: testWebhookSecret}
+	f.svc = &TransactionService{
+		Repo:    repo,
+		Config:  config,
+		Payment: payment.NewFakePaymentClient(config),
+		Tx: &fakeTx{repos: repository.Repositories{
+			Transaction: repo,
+			Ledger:      f.ledger,
+			Catalog:     f.catalog,
+			Coupon:      &fakeCouponRepo{},
+		}},
+	}
+
+	return f
+}
+
+// paid puts the order in the state the payment succeeded event leaves it
+func (f *webhookFixture) paid(t *testing.T) {
+	t.Helper()
+
+	f.repo.payments[0].Status = domain.PAYMENT_SUCCESS
+	f.repo.orders[1] = domain.Order{ID: 1, UserId: 7, Status: domain.ORDER_PAID, Amount: domain.NewMoney(1999, "USD")}
+	if err := recordSale(f.svc.Tx.Repositories(), 1); err != nil {
+		t.Fatalf("recording sale: %v", err)
+	}
+}
+
+func (f *webhookFixture) send(t *testing.T, payload []byte) {
+	t.Helper()
+
+	signature := payment.SignWebhookPayload(payload, testWebhookSecret, time.Now())
+	if err := f.svc.HandlePaymentWebhook(payload, signature); err != nil {
+		t.Fatalf("handling webhook: %v", err)
+	}
+}
+
+func (f *webhookFixture) payment() domain.Payment {
+	return f.repo.payments[0]
+}
+
+// refundEntries is the clearing amount of every refund booked in the ledger
+func (f *webhookFixture) refundEntries() []int64 {
+	var amounts []int64
+	for _, e := range f.ledger.entries {
+		if e.Kind != domain.ENTRY_REFUND {
+			continue
+		}
+		for _, p := range e.Postings {
+			if p.Account == domain.ACCOUNT_CLEARING {
+				amounts = append(amounts, p.Amount.Amount)
+			}
+		}
+	}
+	return amounts
+}
+
+// webhookState is everything a payment event can change
+type webhookState struct {
+	Payments []domain.Payment
+	Orders   map[uint]domain.Order
+	History  int
+	Ledger   int
+}
+
+func (f *webhookFixture) state() webhookState {
+	orders := map[uint]domain.Order{}
+	for id, order := range f.repo.orders {
+		orders[id] = order
+	}
+	return webhookState{
+		Payments: append([]domain.Payment{}, f.repo.payments...),
+		Orders:   orders,
+		History:  len(f.repo.history),
+		Ledger:   len(f.ledger.entries),
+	}
+}
+
+// gatewayFixture reads a signed event fixture of the payment package
+func gatewayFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("..", "..", "pkg", "Payment", "testdata", name))
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", name, err)
+	}
+	return payload
+}
+
+func TestWebhookAppliesPaymentEventsOnce(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		paid        bool
+		fixture     string
+		payment     string
+		transaction string
+		order       string
+		sales       int
+	}{
+		{
+			name:        "succeeded",
+			fixture:     "payment_intent_succeeded.json",
+			payment:     domain.PAYMENT_SUCCESS,
+			transaction: "ch_test_1",
+			order:       domain.ORDER_PAID,
+			sales:       1,
+		},
+		{
+			name:    "failed",
+			fixture: "payment_intent_payment_failed.json",
+			payment: domain.PAYMENT_FAILED,
+			order:   domain.ORDER_PENDING_PAYMENT,
+		},
+		{
+			name:        "failed after succeeding",
+			paid:        true,
+			fixture:     "payment_intent_payment_failed.json",
+			payment:     domain.PAYMENT_SUCCESS,
+			transaction: "ch_test_1",
+			order:       domain.ORDER_PAID,
+			sales:       1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newWebhookFixture(t)
+			if tt.paid {
+				f.paid(t)
+				f.repo.payments[0].TransactionId = "ch_test_1"
+			}
+
+			payload := gatewayFixture(t, tt.fixture)
+			f.send(t, payload)
+
+			p := f.payment()
+			if p.Status != tt.payment || p.TransactionId != tt.transaction {
+				t.Fatalf("payment is %s with transaction %q, want %s with %q", p.Status, p.TransactionId, tt.payment, tt.transaction)
+			}
+			if status := f.repo.orders[1].Status; status != tt.order {
+				t.Fatalf("order is %s, want %s", status, tt.order)
+			}
+			if len(f.ledger.entries) != tt.sales {
+				t.Fatalf("got %d ledger entries, want %d", len(f.ledger.entries), tt.sales)
+			}
+
+			//the gateway delivers events at least once
+			before := f.state()
+			f.send(t, payload)
+
+			if after := f.state(); !reflect.DeepEqual(after, before) {
+				t.Fatalf("redelivered event changed %+v to %+v", before, after)
+			}
+			if len(f.repo.events) != 1 {
+				t.Fatalf("stored %d events, want the event once", len(f.repo.events))
+			}
+		})
+	}
+}
+
+func chargeRefunded(eventId string, refunded int64, full bool) []byte {
+	return []byte(fmt.Sprintf(`{"id": %q, "object": "event", "type": "charge.refunded", "data": {"object": {"id": "ch_test_1", "object": "charge", "amount": 1999, "amount_refunded": %d, "currency": "usd", "refunded": %t, "payment_intent": "pi_test_1"}}}`, eventId, refunded, full))
+}
+
+func TestWebhookBooksGatewayRefunds(t *testing.T) {
+
+	f := newWebhookFixture(t)
+	f.paid(t)
+
+	//the platform refunded 4.99 itself, the gateway reports it back
+	f.repo.refunds = append(f.repo.refunds, domain.Refund{ID: 1, OrderId: 1, PaymentId: "pi_test_1", ProviderRefundId: "re_test_1", Amount: domain.NewMoney(499, "USD")})
+	f.repo.payments[0].Refunded = domain.NewMoney(499, "USD")
+	f.repo.payments[0].Status = domain.PAYMENT_PARTIALLY_REFUNDED
+
+	f.send(t, chargeRefunded("evt_refund_1", 499, false))
+
+	if len(f.repo.refunds) != 1 || len(f.refundEntries()) != 0 {
+		t.Fatalf("refund the platform issued booked again: refunds %+v, ledger %v", f.repo.refunds, f.refundEntries())
+	}
+
+	//5.00 more refunded on the gateway dashboard
+	f.send(t, chargeRefunded("evt_refund_2", 999, false))
+
+	if len(f.repo.refunds) != 2 {
+		t.Fatalf("got %d refunds, want the gateway refund booked", len(f.repo.refunds))
+	}
+	refund := f.repo.refunds[1]
+	if refund.Amount != domain.NewMoney(500, "USD") || refund.ProviderRefundId != "evt_refund_2" {
+		t.Fatalf("unexpected gateway refund %+v", refund)
+	}
+	if got := f.refundEntries(); len(got) != 1 || got[0] != 500 {
+		t.Fatalf("ledger refunds %v, want one of 500", got)
+	}
+	if p := f.payment(); p.Refunded != domain.NewMoney(999, "USD") || p.Status != domain.PAYMENT_PARTIALLY_REFUNDED {
+		t.Fatalf("payment after partial refund %+v", p)
+	}
+	if status := f.repo.orders[1].Status; status != domain.ORDER_PAID {
+		t.Fatalf("order is %s after a partial refund, want %s", status, domain.ORDER_PAID)
+	}
+
+	//a redelivery changes nothing
+	f.send(t, chargeRefunded("evt_refund_2", 999, false))
+
+	if len(f.repo.refunds) != 2 || len(f.refundEntries()) != 1 {
+		t.Fatalf("redelivered refund booked again: refunds %+v, ledger %v", f.repo.refunds, f.refundEntries())
+	}
+
+	//the rest is refunded, nothing is left to ship
+	f.send(t, chargeRefunded("evt_refund_3", 1999, true))
+
+	if got := f.refundEntries(); len(got) != 2 || got[1] != 1000 {
+		t.Fatalf("ledger refunds %v, want 500 and 1000", got)
+	}
+	if p := f.payment(); p.Refunded != domain.NewMoney(1999, "USD") || p.Status != domain.PAYMENT_REFUNDED {
+		t.Fatalf("payment after full refund %+v", p)
+	}
+	for _, item := range f.repo.items {
+		if item.Status != domain.ITEM_CANCELLED {
+			t.Fatalf("item %d is %s after a full refund", item.ID, item.Status)
+		}
+	}
+	if f.catalog.restocked[11] != 1 || f.catalog.restocked[12] != 1 {
+		t.Fatalf("restocked %v, want one of each product", f.catalog.restocked)
+	}
+	if status := f.repo.orders[1].Status; status != domain.ORDER_REFUNDED {
+		t.Fatalf("order is %s after a full refund, want %s", status, domain.ORDER_REFUNDED)
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"fmt"
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/payout"
+	"log"
+	"strconv"
+	"time"
+)
+
+type PayoutService struct {
+	Repo   repository.LedgerRepository
+	Tx     repository.TxManager
+	Auth   helper.Auth
+	Config configs.AppConfig
+	Payout payout.PayoutClient
+}
+
+// GetBalance returns what the platform owes the seller, per currency
+func (s *PayoutService) GetBalance(u domain.User) ([]domain.SellerBalance, error) {
+	return s.Repo.FindSellerBalances(u.ID, time.Now().Add(-s.Config.PayoutHold))
+}
+
+func (s *PayoutService) GetPayouts(u domain.User) ([]*domain.Payout, error) {
+	return s.Repo.FindPayouts(u.ID)
+}
+
+// RunSchedule runs payouts every interval until the process exits, a zero
+// interval leaves payouts to be run by hand
+func (s *PayoutService) RunSchedule(interval time.Duration) {
+
+	if interval <= 0 {
+		log.Println("scheduled payouts are off")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		run, err := s.RunPayouts()
+		if err != nil {
+			log.Printf("payout run failed %v", err)
+			continue
+		}
+		log.Printf("payout run created %d, paid %d, failed %d, pending %d", run.Created, run.Paid, run.Failed, run.Pending)
+	}
+}
+
+// RunPayouts moves the available balance of every seller into a payout to
+// their bank account, then sends every payout still pending. Sending is
+// safe to repeat, the provider knows each payout by its reference.
+func (s *PayoutService) RunPayouts() (*dto.PayoutRunResponse, error) {
+
+	cutoff := time.Now().Add(-s.Config.PayoutHold)
+	balances, err := s.Repo.FindPayableBalances(cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &dto.PayoutRunResponse{}
+	for _, b := range balances {
+		created, err := createPayout(s.Tx, b.SellerId, b.Available.Currency, cutoff)
+		if err != nil {
+			log.Printf("payout for seller %d failed %v", b.SellerId, err)
+			continue
+		}
+		if created {
+			run.Created++
+		}
+	}
+
+	//new payouts and the ones an earlier run didn't get an answer for
+	pending, err := s.Repo.FindPendingPayouts()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range pending {
+		status, err := sendPayout(s.Tx, s.Payout, p)
+		if err != nil {
+			log.Printf("sending payout %d failed %v", p.ID, err)
+			run.Pending++
+			continue
+		}
+
+		switch status {
+		case domain.PAYOUT_PAID:
+			run.Paid++
+		case domain.PAYOUT_FAILED:
+			run.Failed++
+		}
+	}
+
+	return run, nil
+}
+
+// createPayout takes the seller's available balance in currency into a new
+// pending payout. Sellers without a bank account are skipped.
+func createPayout(tx repository.TxManager, sellerId int, currency string, cutoff time.Time) (bool, error) {
+
+	created := false
+	err := tx.WithTx(func(repos repository.Repositories) error {
+
+		//the bank account lock keeps concurrent runs from paying a seller twice
+		account, err := repos.Ledger.FindBankAccountForUpdate(sellerId)
+		if err != nil {
+			return err
+		}
+
+		if account == nil {
+			log.Printf("seller %d has no bank account to pay out to", sellerId)
+			return nil
+		}
+
+		balances, err := repos.Ledger.FindSellerBalances(sellerId, cutoff)
+		if err != nil {
+			return err
+		}
+
+		var available domain.Money
+		for _, b := range balances {
+			if b.Available.Currency == currency {
+				available = b.Available
+			}
+		}
+
+		if !available.GreaterThan(domain.Money{}) {
+			return nil
+		}
+
+		p := &domain.Payout{
+			SellerId:      sellerId,
+			BankAccountId: account.ID,
+			Amount:        available,
+			Status:        domain.PAYOUT_PENDING,
+		}
+		if err := repos.Ledger.CreatePayout(p); err != nil {
+			return err
+		}
+
+		_, err = repos.Ledger.CreateEntry(&domain.LedgerEntry{
+			Kind:      domain.ENTRY_PAYOUT,
+			Reference: fmt.Sprintf("payout:%d", p.ID),
+			PayoutId:  p.ID,
+			Postings: []domain.LedgerPosting{
+				{Account: domain.ACCOUNT_SELLER, OwnerId: sellerId, Amount: domain.Money{}.Sub(available)},
+				{Account: domain.ACCOUNT_PAYOUTS, Amount: available},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		created = true
+		return nil
+	})
+
+	return created, err
+}
+
+// sendPayout makes the transfer of a pending payout and records the result.
+// A failed transfer gives the amount back to the seller's balance.
+func sendPayout(tx repository.TxManager, pc payout.PayoutClient, p *domain.Payout) (string, error) {
+
+	account, err := tx.Repositories().Ledger.FindBankAccount(p.BankAccountId)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := pc.Transfer(payout.TransferRequest{
+		Reference:     fmt.Sprintf("payout-%d", p.ID),
+		AccountNumber: strconv.FormatUint(uint64(account.BankAccount), 10),
+		SwiftCode:     account.SwiftCode,
+		Amount:        p.Amount.Amount,
+		Currency:      p.Amount.Currency,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var status string
+	err = tx.WithTx(func(repos repository.Repositories) error {
+
+		settled, err := repos.Ledger.FindPayoutForUpdate(p.ID)
+		if err != nil {
+			return err
+		}
+
+		//settled by a concurrent run
+		if settled.Status != domain.PAYOUT_PENDING {
+			status = settled.Status
+			return nil
+		}
+
+		settled.ProviderRef = t.ID
+		if t.Status == payout.StatusPaid {
+			now := time.Now()
+			settled.Status = domain.PAYOUT_PAID
+			settled.PaidAt = &now
+		} else {
+			settled.Status = domain.PAYOUT_FAILED
+			settled.FailureReason = t.FailureReason
+
+			_, err = repos.Ledger.CreateEntry(&domain.LedgerEntry{
+				Kind:      domain.ENTRY_PAYOUT_FAILED,
+				Reference: fmt.Sprintf("payout:%d:failed", settled.ID),
+				PayoutId:  settled.ID,
+				Memo:      t.FailureReason,
+				Postings: []domain.LedgerPosting{
+					{Account: domain.ACCOUNT_SELLER, OwnerId: settled.SellerId, Amount: settled.Amount},
+					{Account: domain.ACCOUNT_PAYOUTS, Amount: domain.Money{}.Sub(settled.Amount)},
+				},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		status = settled.Status
+		return repos.Ledger.UpdatePayout(settled)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return status, nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+)
+
+// search results per page, by default and at most
+const (
+	SEARCH_PAGE_SIZE     = 20
+	SEARCH_MAX_PAGE_SIZE = 50
+)
+
+var ErrSearchQuery = errors.New("search needs a query of at most 200 characters")
+
+// SearchProducts finds products by name, description and category name,
+// best matches first, priced the way the catalog shows them.
+func (s *CatalogService) SearchProducts(input dto.ProductSearchRequest) (*dto.ProductSearchResponse, error) {
+
+	text := strings.TrimSpace(input.Query)
+	if text == "" || len(text) > 200 {
+		return nil, ErrSearchQuery
+	}
+
+	limit := input.Limit
+	if limit < 1 {
+		limit = SEARCH_PAGE_SIZE
+	}
+	if limit > SEARCH_MAX_PAGE_SIZE {
+		limit = SEARCH_MAX_PAGE_SIZE
+	}
+
+	page := input.Page
+	if page < 1 {
+		page = 1
+	}
+
+	found, err := s.Search.Search(repository.SearchQuery{
+		Text:       text,
+		CategoryId: input.CategoryId,
+		Limit:      limit,
+		Offset:     (page - 1) * limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.ProductSearchResponse{
+		Query:   text,
+		Total:   found.Total,
+		Page:    page,
+		Limit:   limit,
+		Results: []dto.ProductSearchHit{},
+	}
+	if len(found.Hits) == 0 {
+		return response, nil
+	}
+
+	var ids []int
+	for _, hit := range found.Hits {
+		ids = append(ids, int(hit.ProductId))
+	}
+
+	products, err := s.Repo.FindProductsByIds(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.priceProducts(products, input.Currency); err != nil {
+		return nil, err
+	}
+
+	byId := map[uint]*domain.Product{}
+	for _, product := range products {
+		byId[product.ID] = product
+	}
+
+	//in rank order, skipping products deleted since the search
+	for _, hit := range found.Hits {
+		product, ok := byId[hit.ProductId]
+		if !ok {
+			continue
+		}
+
+		response.Results = append(response.Results, dto.ProductSearchHit{
+			Product: product,
+			Rank:    hit.Rank,
+			Name:    hit.Name,
+			Snippet: hit.Snippet,
+		})
+	}
+
+	return response, nil
+}
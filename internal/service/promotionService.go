@@ -0,0 +1,167 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"time"
+)
+
+var ErrPromotionCurrency = errors.New("products of a promotion should be listed in one currency")
+
+type PromotionService struct {
+	Repo   repository.PromotionRepository
+	CRepo  repository.CatalogRepository
+	Auth   helper.Auth
+	Config configs.AppConfig
+}
+
+func (s *PromotionService) GetPromotions(u domain.User) ([]*domain.Promotion, error) {
+	return s.Repo.FindPromotions(u.ID)
+}
+
+func (s *PromotionService) CreatePromotion(u domain.User, input dto.PromotionRequest) (*domain.Promotion, error) {
+
+	promotion := &domain.Promotion{SellerId: u.ID, Active: true}
+	if err := s.applyPromotionInput(u, promotion, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.CreatePromotion(promotion); err != nil {
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+func (s *PromotionService) UpdatePromotion(u domain.User, promotionId uint, input dto.PromotionRequest) (*domain.Promotion, error) {
+
+	promotion, err := s.Repo.FindPromotion(promotionId, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyPromotionInput(u, promotion, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.UpdatePromotion(promotion); err != nil {
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+func (s *PromotionService) DeletePromotion(u domain.User, promotionId uint) error {
+	return s.Repo.DeletePromotion(promotionId, u.ID)
+}
+
+func (s *PromotionService) applyPromotionInput(u domain.User, promotion *domain.Promotion, input dto.PromotionRequest) error {
+
+	if len(input.Name) < 1 {
+		return errors.New("promotion name is required")
+	}
+
+	if len(input.Products) < 1 {
+		return errors.New("promotion needs at least one product")
+	}
+
+	if input.Price < 0 || input.Percent < 0 || input.Percent > 10000 {
+		return errors.New("price cannot be negative and percent should be at most 10000 basis points")
+	}
+
+	switch input.Type {
+	case domain.PROMO_SALE:
+		if input.Price == 0 && input.Percent == 0 {
+			return errors.New("sales need a price or a percent off")
+		}
+	case domain.PROMO_BUY_GET:
+		if input.BuyQty < 1 || input.GetQty < 1 || input.Percent == 0 {
+			return errors.New("buy-get offers need buy and get quantities and a percent off")
+		}
+	case domain.PROMO_BUNDLE:
+		if len(input.Products) < 2 || input.Price == 0 {
+			return errors.New("bundles need at least two products and a price")
+		}
+	default:
+		return errors.New("promotion type should be sale, buyget or bundle")
+	}
+
+	startsAt := time.Now()
+	if input.StartsAt != nil {
+		startsAt = *input.StartsAt
+	}
+
+	if input.EndsAt != nil && !input.EndsAt.After(startsAt) {
+		return errors.New("promotion ends before it starts")
+	}
+
+	//sellers promote their own products only
+	var ids []int
+	for _, p := range input.Products {
+		ids = append(ids, int(p.ProductId))
+	}
+
+	products, err := s.CRepo.FindProductsByIds(ids)
+	if err != nil {
+		return err
+	}
+
+	owned := map[uint]bool{}
+	listed := map[uint]string{}
+	for _, product := range products {
+		owned[product.ID] = product.UserId == u.ID
+		listed[product.ID] = product.Price.Currency
+	}
+
+	//the price is in the products' listing currency
+	currency := ""
+
+	promotion.Products = []domain.PromotionProduct{}
+	seen := map[uint]bool{}
+	for _, p := range input.Products {
+		if !owned[p.ProductId] {
+			return errors.New("promotions can only include your own products")
+		}
+		if seen[p.ProductId] {
+			return errors.New("a product can only be listed once")
+		}
+		seen[p.ProductId] = true
+
+		if currency != "" && listed[p.ProductId] != currency {
+			return ErrPromotionCurrency
+		}
+		currency = listed[p.ProductId]
+
+		qty := p.Qty
+		if qty < 1 || input.Type != domain.PROMO_BUNDLE {
+			qty = 1
+		}
+		promotion.Products = append(promotion.Products, domain.PromotionProduct{ProductId: p.ProductId, Qty: qty})
+	}
+
+	if currency == "" {
+		currency = s.Config.Currency
+	}
+
+	promotion.Name = input.Name
+	promotion.Type = input.Type
+	promotion.Price = domain.NewMoney(input.Price, currency)
+	promotion.Percent = input.Percent
+	promotion.BuyQty = 0
+	promotion.GetQty = 0
+	if input.Type == domain.PROMO_BUY_GET {
+		promotion.BuyQty = input.BuyQty
+		promotion.GetQty = input.GetQty
+	}
+	promotion.StartsAt = startsAt
+	promotion.EndsAt = input.EndsAt
+	if input.Active != nil {
+		promotion.Active = *input.Active
+	}
+
+	return nil
+}
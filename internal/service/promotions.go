@@ -0,0 +1,234 @@
+package service
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/fx"
+	"sort"
+	"time"
+)
+
+// attachPromotions adds the promotions running at now to the products and
+// sets the sale price of products on sale. The lowest sale price wins when
+// sales overlap. Promotion prices are set in the seller's listing currency
+// and converted at rates into the one the products are priced in.
+func attachPromotions(repo repository.PromotionRepository, rates *fx.Table, products []*domain.Product, now time.Time) error {
+
+	var ids []uint
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+
+	promotions, err := repo.FindLivePromotions(ids, now)
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		product.Promotions = nil
+		for _, promotion := range promotions {
+			if promotionQty(promotion, product.ID) == 0 {
+				continue
+			}
+			if err := localizePromotion(rates, product.Price.Currency, promotion); err != nil {
+				return err
+			}
+			product.Promotions = append(product.Promotions, promotion)
+		}
+
+		product.SalePrice = salePrice(product.Promotions, product.Price)
+
+		//variants at the product price follow its sale price
+		for i := range product.Variants {
+			variant := &product.Variants[i]
+			variant.SalePrice = nil
+			if variant.HasPrice() {
+				variant.SalePrice = salePrice(product.Promotions, variant.Price)
+			}
+		}
+	}
+
+	return nil
+}
+
+// localizePromotion converts the promotion price into currency. All the
+// products of a promotion are listed in one currency, so it is converted
+// once.
+func localizePromotion(rates *fx.Table, currency string, promotion *domain.Promotion) error {
+
+	if promotion.Price.IsZero() || promotion.Price.Currency == currency {
+		return nil
+	}
+
+	rate, err := rates.Rate(promotion.Price.Currency, currency)
+	if err != nil {
+		return err
+	}
+
+	promotion.Price = promotion.Price.Convert(currency, rate.Value)
+	return nil
+}
+
+// salePrice is the lowest price the sales among promotions take listPrice
+// to, nil when none of them is a sale.
+func salePrice(promotions []*domain.Promotion, listPrice domain.Money) *domain.Money {
+
+	var lowest *domain.Money
+	for _, promotion := range promotions {
+		if promotion.Type != domain.PROMO_SALE {
+			continue
+		}
+
+		price := promotion.SalePrice(listPrice)
+		if price.IsNegative() {
+			price = domain.Money{Currency: listPrice.Currency}
+		}
+		if lowest == nil || price.LessThan(*lowest) {
+			lowest = &price
+		}
+	}
+
+	return lowest
+}
+
+// promotionQty is how many units of the product the promotion takes, zero
+// when it doesn't cover the product
+func promotionQty(promotion *domain.Promotion, productId uint) int {
+	for _, p := range promotion.Products {
+		if p.ProductId == productId {
+			if p.Qty < 1 {
+				return 1
+			}
+			return p.Qty
+		}
+	}
+	return 0
+}
+
+// applyPromotions prices the buy-get offers and bundles the cart qualifies
+// for and spreads their discounts over the lines. Every unit counts towards
+// one offer only, offers are taken in the order they were created.
+func applyPromotions(cart *dto.CartResponse, products []*domain.Product) {
+
+	cart.Promotions = []dto.AppliedPromotion{}
+	cart.Discount = domain.Money{Currency: cart.Subtotal.Currency}
+	for i := range cart.Items {
+		cart.Items[i].Discount = domain.Money{Currency: cart.Items[i].CurrentPrice.Currency}
+	}
+
+	//units of every product still free to take part in an offer
+	units := map[uint]int{}
+	prices := map[uint]domain.Money{}
+	lines := map[uint][]int{}
+	for i, line := range cart.Items {
+		qty := lineQty(line)
+		if qty == 0 {
+			continue
+		}
+		id := uint(line.ProductId)
+		units[id] += qty
+		//variants may be priced apart, offers are worked out on the cheapest
+		if price, ok := prices[id]; !ok || line.CurrentPrice.LessThan(price) {
+			prices[id] = line.CurrentPrice
+		}
+		lines[id] = append(lines[id], i)
+	}
+
+	byId := map[uint]*domain.Promotion{}
+	for _, product := range products {
+		if units[product.ID] == 0 {
+			continue
+		}
+		for _, promotion := range product.Promotions {
+			byId[promotion.ID] = promotion
+		}
+	}
+
+	var promotionIds []uint
+	for id := range byId {
+		promotionIds = append(promotionIds, id)
+	}
+	sort.Slice(promotionIds, func(a, b int) bool { return promotionIds[a] < promotionIds[b] })
+
+	discounts := map[uint]domain.Money{}
+	for _, id := range promotionIds {
+		promotion := byId[id]
+
+		total := domain.Money{Currency: cart.Subtotal.Currency}
+		switch promotion.Type {
+		case domain.PROMO_BUY_GET:
+			group := promotion.BuyQty + promotion.GetQty
+			if promotion.BuyQty < 1 || promotion.GetQty < 1 {
+				continue
+			}
+
+			for _, p := range promotion.Products {
+				sets := units[p.ProductId] / group
+				if sets == 0 {
+					continue
+				}
+
+				discount := prices[p.ProductId].Mul(int64(sets * promotion.GetQty)).Percent(promotion.Percent)
+				units[p.ProductId] -= sets * group
+				discounts[p.ProductId] = discounts[p.ProductId].Add(discount)
+				total = total.Add(discount)
+			}
+
+		case domain.PROMO_BUNDLE:
+			sets := -1
+			for _, p := range promotion.Products {
+				n := units[p.ProductId] / promotionQty(promotion, p.ProductId)
+				if sets < 0 || n < sets {
+					sets = n
+				}
+			}
+			if sets <= 0 {
+				continue
+			}
+
+			full := domain.Money{Currency: cart.Subtotal.Currency}
+			var weights []int64
+			for _, p := range promotion.Products {
+				price := prices[p.ProductId].Mul(int64(promotionQty(promotion, p.ProductId)))
+				full = full.Add(price)
+				weights = append(weights, price.Amount)
+			}
+
+			if !promotion.Price.LessThan(full) {
+				continue
+			}
+
+			total = full.Sub(promotion.Price).Mul(int64(sets))
+			for k, part := range total.Allocate(weights) {
+				p := promotion.Products[k]
+				units[p.ProductId] -= sets * promotionQty(promotion, p.ProductId)
+				discounts[p.ProductId] = discounts[p.ProductId].Add(part)
+			}
+		}
+
+		if total.IsZero() {
+			continue
+		}
+
+		cart.Promotions = append(cart.Promotions, dto.AppliedPromotion{
+			PromotionId: promotion.ID,
+			Name:        promotion.Name,
+			Type:        promotion.Type,
+			Discount:    total,
+		})
+		cart.Discount = cart.Discount.Add(total)
+	}
+
+	//a product split over several lines shares its discount by quantity
+	for id, discount := range discounts {
+		var weights []int64
+		for _, i := range lines[id] {
+			weights = append(weights, int64(lineQty(cart.Items[i])))
+		}
+		for k, part := range discount.Allocate(weights) {
+			i := lines[id][k]
+			cart.Items[i].Discount = cart.Items[i].Discount.Add(part)
+		}
+	}
+}
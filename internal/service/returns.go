@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/repository"
+	payment "go-ecommerce-app/pkg/Payment"
+)
+
+// findOrderItem locks the items of an order and returns the active one with
+// the given id.
+func findOrderItem(repo repository.TransactionRepo, orderId uint, orderItemId int) (*domain.OrderItem, error) {
+
+	items, err := repo.FindOrderItemsForUpdate(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		if item.ID == orderItemId && item.Status == domain.ITEM_ACTIVE {
+			return item, nil
+		}
+	}
+
+	return nil, errors.New("order item not found")
+}
+
+// updateReturn moves a return request on for the seller. Received goods go
+// back into stock, and refunding gives the buyer back what they paid for
+// the returned units. The order becomes refunded once its payment has been
+// given back in full.
+func updateReturn(repos repository.Repositories, pc payment.PaymentClient, returnId uint, status string, note string, actor OrderActor) (*domain.ReturnRequest, error) {
+
+	r, err := repos.Transaction.FindReturnForUpdate(returnId, actor.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := repos.Transaction.FindOrderForUpdate(r.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !domain.CanTransitionReturn(r.Status, status) {
+		return nil, fmt.Errorf("%w: return is %s, cannot move to %s", ErrInvalidTransition, r.Status, status)
+	}
+
+	item, err := findOrderItem(repos.Transaction, r.OrderId, r.OrderItemId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case domain.RETURN_RECEIVED:
+		if err := restock(repos.Catalog, item, r.Qty); err != nil {
+			return nil, err
+		}
+
+	case domain.RETURN_REFUNDED:
+		reason := fmt.Sprintf("return %d: %s", r.ID, r.Reason)
+		refund, fullyRefunded, err := issueRefund(repos, pc, r.OrderId, []refundLine{{item: item, qty: r.Qty}}, nil, false, reason, actor)
+		if err != nil {
+			return nil, err
+		}
+		if refund == nil {
+			return nil, errors.New("order has no payment left to refund")
+		}
+		r.RefundId = refund.ID
+
+		refunded, err := repos.Transaction.RefundedQty(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		if refunded >= item.Qty {
+			item.Status = domain.ITEM_RETURNED
+			if err := repos.Transaction.UpdateOrderItem(item); err != nil {
+				return nil, err
+			}
+		}
+
+		if fullyRefunded && order.Status == domain.ORDER_DELIVERED {
+			if _, err := transitionOrder(repos.Transaction, r.OrderId, domain.ORDER_REFUNDED, systemActor, reason); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	r.Status = status
+	if note != "" {
+		r.SellerNote = note
+	}
+	if err := repos.Transaction.UpdateReturn(r); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/carrier"
+	"log"
+	"strings"
+	"time"
+)
+
+// trackingInterval is how long tracking of a parcel is trusted before the
+// carrier is asked again.
+const trackingInterval = 5 * time.Minute
+
+// createShipment books a parcel with the carrier for items of the seller's
+// fulfilment, all items not shipped yet when none are given. The fulfilment
+// is shipped once every active item is in a parcel.
+func createShipment(repos repository.Repositories, carriers carrier.Carriers, input dto.CreateShipmentRequest, actor OrderActor) (*domain.Shipment, error) {
+
+	cc, err := carriers.Get(input.Carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := repos.Transaction.FindFulfilment(input.FulfilmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.SellerId != actor.Id {
+		return nil, errors.New("order not found")
+	}
+
+	order, err := repos.Transaction.FindOrderForUpdate(f.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status == domain.ORDER_PENDING_PAYMENT {
+		return nil, fmt.Errorf("%w: order is not paid yet", ErrInvalidTransition)
+	}
+
+	fulfilments, err := repos.Transaction.FindOrderFulfilmentsForUpdate(f.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, locked := range fulfilments {
+		if locked.ID == f.ID {
+			f = locked
+		}
+	}
+
+	if !domain.FulfilmentCancellable(f.Status) {
+		return nil, fmt.Errorf("%w: fulfilment is %s", ErrInvalidTransition, f.Status)
+	}
+
+	items, err := repos.Transaction.FindOrderItemsForUpdate(f.OrderId)
+	if err != nil {
+		return nil, err
+	}
+
+	shippedIds, err := repos.Shipment.ShippedItemIds(f.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	shipped := map[int]bool{}
+	for _, id := range shippedIds {
+		shipped[id] = true
+	}
+
+	requested := map[int]bool{}
+	for _, id := range input.ItemIds {
+		requested[id] = true
+	}
+
+	shipment := &domain.Shipment{
+		OrderId:      f.OrderId,
+		FulfilmentId: f.ID,
+		SellerId:     f.SellerId,
+		Carrier:      cc.Name(),
+		Service:      input.Service,
+		Status:       domain.SHIPMENT_LABEL_CREATED,
+	}
+
+	unshipped := 0
+	for _, item := range items {
+		if item.FulfilmentId != f.ID || item.Status != domain.ITEM_ACTIVE || shipped[item.ID] {
+			continue
+		}
+		unshipped++
+
+		if len(requested) > 0 && !requested[item.ID] {
+			continue
+		}
+		shipment.Items = append(shipment.Items, domain.ShipmentItem{OrderItemId: item.ID, Qty: item.Qty})
+	}
+
+	if len(shipment.Items) == 0 || (len(requested) > 0 && len(shipment.Items) != len(requested)) {
+		return nil, errors.New("order items not found, cancelled or already shipped")
+	}
+
+	buyer, err := repos.User.FindUserbyID(int(order.UserId))
+	if err != nil {
+		return nil, err
+	}
+
+	label, err := cc.CreateShipment(carrier.ShipmentRequest{
+		Reference: fmt.Sprintf("%d-%d", order.OrderRefNumber, f.ID),
+		Service:   input.Service,
+		ToName:    strings.TrimSpace(buyer.FirstName + " " + buyer.LastName),
+		ToAddress: formatAddress(buyer.Address),
+		Items:     len(shipment.Items),
+	})
+	if err != nil {
+		log.Printf("carrier %s shipment for fulfilment %d failed %v", cc.Name(), f.ID, err)
+		return nil, fmt.Errorf("carrier rejected the shipment %w", err)
+	}
+	shipment.TrackingNumber = label.TrackingNumber
+	shipment.LabelRef = label.LabelRef
+
+	if err := repos.Shipment.CreateShipment(shipment); err != nil {
+		return nil, err
+	}
+
+	if f.Status == domain.FULFILMENT_PENDING {
+		if _, err := transitionFulfilment(repos.Transaction, f.ID, dto.UpdateOrderStatusRequest{Status: domain.FULFILMENT_PROCESSING}, actor); err != nil {
+			return nil, err
+		}
+	}
+
+	if unshipped == len(shipment.Items) {
+		if _, err := transitionFulfilment(repos.Transaction, f.ID, dto.UpdateOrderStatusRequest{
+			Status:         domain.FULFILMENT_SHIPPED,
+			Carrier:        shipment.Carrier,
+			TrackingNumber: shipment.TrackingNumber,
+			Note:           "shipped with " + shipment.Carrier,
+		}, actor); err != nil {
+			return nil, err
+		}
+	}
+
+	return shipment, nil
+}
+
+func formatAddress(a domain.Address) string {
+	var parts []string
+	for _, part := range []string{a.AddressLine1, a.AddressLine2, a.City, fmt.Sprint(a.PostCode), a.Country} {
+		if part != "" && part != "0" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// refreshTracking asks the carriers for news on the order's parcels that
+// were not checked recently and stores the new tracking events. Carriers
+// are called outside of any transaction. A delivered parcel completes its
+// fulfilment once every parcel of it is delivered.
+func refreshTracking(tx repository.TxManager, repo repository.ShipmentRepository, carriers carrier.Carriers, orderId uint) error {
+
+	now := time.Now()
+	shipments, err := repo.FindShipmentsToTrack(orderId, now.Add(-trackingInterval))
+	if err != nil {
+		return err
+	}
+
+	for _, s := range shipments {
+		cc, err := carriers.Get(s.Carrier)
+		if err != nil {
+			log.Printf("shipment %d: %v", s.ID, err)
+			continue
+		}
+
+		timeline, err := cc.Track(s.TrackingNumber)
+		if err != nil {
+			log.Printf("tracking shipment %d failed %v", s.ID, err)
+			continue
+		}
+
+		err = tx.WithTx(func(repos repository.Repositories) error {
+			return applyTracking(repos, s.ID, timeline, now)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyTracking(repos repository.Repositories, shipmentId uint, timeline []carrier.TrackingEvent, checkedAt time.Time) error {
+
+	s, err := repos.Shipment.FindShipmentForUpdate(shipmentId)
+	if err != nil {
+		return err
+	}
+
+	events := make([]domain.ShipmentEvent, 0, len(timeline))
+	for _, e := range timeline {
+		events = append(events, domain.ShipmentEvent{
+			ShipmentId:  s.ID,
+			Status:      e.Status,
+			Description: e.Description,
+			Location:    e.Location,
+			OccurredAt:  e.OccurredAt,
+		})
+	}
+
+	if err := repos.Shipment.AddShipmentEvents(events); err != nil {
+		return err
+	}
+
+	if len(timeline) > 0 {
+		s.Status = timeline[len(timeline)-1].Status
+	}
+	s.LastCheckedAt = &checkedAt
+
+	delivered := s.Status == domain.SHIPMENT_DELIVERED && s.DeliveredAt == nil
+	if delivered {
+		at := timeline[len(timeline)-1].OccurredAt
+		s.DeliveredAt = &at
+	}
+
+	if err := repos.Shipment.UpdateShipment(s); err != nil {
+		return err
+	}
+
+	if !delivered {
+		return nil
+	}
+
+	parcels, err := repos.Shipment.FindFulfilmentShipments(s.FulfilmentId)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range parcels {
+		if p.Status != domain.SHIPMENT_DELIVERED {
+			return nil
+		}
+	}
+
+	f, err := repos.Transaction.FindFulfilment(s.FulfilmentId)
+	if err != nil {
+		return err
+	}
+
+	//the seller may still have items left to ship
+	if f.Status != domain.FULFILMENT_SHIPPED {
+		return nil
+	}
+
+	_, err = transitionFulfilment(repos.Transaction, f.ID, dto.UpdateOrderStatusRequest{
+		Status: domain.FULFILMENT_DELIVERED,
+		Note:   "delivered by " + s.Carrier,
+	}, systemActor)
+	if err != nil {
+		return err
+	}
+
+	return collectOnDelivery(repos, f.OrderId)
+}
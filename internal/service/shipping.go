@@ -0,0 +1,217 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/fx"
+	"go-ecommerce-app/pkg/tax"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrShippingUnavailable = errors.New("some sellers in the cart don't ship to your address")
+
+// lineQty is how many units of a cart line the buyer gets after accepting
+// the changes found by reconcileCart.
+func lineQty(line dto.CartItemResponse) int {
+	if line.Removed {
+		return 0
+	}
+	if line.InsufficientStock {
+		return int(line.Available)
+	}
+	return line.Qty
+}
+
+// quoteShipping prices every seller's parcel in the cart with the method the
+// buyer picked, or the cheapest one. Sellers without any shipping zone ship
+// for free.
+func quoteShipping(repos repository.Repositories, userId int, address domain.Address, cart *dto.CartResponse, products []*domain.Product) error {
+
+	byId := map[int]*domain.Product{}
+	for _, product := range products {
+		byId[int(product.ID)] = product
+	}
+
+	parcels := map[int]*dto.SellerShipping{}
+	var sellerIds []int
+	for _, line := range cart.Items {
+		qty := lineQty(line)
+		if qty == 0 {
+			continue
+		}
+
+		parcel, ok := parcels[line.SellerId]
+		if !ok {
+			parcel = &dto.SellerShipping{SellerId: line.SellerId, Subtotal: domain.Money{Currency: line.CurrentPrice.Currency}}
+			parcels[line.SellerId] = parcel
+			sellerIds = append(sellerIds, line.SellerId)
+		}
+
+		parcel.Subtotal = parcel.Subtotal.Add(line.CurrentPrice.Mul(int64(qty)))
+		parcel.Weight += domain.ParcelWeight(byId[line.ProductId]) * uint(qty)
+	}
+	sort.Ints(sellerIds)
+
+	cart.Shipping = []dto.SellerShipping{}
+	cart.ShippingTotal = domain.Money{Currency: cart.Subtotal.Currency}
+	if len(sellerIds) == 0 {
+		return nil
+	}
+
+	zones, err := repos.Shipping.FindSellerZones(sellerIds)
+	if err != nil {
+		return err
+	}
+
+	sellerZones := map[int][]*domain.ShippingZone{}
+	for _, zone := range zones {
+		sellerZones[zone.SellerId] = append(sellerZones[zone.SellerId], zone)
+	}
+
+	selections, err := repos.Shipping.FindSelections(userId)
+	if err != nil {
+		return err
+	}
+
+	picked := map[int]uint{}
+	for _, selection := range selections {
+		picked[selection.SellerId] = selection.MethodId
+	}
+
+	for _, sellerId := range sellerIds {
+		parcel := parcels[sellerId]
+
+		if len(sellerZones[sellerId]) == 0 {
+			parcel.Options = []dto.ShippingOption{{Name: "free shipping", Price: domain.Money{Currency: parcel.Subtotal.Currency}}}
+		} else if zone := matchZone(sellerZones[sellerId], address); zone != nil {
+			for i := range zone.Methods {
+				parcel.Options = append(parcel.Options, dto.ShippingOption{
+					MethodId: zone.Methods[i].ID,
+					Name:     zone.Methods[i].Name,
+					Price:    shippingPrice(&zone.Methods[i], parcel.Subtotal, parcel.Weight),
+				})
+			}
+		}
+
+		if len(parcel.Options) == 0 {
+			parcel.Unavailable = true
+			cart.ShippingUnavailable = true
+			cart.Shipping = append(cart.Shipping, *parcel)
+			continue
+		}
+
+		//cheapest first, the first one is the default
+		sort.SliceStable(parcel.Options, func(a, b int) bool {
+			return parcel.Options[a].Price.LessThan(parcel.Options[b].Price)
+		})
+
+		parcel.Selected = &parcel.Options[0]
+		for i := range parcel.Options {
+			if parcel.Options[i].MethodId == picked[sellerId] {
+				parcel.Selected = &parcel.Options[i]
+			}
+		}
+
+		cart.ShippingTotal = cart.ShippingTotal.Add(parcel.Selected.Price)
+		cart.Shipping = append(cart.Shipping, *parcel)
+	}
+
+	return nil
+}
+
+// matchZone picks the most specific zone covering the address: a post code
+// range, then a whole country, then the zone covering everywhere.
+func matchZone(zones []*domain.ShippingZone, address domain.Address) *domain.ShippingZone {
+
+	var best *domain.ShippingZone
+	bestScore := -1
+	for _, zone := range zones {
+		score := -1
+		switch {
+		case zone.Country == "":
+			score = 0
+		case !strings.EqualFold(zone.Country, address.Country):
+			continue
+		case zone.PostCodeTo == 0:
+			score = 1
+		case address.PostCode >= zone.PostCodeFrom && address.PostCode <= zone.PostCodeTo:
+			score = 2
+		}
+
+		if score > bestScore {
+			best = zone
+			bestScore = score
+		}
+	}
+
+	return best
+}
+
+func shippingPrice(method *domain.ShippingMethod, subtotal domain.Money, weight uint) domain.Money {
+
+	if !method.FreeOver.IsZero() && !subtotal.LessThan(method.FreeOver) {
+		return domain.Money{Currency: subtotal.Currency}
+	}
+
+	price := method.Rate
+	if method.Type == domain.SHIPPING_WEIGHT {
+		//every started kilogram
+		kg := (int64(weight) + 999) / 1000
+		price = price.Add(method.PerKg.Mul(kg))
+	}
+
+	return price
+}
+
+// priceCart works out what the buyer pays for their cart: the reconciled
+// items at their selling price less promotion and coupon discounts, plus
+// shipping and tax. The cart view, the payment and checkout all
+// price the cart here so they always agree on the total. Everything is
+// priced in currency, product prices are converted to it at rates first.
+func priceCart(repos repository.Repositories, rules *tax.Rules, rates *fx.Table, currency string, userId int, cartItems []*domain.Cart, products []*domain.Product) (*dto.CartResponse, error) {
+
+	if err := localizePrices(rates, currency, products); err != nil {
+		return nil, err
+	}
+
+	if err := attachPromotions(repos.Promotion, rates, products, time.Now()); err != nil {
+		return nil, err
+	}
+
+	cart := reconcileCart(cartItems, products)
+	applyPromotions(cart, products)
+
+	address, err := repos.User.FindAddress(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyCoupon(repos, userId, cart, products); err != nil {
+		return nil, err
+	}
+
+	if err := quoteShipping(repos, userId, address, cart, products); err != nil {
+		return nil, err
+	}
+
+	exclusive := applyTax(rules, address, cart, products)
+
+	cart.Total = cart.Subtotal.Sub(cart.Discount).Add(cart.ShippingTotal).Add(exclusive)
+
+	//gift cards pay first as they expire, then the wallet, the card pays
+	//the rest
+	cart.AmountDue = cart.Total
+	if err := applyGiftCards(repos, userId, cart); err != nil {
+		return nil, err
+	}
+
+	if err := applyWallet(repos, userId, cart); err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
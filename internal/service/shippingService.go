@@ -0,0 +1,144 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+)
+
+type ShippingService struct {
+	Repo   repository.ShippingRepository
+	Auth   helper.Auth
+	Config configs.AppConfig
+}
+
+func (s *ShippingService) GetZones(u domain.User) ([]*domain.ShippingZone, error) {
+	return s.Repo.FindZones(u.ID)
+}
+
+func (s *ShippingService) CreateZone(u domain.User, input dto.ShippingZoneRequest) (*domain.ShippingZone, error) {
+
+	zone := &domain.ShippingZone{SellerId: u.ID}
+	if err := applyZoneInput(zone, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.CreateZone(zone); err != nil {
+		return nil, err
+	}
+
+	return zone, nil
+}
+
+func (s *ShippingService) UpdateZone(u domain.User, zoneId uint, input dto.ShippingZoneRequest) (*domain.ShippingZone, error) {
+
+	zone, err := s.Repo.FindZone(zoneId, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyZoneInput(zone, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.UpdateZone(zone); err != nil {
+		return nil, err
+	}
+
+	return zone, nil
+}
+
+func (s *ShippingService) DeleteZone(u domain.User, zoneId uint) error {
+	return s.Repo.DeleteZone(zoneId, u.ID)
+}
+
+func applyZoneInput(zone *domain.ShippingZone, input dto.ShippingZoneRequest) error {
+
+	if len(input.Name) < 1 {
+		return errors.New("zone name is required")
+	}
+
+	if input.PostCodeTo > 0 && len(input.Country) < 1 {
+		return errors.New("a post code range needs a country")
+	}
+
+	if input.PostCodeFrom > input.PostCodeTo && input.PostCodeTo > 0 {
+		return errors.New("post code range is reversed")
+	}
+
+	zone.Name = input.Name
+	zone.Country = strings.TrimSpace(input.Country)
+	zone.PostCodeFrom = input.PostCodeFrom
+	zone.PostCodeTo = input.PostCodeTo
+	return nil
+}
+
+func (s *ShippingService) CreateMethod(u domain.User, zoneId uint, input dto.ShippingMethodRequest) (*domain.ShippingMethod, error) {
+
+	zone, err := s.Repo.FindZone(zoneId, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	method := &domain.ShippingMethod{ZoneId: zone.ID, SellerId: u.ID, Active: true}
+	if err := s.applyMethodInput(method, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.CreateMethod(method); err != nil {
+		return nil, err
+	}
+
+	return method, nil
+}
+
+func (s *ShippingService) UpdateMethod(u domain.User, methodId uint, input dto.ShippingMethodRequest) (*domain.ShippingMethod, error) {
+
+	method, err := s.Repo.FindMethod(methodId, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applyMethodInput(method, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.UpdateMethod(method); err != nil {
+		return nil, err
+	}
+
+	return method, nil
+}
+
+func (s *ShippingService) DeleteMethod(u domain.User, methodId uint) error {
+	return s.Repo.DeleteMethod(methodId, u.ID)
+}
+
+func (s *ShippingService) applyMethodInput(method *domain.ShippingMethod, input dto.ShippingMethodRequest) error {
+
+	if len(input.Name) < 1 {
+		return errors.New("method name is required")
+	}
+
+	if input.Type != domain.SHIPPING_FLAT && input.Type != domain.SHIPPING_WEIGHT {
+		return errors.New("method type should be flat or weight")
+	}
+
+	if input.Rate < 0 || input.PerKg < 0 || input.FreeOver < 0 {
+		return errors.New("shipping amounts cannot be negative")
+	}
+
+	method.Name = input.Name
+	method.Type = input.Type
+	method.Rate = domain.NewMoney(input.Rate, s.Config.Currency)
+	method.PerKg = domain.NewMoney(input.PerKg, s.Config.Currency)
+	method.FreeOver = domain.NewMoney(input.FreeOver, s.Config.Currency)
+	if input.Active != nil {
+		method.Active = *input.Active
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/pkg/tax"
+)
+
+// applyTax adds the tax lines for the buyer's address to every cart line and
+// returns the exclusive tax the buyer pays on top of the subtotal.
+func applyTax(rules *tax.Rules, address domain.Address, cart *dto.CartResponse, products []*domain.Product) domain.Money {
+
+	cart.Tax = domain.Money{Currency: cart.Subtotal.Currency}
+	exclusive := domain.Money{Currency: cart.Subtotal.Currency}
+	if rules == nil {
+		return exclusive
+	}
+
+	categories := map[int]uint{}
+	for _, product := range products {
+		categories[int(product.ID)] = product.CategoryID
+	}
+
+	for i := range cart.Items {
+		line := &cart.Items[i]
+		line.Taxes = []domain.OrderItemTax{}
+
+		qty := lineQty(*line)
+		if qty == 0 {
+			continue
+		}
+
+		//tax is due on what the buyer pays after the discount
+		amount := line.CurrentPrice.Mul(int64(qty)).Sub(line.Discount)
+		lineTax := rules.Compute(address.Country, address.PostCode, tax.Line{
+			Category: categories[line.ProductId],
+			SellerId: line.SellerId,
+			Amount:   amount.Amount,
+		})
+		if lineTax == nil {
+			continue
+		}
+
+		taxAmount := domain.Money{Amount: lineTax.Amount, Currency: amount.Currency}
+		line.Taxes = append(line.Taxes, domain.OrderItemTax{
+			Name:         lineTax.Name,
+			Rate:         lineTax.Rate,
+			Inclusive:    lineTax.Inclusive,
+			Amount:       taxAmount,
+			RulesVersion: lineTax.RulesVersion,
+		})
+
+		cart.Tax = cart.Tax.Add(taxAmount)
+		if !lineTax.Inclusive {
+			exclusive = exclusive.Add(taxAmount)
+		}
+	}
+
+	return exclusive
+}
+
+// exclusiveTax is the tax charged on top of the price for qty units of item
+func exclusiveTax(item *domain.OrderItem, qty int) domain.Money {
+
+	total := domain.Money{Currency: item.Price.Currency}
+	for _, t := range item.Taxes {
+		if t.Inclusive || item.Qty == 0 {
+			continue
+		}
+		share := t.Amount.Allocate([]int64{int64(qty), int64(item.Qty - qty)})[0]
+		total = total.Add(share)
+	}
+
+	return total
+}
@@ -1,39 +1,422 @@
 package service
 
 import (
+	"errors"
+	"go-ecommerce-app/configs"
 	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/helper"
 	"go-ecommerce-app/internal/repository"
+	payment "go-ecommerce-app/pkg/Payment"
+	"go-ecommerce-app/pkg/carrier"
+	"go-ecommerce-app/pkg/fx"
+	"go-ecommerce-app/pkg/tax"
+	"log"
 )
 
 type TransactionService struct {
-	Repo repository.TransactionRepo
-	Auth helper.Auth
+	Repo         repository.TransactionRepo
+	UserRepo     repository.UserRepository
+	CatalogRepo  repository.CatalogRepository
+	ShipmentRepo repository.ShipmentRepository
+	Tx           repository.TxManager
+	Auth         helper.Auth
+	Config       configs.AppConfig
+	Payment      payment.PaymentClient
+	Carriers     carrier.Carriers
+	Tax          *tax.Rules
+	Rates        fx.Source
 }
 
-func NewTransactionService(r repository.TransactionRepo, auth helper.Auth) *TransactionService {
+func NewTransactionService(r repository.TransactionRepo, ur repository.UserRepository, cr repository.CatalogRepository, sr repository.ShipmentRepository, tx repository.TxManager, auth helper.Auth, config configs.AppConfig, pc payment.PaymentClient, carriers carrier.Carriers, rules *tax.Rules, rates fx.Source) *TransactionService {
 	return &TransactionService{
-		Repo: r,
-		Auth: auth,
+		Repo:         r,
+		UserRepo:     ur,
+		CatalogRepo:  cr,
+		ShipmentRepo: sr,
+		Tx:           tx,
+		Auth:         auth,
+		Config:       config,
+		Payment:      pc,
+		Carriers:     carriers,
+		Tax:          rules,
+		Rates:        rates,
 	}
 }
 
-func (s *TransactionService) GetOrders(u domain.User) ([]*domain.OrderItem, error) {
+func (s *TransactionService) MakePayment(u domain.User) (*dto.PaymentResponse, error) {
 
-	orderItems, err := s.Repo.FindOrders(u.ID)
+	cartItems, err := s.UserRepo.FindCartItems(u.ID)
+	if err != nil {
+		return nil, errors.New("could not find cart items")
+	}
+
+	if len(cartItems) == 0 {
+		return nil, errors.New("no items found in cart")
+	}
+
+	products, err := s.CatalogRepo.FindProductsByIds(cartProductIds(cartItems))
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := s.Rates.Latest()
+	if err != nil {
+		return nil, err
+	}
+
+	//charge current prices, checkout asks the buyer to accept any change
+	cart, err := priceCart(s.Tx.Repositories(), s.Tax, rates, s.Config.Currency, u.ID, cartItems, products)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cart.Subtotal.GreaterThan(domain.Money{}) {
+		return nil, errors.New("none of the cart items are available")
+	}
+
+	if cart.ShippingUnavailable {
+		return nil, ErrShippingUnavailable
+	}
+
+	p, err := preparePayment(s.Repo, s.Payment, u.ID, cart.AmountDue)
+	if err != nil {
+		return nil, err
+	}
+
+	//the wallet covers the whole order, it is placed without a card payment
+	if p == nil {
+		return &dto.PaymentResponse{PubKey: s.Config.PubKey, Amount: cart.AmountDue}, nil
+	}
+
+	return &dto.PaymentResponse{
+		PaymentId: p.PaymentId,
+		Secret:    p.ClientSecret,
+		PubKey:    s.Config.PubKey,
+		Amount:    p.Amount,
+	}, nil
+}
+
+func (s *TransactionService) GetOrders(u domain.User) ([]*dto.SellerOrderDetails, error) {
+
+	orders, err := s.Repo.FindOrders(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (s *TransactionService) GetOrderDetails(u domain.User, fulfilmentId uint) (*dto.SellerOrderDetails, error) {
+
+	orderDetails, err := s.Repo.FindOrderById(fulfilmentId, u.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return orderItems, nil
+	return orderDetails, nil
 }
 
-func (s *TransactionService) GetOrdersById(user domain.User, orderId int) (*domain.Order, error) {
+// GetInvoice renders the invoice of the seller's fulfilment, issuing it on
+// the first download.
+func (s *TransactionService) GetInvoice(u domain.User, fulfilmentId uint, format string) (*dto.InvoiceFile, error) {
 
-	OrderDetails, err := s.Repo.FindOrderById(orderId, user.ID)
+	fulfilment, err := s.Repo.FindFulfilment(fulfilmentId)
 	if err != nil {
 		return nil, err
 	}
 
-	return OrderDetails, nil
+	issued, err := issueInvoice(s.Tx, fulfilment.OrderId, fulfilment.ID, OrderActor{Id: u.ID, Role: domain.SELLER})
+	if err != nil {
+		return nil, err
+	}
+
+	return renderInvoice(issued, format)
+}
+
+func (s *TransactionService) UpdateOrderStatus(u domain.User, fulfilmentId uint, input dto.UpdateOrderStatusRequest) (*domain.Fulfilment, error) {
+
+	var fulfilment *domain.Fulfilment
+	err := s.Tx.WithTx(func(repos repository.Repositories) error {
+		var err error
+		fulfilment, err = transitionFulfilment(repos.Transaction, fulfilmentId, input, OrderActor{Id: u.ID, Role: domain.SELLER})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fulfilment, nil
+}
+
+// CancelFulfilment cancels the given items of the seller's fulfilment, or
+// all of them when none are given.
+func (s *TransactionService) CancelFulfilment(u domain.User, fulfilmentId uint, input dto.CancelOrderRequest) (*dto.CancelOrderResponse, error) {
+
+	if len(input.Reason) < 1 {
+		return nil, errors.New("a reason is required to cancel a buyer's order")
+	}
+
+	var response *dto.CancelOrderResponse
+	err := s.Tx.WithTx(func(repos repository.Repositories) error {
+
+		f, err := repos.Transaction.FindFulfilment(fulfilmentId)
+		if err != nil {
+			return err
+		}
+
+		if f.SellerId != u.ID {
+			return errors.New("order not found")
+		}
+
+		response, err = cancelOrderItems(repos, s.Payment, f.OrderId, input.ItemIds, false, input.Reason, OrderActor{Id: u.ID, Role: domain.SELLER})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (s *TransactionService) CreateShipment(u domain.User, input dto.CreateShipmentRequest) (*domain.Shipment, error) {
+
+	var shipment *domain.Shipment
+	err := s.Tx.WithTx(func(repos repository.Repositories) error {
+		var err error
+		shipment, err = createShipment(repos, s.Carriers, input, OrderActor{Id: u.ID, Role: domain.SELLER})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return shipment, nil
+}
+
+func (s *TransactionService) GetShipment(u domain.User, shipmentId uint) (*domain.Shipment, error) {
+
+	shipment, err := s.ShipmentRepo.FindShipment(shipmentId, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := refreshTracking(s.Tx, s.ShipmentRepo, s.Carriers, shipment.OrderId); err != nil {
+		log.Printf("refreshing tracking of order %d failed %v", shipment.OrderId, err)
+		return shipment, nil
+	}
+
+	return s.ShipmentRepo.FindShipment(shipmentId, u.ID)
+}
+
+// GetReturns lists the seller's return requests, the ones still waiting on
+// the seller when no status is given.
+func (s *TransactionService) GetReturns(u domain.User, status string) ([]*domain.ReturnRequest, error) {
+
+	statuses := []string{domain.RETURN_REQUESTED, domain.RETURN_APPROVED, domain.RETURN_RECEIVED}
+	if status != "" {
+		statuses = []string{status}
+	}
+
+	return s.Repo.FindSellerReturns(u.ID, statuses)
+}
+
+func (s *TransactionService) UpdateReturnStatus(u domain.User, returnId uint, input dto.UpdateReturnStatusRequest) (*domain.ReturnRequest, error) {
+
+	if input.Status == domain.RETURN_REJECTED && len(input.Note) < 1 {
+		return nil, errors.New("a note is required to reject a return")
+	}
+
+	var request *domain.ReturnRequest
+	err := s.Tx.WithTx(func(repos repository.Repositories) error {
+		var err error
+		request, err = updateReturn(repos, s.Payment, returnId, input.Status, input.Note, OrderActor{Id: u.ID, Role: domain.SELLER})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// HandlePaymentWebhook verifies a gateway event and applies it to the
+// matching payment and order. Every event is stored once by its id, so a
+// redelivery is acknowledged without being applied again.
+func (s *TransactionService) HandlePaymentWebhook(payload []byte, signature string) error {
+
+	event, err := s.Payment.ParseWebhook(payload, signature)
+	if err != nil {
+		return err
+	}
+
+	return s.Tx.WithTx(func(repos repository.Repositories) error {
+
+		created, err := repos.Transaction.CreatePaymentEvent(&domain.PaymentEvent{
+			EventId:      event.ID,
+			Type:         event.Type,
+			ProviderType: event.ProviderType,
+			PaymentId:    event.PaymentId,
+			Payload:      string(event.Payload),
+		})
+		if err != nil {
+			return err
+		}
+
+		if !created {
+			log.Printf("payment event %s already processed", event.ID)
+			return nil
+		}
+
+		if event.PaymentId == "" {
+			return nil
+		}
+
+		p, err := repos.Transaction.FindPaymentByPaymentId(event.PaymentId)
+		if err != nil {
+			return err
+		}
+
+		if p == nil {
+			log.Printf("payment event %s for unknown payment %s", event.ID, event.PaymentId)
+			return nil
+		}
+
+		//a refunded payment never goes back to an earlier state
+		if p.Status == domain.PAYMENT_REFUNDED {
+			return nil
+		}
+
+		var orderStatus string
+		switch event.Type {
+		case payment.EventPaymentSucceeded:
+			//the order is only paid once the whole amount came in
+			if charged := domain.NewMoney(event.Amount, event.Currency); !charged.Equal(p.Amount) {
+				log.Printf("payment event %s charged %s but payment %s is for %s", event.ID, charged, p.PaymentId, p.Amount)
+				return nil
+			}
+			p.Status = domain.PAYMENT_SUCCESS
+			p.TransactionId = event.TransactionId
+			orderStatus = domain.ORDER_PAID
+		case payment.EventPaymentFailed, payment.EventPaymentCanceled:
+			if p.Status == domain.PAYMENT_SUCCESS {
+				return nil
+			}
+			p.Status = domain.PAYMENT_FAILED
+		case payment.EventPaymentRefunded:
+			//refunds we issued are already booked, this picks up ones made
+			//straight on the gateway
+			refunded := domain.NewMoney(event.AmountRefunded, p.Amount.Currency)
+			if p.OrderId > 0 {
+				return bookGatewayRefund(repos, p.OrderId, refunded, event.FullyRefunded, event.ProviderType, event.ID)
+			}
+			if refunded.GreaterThan(p.Refunded) {
+				p.Refunded = refunded
+			}
+			p.Status = domain.PAYMENT_PARTIALLY_REFUNDED
+			if event.FullyRefunded {
+				p.Status = domain.PAYMENT_REFUNDED
+			}
+		default:
+			return nil
+		}
+
+		p.Response = event.ProviderType
+		if err := repos.Transaction.UpdatePayment(p); err != nil {
+			return err
+		}
+
+		//payment started from the cart, order not placed yet
+		if p.OrderId == 0 {
+			return nil
+		}
+
+		if p.TransactionId != "" {
+			if err := repos.Transaction.UpdateOrderTransaction(p.OrderId, p.TransactionId); err != nil {
+				return err
+			}
+		}
+
+		if orderStatus == "" {
+			return nil
+		}
+
+		_, err = transitionOrder(repos.Transaction, p.OrderId, orderStatus, systemActor, event.ProviderType)
+		if errors.Is(err, ErrInvalidTransition) {
+			log.Printf("payment event %s ignored for order %d: %v", event.ID, p.OrderId, err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		//sellers are credited and gift cards issued once the buyer's money
+		//is in
+		if orderStatus == domain.ORDER_PAID {
+			if err := recordSale(repos, p.OrderId); err != nil {
+				return err
+			}
+			return issueGiftCards(repos, p.OrderId, s.Config.GiftCardValidity)
+		}
+
+		return nil
+	})
+}
+
+var ErrCartChanged = errors.New("cart changed while placing the order, please retry")
+
+// preparePayment returns the buyer's open payment for the given amount. A
+// payment started for a different amount is cancelled at the gateway and
+// replaced, so the buyer is always charged for what is in the cart. There
+// is no payment when nothing is left to charge.
+func preparePayment(repo repository.TransactionRepo, pc payment.PaymentClient, userId int, amount domain.Money) (*domain.Payment, error) {
+
+	existing, err := repo.FindOpenPayment(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		if existing.Amount.Equal(amount) {
+			return existing, nil
+		}
+
+		if existing.Status == domain.PAYMENT_SUCCESS {
+			return nil, errors.New("cart changed after the payment was completed, contact support for a refund")
+		}
+
+		if _, err := pc.CancelPayment(existing.PaymentId); err != nil {
+			log.Printf("cancelling stale payment %s failed %v", existing.PaymentId, err)
+		}
+		existing.Status = domain.PAYMENT_FAILED
+		if err := repo.UpdatePayment(existing); err != nil {
+			return nil, err
+		}
+	}
+
+	if amount.IsZero() {
+		return nil, nil
+	}
+
+	pi, err := pc.CreatePayment(amount.Amount, amount.Currency, userId, 0)
+	if err != nil {
+		log.Printf("payment creation failed %v", err)
+		return nil, errors.New("payment creation failed")
+	}
+
+	p := &domain.Payment{
+		UserId:        uint(userId),
+		CaptureMethod: domain.CAPTURE_CARD,
+		Amount:        amount,
+		PaymentId:     pi.ID,
+		TransactionId: pi.TransactionId,
+		ClientSecret:  pi.ClientSecret,
+		Status:        domain.PAYMENT_INITIAL,
+		Response:      pi.Status,
+	}
+	if err := repo.CreatePayment(p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
 }
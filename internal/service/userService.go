@@ -8,17 +8,29 @@ import (
 	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/helper"
 	"go-ecommerce-app/internal/repository"
+	payment "go-ecommerce-app/pkg/Payment"
+	"go-ecommerce-app/pkg/carrier"
+	"go-ecommerce-app/pkg/fx"
 	"go-ecommerce-app/pkg/notification"
+	"go-ecommerce-app/pkg/tax"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type UserService struct {
-	Repo   repository.UserRepository
-	CRepo  repository.CatalogRepository
-	Auth   helper.Auth
-	Config configs.AppConfig
+	Repo     repository.UserRepository
+	CRepo    repository.CatalogRepository
+	TRepo    repository.TransactionRepo
+	SRepo    repository.ShipmentRepository
+	Tx       repository.TxManager
+	Auth     helper.Auth
+	Config   configs.AppConfig
+	Payment  payment.PaymentClient
+	Carriers carrier.Carriers
+	Tax      *tax.Rules
+	Rates    fx.Source
 }
 
 // func (s UserService) findUserByEmail(email string) (*domain.User, error) {
@@ -182,6 +194,7 @@ func (s *UserService) GetProfile(id uint) (*dto.UserProfileResponse, error) {
 		Email:     profile.Email,
 		Phone:     profile.Phone,
 		UserType:  profile.UserType,
+		Currency:  profile.Currency,
 		Address:   profile.Address,
 		Cart:      profile.Cart,
 		Orders:    profile.Orders,
@@ -204,6 +217,18 @@ func (s *UserService) UpdateProfile(id int, input *dto.ProfileInput) error {
 		user.LastName = input.LastName
 	}
 
+	if input.Currency != "" {
+		rates, err := s.Rates.Latest()
+		if err != nil {
+			return err
+		}
+
+		user.Currency, err = currencyCode(rates, input.Currency)
+		if err != nil {
+			return err
+		}
+	}
+
 	//update user with current firstname,lastname
 	_, err = s.Repo.UpdateUser(id, user)
 	if err != nil {
@@ -233,6 +258,10 @@ func (s *UserService) BecomeSeller(id int, input dto.SellerInput) (string, error
 		return "", errors.New("user is already upgraded as seller")
 	}
 
+	if user.UserType == domain.ADMIN {
+		return "", errors.New("admin accounts can't join the seller program")
+	}
+
 	//update user
 	seller, err := s.Repo.UpdateUser(id, domain.User{
 		FirstName: input.FirstName,
@@ -261,7 +290,7 @@ func (s *UserService) BecomeSeller(id int, input dto.SellerInput) (string, error
 	return token, err
 }
 
-func (s *UserService) FindCart(id uint) ([]*domain.Cart, error) {
+func (s *UserService) FindCart(id uint) (*dto.CartResponse, error) {
 
 	cart, err := s.Repo.FindCartItems(int(id))
 	if err != nil {
@@ -269,12 +298,335 @@ func (s *UserService) FindCart(id uint) ([]*domain.Cart, error) {
 		return nil, errors.New("something went wrong while fetching cart")
 	}
 
+	products, err := s.CRepo.FindProductsByIds(cartProductIds(cart))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.Repo.FindUserbyID(int(id))
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := s.Rates.Latest()
+	if err != nil {
+		return nil, err
+	}
+
+	priced, err := priceCart(s.Tx.Repositories(), s.Tax, rates, s.Config.Currency, int(id), cart, products)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := displayCart(rates, s.Config.Currency, user.Currency, priced); err != nil {
+		return nil, err
+	}
+
+	return priced, nil
+}
+
+// SelectShipping picks the shipping method for one seller's items in the
+// cart.
+func (s *UserService) SelectShipping(u domain.User, input dto.SelectShippingRequest) (*dto.CartResponse, error) {
+
+	cart, err := s.FindCart(uint(u.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	offered := false
+	for _, parcel := range cart.Shipping {
+		for _, option := range parcel.Options {
+			if parcel.SellerId == input.SellerId && option.MethodId == input.MethodId {
+				offered = true
+			}
+		}
+	}
+
+	if !offered {
+		return nil, errors.New("shipping method not available for these items")
+	}
+
+	err = s.Tx.Repositories().Shipping.SaveSelection(&domain.ShippingSelection{
+		UserId:   u.ID,
+		SellerId: input.SellerId,
+		MethodId: input.MethodId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.FindCart(uint(u.ID))
+}
+
+// ApplyCoupon puts a coupon code on the buyer's cart, replacing any coupon
+// applied before.
+func (s *UserService) ApplyCoupon(u domain.User, input dto.ApplyCouponRequest) (*dto.CartResponse, error) {
+
+	repos := s.Tx.Repositories()
+
+	coupon, err := repos.Coupon.FindCouponByCode(strings.ToUpper(strings.TrimSpace(input.Code)))
+	if err != nil {
+		return nil, err
+	}
+
+	if coupon == nil {
+		return nil, ErrCouponNotFound
+	}
+
+	previous, err := repos.Coupon.FindCartCoupon(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repos.Coupon.SaveCartCoupon(&domain.CartCoupon{UserId: u.ID, CouponId: coupon.ID}); err != nil {
+		return nil, err
+	}
+
+	cart, err := s.FindCart(uint(u.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	//keep the coupon the buyer had when the new one doesn't apply
+	if cart.Coupon != nil && cart.Coupon.Invalid != "" {
+		if previous != nil {
+			err = repos.Coupon.SaveCartCoupon(&domain.CartCoupon{UserId: u.ID, CouponId: previous.CouponId})
+		} else {
+			err = repos.Coupon.DeleteCartCoupon(u.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w, %s", ErrCouponInvalid, cart.Coupon.Invalid)
+	}
+
 	return cart, nil
 }
 
+func (s *UserService) RemoveCoupon(u domain.User) (*dto.CartResponse, error) {
+
+	if err := s.Tx.Repositories().Coupon.DeleteCartCoupon(u.ID); err != nil {
+		return nil, err
+	}
+
+	return s.FindCart(uint(u.ID))
+}
+
+// UseWallet pays for the cart from the buyer's wallet first, up to the
+// amount asked for or as much as the cart needs
+func (s *UserService) UseWallet(u domain.User, input dto.UseWalletRequest) (*dto.CartResponse, error) {
+
+	if input.Amount < 0 {
+		return nil, errors.New("amount cannot be negative")
+	}
+
+	repos := s.Tx.Repositories()
+
+	wallet, err := repos.Wallet.FindWallet(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if wallet == nil || !wallet.Balance.GreaterThan(domain.Money{}) {
+		return nil, ErrWalletBalance
+	}
+
+	err = repos.Wallet.SaveCartWallet(&domain.CartWallet{UserId: u.ID, Amount: domain.NewMoney(input.Amount, wallet.Balance.Currency)})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.FindCart(uint(u.ID))
+}
+
+func (s *UserService) StopUsingWallet(u domain.User) (*dto.CartResponse, error) {
+
+	if err := s.Tx.Repositories().Wallet.DeleteCartWallet(u.ID); err != nil {
+		return nil, err
+	}
+
+	return s.FindCart(uint(u.ID))
+}
+
+// GetWallet returns the buyer's balance with every change made to it
+func (s *UserService) GetWallet(u domain.User) (*dto.WalletResponse, error) {
+
+	repos := s.Tx.Repositories()
+
+	wallet, err := repos.Wallet.FindWallet(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if wallet == nil {
+		wallet = &domain.Wallet{UserId: u.ID, Balance: domain.NewMoney(0, s.Config.Currency)}
+	}
+
+	txns, err := repos.Wallet.FindTransactions(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.WalletResponse{Wallet: wallet, Transactions: txns}, nil
+}
+
+// RedeemGiftCard adds a gift card to the buyer's cart, it pays for the cart
+// before the wallet and the card do
+func (s *UserService) RedeemGiftCard(u domain.User, input dto.RedeemGiftCardRequest) (*dto.CartResponse, error) {
+
+	repos := s.Tx.Repositories()
+
+	card, err := repos.GiftCard.FindGiftCardByCode(normalizeGiftCardCode(input.Code))
+	if err != nil {
+		return nil, err
+	}
+
+	if card == nil {
+		return nil, ErrGiftCardNotFound
+	}
+
+	if problem := giftCardProblem(card, s.Config.Currency, time.Now()); problem != "" {
+		return nil, fmt.Errorf("%w, %s", ErrGiftCardInvalid, problem)
+	}
+
+	if err := repos.GiftCard.AddCartGiftCard(u.ID, card.ID); err != nil {
+		return nil, err
+	}
+
+	return s.FindCart(uint(u.ID))
+}
+
+func (s *UserService) RemoveGiftCard(u domain.User, giftCardId uint) (*dto.CartResponse, error) {
+
+	if err := s.Tx.Repositories().GiftCard.RemoveCartGiftCard(u.ID, giftCardId); err != nil {
+		return nil, err
+	}
+
+	return s.FindCart(uint(u.ID))
+}
+
+// GetGiftCards returns the cards the buyer bought, with their codes to
+// pass on
+func (s *UserService) GetGiftCards(u domain.User) ([]*domain.GiftCard, error) {
+	return s.Tx.Repositories().GiftCard.FindPurchasedGiftCards(u.ID)
+}
+
+func (s *UserService) CheckGiftCard(input dto.RedeemGiftCardRequest) (*dto.GiftCardBalance, error) {
+
+	card, err := s.Tx.Repositories().GiftCard.FindGiftCardByCode(normalizeGiftCardCode(input.Code))
+	if err != nil {
+		return nil, err
+	}
+
+	if card == nil {
+		return nil, ErrGiftCardNotFound
+	}
+
+	return &dto.GiftCardBalance{
+		Code:      card.MaskedCode(),
+		Balance:   card.Balance,
+		ExpiresAt: card.ExpiresAt,
+		Expired:   card.Expired(time.Now()),
+		Disabled:  card.Disabled,
+	}, nil
+}
+
+// CartChangedError carries the reconciled cart back to the buyer when
+// checkout finds changes they have not accepted yet.
+type CartChangedError struct {
+	Cart *dto.CartResponse
+}
+
+func (e *CartChangedError) Error() string {
+	return "cart items changed since they were added, review the cart and accept the changes"
+}
+
+func cartProductIds(cartItems []*domain.Cart) []int {
+	var ids []int
+	for _, item := range cartItems {
+		ids = append(ids, item.ProductId)
+	}
+	return ids
+}
+
+// reconcileCart checks every cart line against the current product. Name,
+// price and image are copied into the cart when an item is added, so the
+// seller may have changed or deleted the product since, or a sale may have
+// started or ended. Subtotal is what the buyer pays for the items after
+// accepting the changes: current selling prices, lines of deleted products
+// dropped and quantities capped at the available stock. Lines of a variant
+// are priced and stocked by the variant.
+func reconcileCart(cartItems []*domain.Cart, products []*domain.Product) *dto.CartResponse {
+
+	byId := map[int]*domain.Product{}
+	for _, product := range products {
+		byId[int(product.ID)] = product
+	}
+
+	//stock already taken by earlier lines of the same product or variant
+	type stockKey struct {
+		product int
+		variant uint
+	}
+	claimed := map[stockKey]uint{}
+
+	cart := &dto.CartResponse{Items: []dto.CartItemResponse{}}
+	for _, item := range cartItems {
+		line := dto.CartItemResponse{Cart: *item, CurrentPrice: item.Price}
+
+		product, ok := byId[item.ProductId]
+		if !ok {
+			line.Removed = true
+			cart.HasChanges = true
+			cart.Items = append(cart.Items, line)
+			continue
+		}
+
+		//the variant was deleted, or the product got variants after the
+		//line was added and it no longer says which one
+		price, stock := product.SellingPrice(), product.Stock
+		if item.VariantId > 0 || len(product.Variants) > 0 {
+			variant := product.Variant(item.VariantId)
+			if variant == nil {
+				line.Removed = true
+				cart.HasChanges = true
+				cart.Items = append(cart.Items, line)
+				continue
+			}
+			price, stock = product.VariantPrice(variant), variant.Stock
+		}
+
+		key := stockKey{product: item.ProductId, variant: item.VariantId}
+		line.CurrentPrice = price
+		line.GiftCard = product.Type == domain.PRODUCT_GIFT_CARD
+		if stock > claimed[key] {
+			line.Available = stock - claimed[key]
+		}
+
+		if !line.CurrentPrice.Equal(item.Price) {
+			line.PriceChanged = true
+			cart.HasChanges = true
+		}
+
+		qty := uint(item.Qty)
+		if line.Available < qty {
+			line.InsufficientStock = true
+			cart.HasChanges = true
+			qty = line.Available
+		}
+		claimed[key] += qty
+
+		cart.Subtotal = cart.Subtotal.Add(line.CurrentPrice.Mul(int64(qty)))
+		cart.Items = append(cart.Items, line)
+	}
+
+	return cart
+}
+
 func (s *UserService) CreateCart(input *dto.CreateCartRequest, u domain.User) ([]*domain.Cart, error) {
 	//Check if cart exist
-	cart, _ := s.Repo.FindCartItem(u.ID, int(input.ProductId))
+	cart, _ := s.Repo.FindCartItem(u.ID, int(input.ProductId), input.VariantId)
 	if cart.ID > 0 {
 		if input.ProductId == 0 {
 			return nil, errors.New("invalid product id")
@@ -282,7 +634,7 @@ func (s *UserService) CreateCart(input *dto.CreateCartRequest, u domain.User) ([
 
 		if input.Qty < 1 {
 			//delete the cart
-			if err := s.Repo.DeleteCartItemByid(u.ID); err != nil {
+			if err := s.Repo.DeleteCartItemByid(cart.ID); err != nil {
 				log.Printf("error on deleting cart item %v", err)
 				return nil, errors.New("error on deleting cart item")
 			}
@@ -302,16 +654,48 @@ func (s *UserService) CreateCart(input *dto.CreateCartRequest, u domain.User) ([
 			return nil, errors.New("product not found to create cart item")
 		}
 
-		//create cart
-		err = s.Repo.CreateCart(domain.Cart{
+		rates, err := s.Rates.Latest()
+		if err != nil {
+			return nil, err
+		}
+
+		//buyer adds the item at the price shown, sale price included
+		products := []*domain.Product{product}
+		if err := localizePrices(rates, s.Config.Currency, products); err != nil {
+			return nil, err
+		}
+		if err := attachPromotions(s.Tx.Repositories().Promotion, rates, products, time.Now()); err != nil {
+			return nil, err
+		}
+
+		item := domain.Cart{
 			UserId:    u.ID,
 			ProductId: int(input.ProductId),
 			Name:      product.Name,
 			ImageUrl:  product.ImageUrl,
-			Price:     product.Price,
+			Price:     product.SellingPrice(),
 			Qty:       int(input.Qty),
 			SellerId:  product.UserId,
-		})
+		}
+
+		//products with variants are bought as one of them
+		if input.VariantId > 0 || len(product.Variants) > 0 {
+			variant := product.Variant(input.VariantId)
+			if variant == nil {
+				return nil, ErrVariantRequired
+			}
+
+			item.VariantId = variant.ID
+			item.SKU = variant.SKU
+			item.Name = fmt.Sprintf("%s (%s)", product.Name, variant.Title())
+			item.Price = product.VariantPrice(variant)
+			if variant.ImageUrl != "" {
+				item.ImageUrl = variant.ImageUrl
+			}
+		}
+
+		//create cart
+		err = s.Repo.CreateCart(item)
 
 		if err != nil {
 			log.Printf("cart creation error-%v", err)
@@ -323,53 +707,336 @@ func (s *UserService) CreateCart(input *dto.CreateCartRequest, u domain.User) ([
 	return s.Repo.FindCartItems(u.ID)
 }
 
-func (s *UserService) CreateOrder(u domain.User) (int, error) {
+// CreateOrder turns the cart into an order in a single transaction: cart
+// and product rows are locked, every line is checked against the current
+// product, stock is decremented and the cart is cleared only if everything
+// else succeeded.
+func (s *UserService) CreateOrder(u domain.User, input dto.CreateOrderRequest) (int, error) {
 
 	//Get cart items of current user
-	cartItems, err := s.Repo.FindCartItems(u.ID)
+	cart, err := s.FindCart(uint(u.ID))
 	if err != nil {
-		return 0, errors.New("could not find cart items")
+		return 0, err
 	}
 
 	//if above function return object with no item
-	if len(cartItems) == 0 {
+	if len(cart.Items) == 0 {
 		return 0, errors.New("no items found")
 	}
 
-	//find success payment status
-	paymentId := "PAY12345"
-	txnId := "TXN12345"
+	if cart.HasChanges && !input.AcceptChanges {
+		return 0, &CartChangedError{Cart: cart}
+	}
+
+	if !cart.Subtotal.GreaterThan(domain.Money{}) {
+		return 0, errors.New("none of the cart items are available")
+	}
+
+	if cart.ShippingUnavailable {
+		return 0, ErrShippingUnavailable
+	}
+
+	method := input.PaymentMethod
+	if len(method) < 1 {
+		method = domain.CAPTURE_CARD
+	}
+	if method != domain.CAPTURE_CARD && method != domain.CAPTURE_COD && method != domain.CAPTURE_BANK_TRANSFER {
+		return 0, ErrPaymentMethod
+	}
+
+	//reuse the payment started from the cart or create one for what gift
+	//cards and the wallet don't cover, done before locking anything since
+	//it calls the gateway. Paying later drops a card payment started
+	//from the cart.
+	amount := cart.AmountDue
+	if method != domain.CAPTURE_CARD {
+		amount = domain.Money{}
+	}
+	p, err := preparePayment(s.TRepo, s.Payment, u.ID, amount)
+	if err != nil {
+		return 0, err
+	}
+
 	orderRef, _ := helper.RandomNumbers(8)
 
-	//create order with generated OrderNumber
-	var amount float64
-	var orderItems []domain.OrderItem
+	//one rate table for the whole checkout, the order keeps the rates used
+	rates, err := s.Rates.Latest()
+	if err != nil {
+		return 0, err
+	}
 
-	for _, item := range cartItems {
-		amount += item.Price * float64(item.Qty)
-		orderItems = append(orderItems, domain.OrderItem{
-			ProductId: item.ProductId,
-			Name:      item.Name,
-			Price:     item.Price,
-			Qty:       item.Qty,
-			ImageUrl:  item.ImageUrl,
-			SellerId:  item.SellerId,
-		})
-	}
-	order := domain.Order{
-		UserId:         uint(u.ID),
-		PaymentId:      paymentId,
-		TransactionId:  txnId,
-		OrderRefNumber: orderRef,
-		Amount:         amount,
-		Items:          orderItems,
-	}
-	if err := s.Repo.CreateOrder(order); err != nil {
+	buyer, err := s.Repo.FindUserbyID(u.ID)
+	if err != nil {
 		return 0, err
 	}
 
-	//Delete items from cart after order success
-	if err := s.Repo.DeleteCartItems(u.ID); err != nil {
+	err = s.Tx.WithTx(func(repos repository.Repositories) error {
+
+		//cart or products could have changed since the payment was prepared
+		cartItems, err := repos.User.LockCartItems(u.ID)
+		if err != nil {
+			return errors.New("could not find cart items")
+		}
+
+		if len(cartItems) == 0 {
+			return errors.New("no items found")
+		}
+
+		products, err := repos.Catalog.FindProductsForUpdate(cartProductIds(cartItems))
+		if err != nil {
+			return err
+		}
+
+		//locked before pricing, so the wallet credit is still there when spent
+		selected, err := repos.Wallet.FindCartWallet(u.ID)
+		if err != nil {
+			return err
+		}
+		if selected != nil {
+			if _, err := repos.Wallet.LockWallet(u.ID, s.Config.Currency); err != nil {
+				return err
+			}
+		}
+
+		if _, err := repos.GiftCard.LockCartGiftCards(u.ID); err != nil {
+			return err
+		}
+
+		cart, err := priceCart(repos, s.Tax, rates, s.Config.Currency, u.ID, cartItems, products)
+		if err != nil {
+			return err
+		}
+
+		if err := displayCart(rates, s.Config.Currency, buyer.Currency, cart); err != nil {
+			return err
+		}
+
+		if cart.HasChanges && !input.AcceptChanges {
+			return &CartChangedError{Cart: cart}
+		}
+
+		//the last units sold to someone else while waiting on the locks
+		if !cart.Subtotal.GreaterThan(domain.Money{}) {
+			return repository.ErrInsufficientStock
+		}
+
+		if cart.ShippingUnavailable {
+			return ErrShippingUnavailable
+		}
+
+		//paid to the courier or by bank transfer after the order is placed
+		deferred := method != domain.CAPTURE_CARD && cart.AmountDue.GreaterThan(domain.Money{})
+
+		//the payment covers what gift cards and the wallet don't
+		if p == nil && !deferred && !cart.AmountDue.IsZero() || p != nil && !cart.AmountDue.Equal(p.Amount) {
+			return ErrCartChanged
+		}
+
+		//gift cards are issued once paid, they have no delivery to pay on
+		if deferred && method == domain.CAPTURE_COD {
+			for _, line := range cart.Items {
+				if line.GiftCard && lineQty(line) > 0 {
+					return ErrCodGiftCards
+				}
+			}
+		}
+
+		byId := map[int]*domain.Product{}
+		for _, product := range products {
+			byId[int(product.ID)] = product
+		}
+
+		var orderItems []domain.OrderItem
+		for _, line := range cart.Items {
+			qty := lineQty(line)
+			if qty == 0 {
+				continue
+			}
+
+			product := byId[line.ProductId]
+			listPrice := *product.ListPrice
+			if line.VariantId > 0 {
+				if err := repos.Catalog.DecrementVariantStock(line.VariantId, uint(qty)); err != nil {
+					return err
+				}
+				listPrice = *product.Variant(line.VariantId).ListPrice
+			} else if err := repos.Catalog.DecrementStock(line.ProductId, uint(qty)); err != nil {
+				return err
+			}
+
+			orderItems = append(orderItems, domain.OrderItem{
+				ProductId: line.ProductId,
+				VariantId: line.VariantId,
+				SKU:       line.SKU,
+				Name:      line.Name,
+				Price:     line.CurrentPrice,
+				ListPrice: listPrice,
+				Qty:       qty,
+				Discount:  line.Discount,
+				ImageUrl:  line.ImageUrl,
+				SellerId:  line.SellerId,
+				GiftCard:  line.GiftCard,
+				Taxes:     line.Taxes,
+			})
+		}
+
+		if err := setCommission(repos, orderItems, products, s.Config.CommissionRate); err != nil {
+			return err
+		}
+
+		//payment row is locked too, so a payment backs one order only
+		if p != nil {
+			p, err = repos.Transaction.FindPaymentByPaymentId(p.PaymentId)
+			if err != nil {
+				return err
+			}
+
+			if p == nil || p.OrderId != 0 {
+				return ErrCartChanged
+			}
+		}
+
+		order := &domain.Order{
+			UserId:         uint(u.ID),
+			OrderRefNumber: orderRef,
+			Subtotal:       cart.Subtotal,
+			Shipping:       cart.ShippingTotal,
+			Discount:       cart.Discount,
+			Tax:            cart.Tax,
+			Amount:         cart.Total,
+			WalletAmount:   cart.WalletCredit,
+			GiftCardAmount: cart.GiftCardCredit,
+			Items:          orderItems,
+		}
+
+		//shown to the buyer in their currency, charged in the order's
+		order.DisplayCurrency = s.Config.Currency
+		order.DisplayTotal = cart.Total
+		if cart.Display != nil {
+			order.DisplayCurrency = cart.Display.Currency
+			order.DisplayTotal = cart.Display.Total
+		}
+
+		order.ExchangeRates, err = exchangeRates(rates, s.Config.Currency, order)
+		if err != nil {
+			return err
+		}
+		if p != nil {
+			order.PaymentId = p.PaymentId
+			order.TransactionId = p.TransactionId
+		}
+		if deferred {
+			order.PaymentId = deferredReference(method, orderRef)
+		}
+		if cart.Coupon != nil && cart.Coupon.Invalid == "" {
+			order.CouponCode = cart.Coupon.Code
+		}
+
+		//buyer may have completed the payment before placing the order, or
+		//gift cards and the wallet cover all of it
+		order.Status = domain.ORDER_PENDING_PAYMENT
+		if p == nil && !deferred || p != nil && p.Status == domain.PAYMENT_SUCCESS {
+			order.Status = domain.ORDER_PAID
+		}
+		//cash on delivery goes ahead now and is paid when delivered, bank
+		//transfers wait for the money
+		if deferred && method == domain.CAPTURE_COD {
+			order.Status = domain.ORDER_CONFIRMED
+		}
+		order.History = []domain.OrderHistory{{
+			ToStatus:  order.Status,
+			ActorId:   u.ID,
+			ActorRole: domain.BUYER,
+			Note:      "order placed",
+		}}
+
+		if err := repos.User.CreateOrder(order); err != nil {
+			return err
+		}
+
+		if err := createFulfilments(repos.Transaction, order, cart.Shipping); err != nil {
+			return err
+		}
+
+		if cart.Coupon != nil && cart.Coupon.Invalid == "" {
+			if err := redeemCoupon(repos, u.ID, order, cart.Coupon); err != nil {
+				return err
+			}
+		}
+
+		if err := redeemGiftCards(repos, order.ID, cart.GiftCards, OrderActor{Id: u.ID, Role: domain.BUYER}); err != nil {
+			return err
+		}
+
+		if cart.WalletCredit.GreaterThan(domain.Money{}) {
+			err := postWallet(repos, u.ID, &domain.WalletTransaction{
+				Kind:      domain.WALLET_CHECKOUT,
+				Amount:    domain.Money{}.Sub(cart.WalletCredit),
+				OrderId:   order.ID,
+				Note:      fmt.Sprintf("order %d", orderRef),
+				ActorId:   u.ID,
+				ActorRole: domain.BUYER,
+			})
+			if errors.Is(err, ErrWalletBalance) {
+				return ErrCartChanged
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if order.Status == domain.ORDER_PAID {
+			if err := recordSale(repos, order.ID); err != nil {
+				return err
+			}
+
+			if err := issueGiftCards(repos, order.ID, s.Config.GiftCardValidity); err != nil {
+				return err
+			}
+		}
+
+		//link payment to the placed order
+		if p != nil {
+			p.OrderId = order.ID
+			if err := repos.Transaction.UpdatePayment(p); err != nil {
+				return err
+			}
+		}
+
+		if deferred {
+			err := repos.Transaction.CreatePayment(&domain.Payment{
+				UserId:        uint(u.ID),
+				OrderId:       order.ID,
+				CaptureMethod: method,
+				Amount:        cart.AmountDue,
+				PaymentId:     order.PaymentId,
+				Status:        domain.PAYMENT_INITIAL,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := repos.Shipping.DeleteSelections(u.ID); err != nil {
+			return err
+		}
+
+		if err := repos.Coupon.DeleteCartCoupon(u.ID); err != nil {
+			return err
+		}
+
+		if err := repos.Wallet.DeleteCartWallet(u.ID); err != nil {
+			return err
+		}
+
+		if err := repos.GiftCard.DeleteCartGiftCards(u.ID); err != nil {
+			return err
+		}
+
+		//Delete items from cart after order success
+		return repos.User.DeleteCartItems(u.ID)
+	})
+	if err != nil {
 		return 0, err
 	}
 
@@ -387,9 +1054,145 @@ func (s *UserService) GetOrders(userId int) ([]*domain.Order, error) {
 
 func (s *UserService) GetOrderById(orderId uint, userId int) (*domain.Order, error) {
 
+	//only the buyer's own orders get their parcels tracked
 	order, err := s.Repo.FindOrderById(int(orderId), userId)
 	if err != nil {
 		return nil, err
 	}
+
+	//stale tracking is still worth showing, so a carrier failure is not fatal
+	if err := refreshTracking(s.Tx, s.SRepo, s.Carriers, order.ID); err != nil {
+		log.Printf("refreshing tracking of order %d failed %v", orderId, err)
+		return order, nil
+	}
+
+	return s.Repo.FindOrderById(int(orderId), userId)
+}
+
+func (s *UserService) UpdateOrderStatus(u domain.User, orderId uint, input dto.UpdateOrderStatusRequest) (*domain.Order, error) {
+
+	//cancelling restocks and refunds, only CancelOrder does that
+	if input.Status == domain.ORDER_CANCELLED {
+		return nil, fmt.Errorf("%w: use order cancellation instead", ErrInvalidTransition)
+	}
+
+	var order *domain.Order
+	err := s.Tx.WithTx(func(repos repository.Repositories) error {
+		var err error
+		order, err = transitionOrder(repos.Transaction, orderId, input.Status, OrderActor{Id: u.ID, Role: domain.BUYER}, input.Note)
+		if err != nil {
+			return err
+		}
+
+		//buyer confirmed the whole order, every shipped parcel arrived.
+		//Cash on delivery is only settled by the courier or an admin.
+		if order.Status == domain.ORDER_DELIVERED {
+			return repos.Transaction.DeliverFulfilments(orderId)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return order, nil
 }
+
+func (s *UserService) CancelOrder(u domain.User, orderId uint, input dto.CancelOrderRequest) (*dto.CancelOrderResponse, error) {
+
+	var response *dto.CancelOrderResponse
+	err := s.Tx.WithTx(func(repos repository.Repositories) error {
+		var err error
+		response, err = cancelOrderItems(repos, s.Payment, orderId, input.ItemIds, input.RefundTo == dto.REFUND_TO_WALLET, input.Reason, OrderActor{Id: u.ID, Role: domain.BUYER})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (s *UserService) RequestReturn(u domain.User, orderId uint, input dto.CreateReturnRequest) (*domain.ReturnRequest, error) {
+
+	if input.Qty < 1 {
+		return nil, errors.New("qty should be at least 1")
+	}
+
+	if len(input.Reason) < 1 {
+		return nil, errors.New("a reason is required to return an item")
+	}
+
+	var request *domain.ReturnRequest
+	err := s.Tx.WithTx(func(repos repository.Repositories) error {
+
+		order, err := repos.Transaction.FindOrderForUpdate(orderId)
+		if err != nil {
+			return err
+		}
+
+		if order.UserId != uint(u.ID) {
+			return errors.New("order not found")
+		}
+
+		item, err := findOrderItem(repos.Transaction, orderId, input.OrderItemId)
+		if err != nil {
+			return err
+		}
+
+		if item.GiftCard {
+			return errors.New("gift cards cannot be returned")
+		}
+
+		//each seller's parcel can be returned once it has arrived
+		f, err := repos.Transaction.FindFulfilment(item.FulfilmentId)
+		if err != nil {
+			return err
+		}
+
+		if f.Status != domain.FULFILMENT_DELIVERED {
+			return fmt.Errorf("%w: items can be returned once delivered, they are %s", ErrInvalidTransition, f.Status)
+		}
+
+		claimed, err := repos.Transaction.ReturnedQty(item.ID)
+		if err != nil {
+			return err
+		}
+
+		if claimed+input.Qty > item.Qty {
+			return fmt.Errorf("only %d units of this item can still be returned", item.Qty-claimed)
+		}
+
+		request = &domain.ReturnRequest{
+			OrderId:     orderId,
+			OrderItemId: item.ID,
+			UserId:      order.UserId,
+			SellerId:    item.SellerId,
+			Qty:         input.Qty,
+			Reason:      input.Reason,
+			Status:      domain.RETURN_REQUESTED,
+		}
+		return repos.Transaction.CreateReturn(request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// GetInvoice renders the invoice of the buyer's whole order, issuing it on
+// the first download.
+func (s *UserService) GetInvoice(u domain.User, orderId uint, format string) (*dto.InvoiceFile, error) {
+
+	issued, err := issueInvoice(s.Tx, orderId, 0, OrderActor{Id: u.ID, Role: domain.BUYER})
+	if err != nil {
+		return nil, err
+	}
+
+	return renderInvoice(issued, format)
+}
+
+func (s *UserService) GetReturns(u domain.User) ([]*domain.ReturnRequest, error) {
+	return s.TRepo.FindUserReturns(uint(u.ID))
+}
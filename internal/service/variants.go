@@ -0,0 +1,242 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"strings"
+)
+
+var (
+	ErrVariantNotFound = errors.New("variant not found")
+	ErrVariantRequired = errors.New("pick one of the product's variants")
+	ErrVariantInvalid  = errors.New("invalid variant")
+	ErrVariantStock    = errors.New("stock is kept per variant for products with variants")
+	ErrSKUTaken        = errors.New("sku is already used by another variant")
+)
+
+// sellerProduct finds a product of the seller with its options and variants
+func (s *CatalogService) sellerProduct(productId int, user domain.User) (*domain.Product, error) {
+
+	product, err := s.Repo.FindProductById(productId)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.UserId != user.ID {
+		return nil, errors.New("sorry, the product does not belongs to your stock")
+	}
+
+	return product, nil
+}
+
+// SetProductOptions sets the option types a product comes in. They can
+// only change while the product has no variants, every variant has a value
+// for each of them.
+func (s *CatalogService) SetProductOptions(productId int, input dto.ProductOptionsRequest, user domain.User) (*domain.Product, error) {
+
+	product, err := s.sellerProduct(productId, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(product.Variants) > 0 {
+		return nil, fmt.Errorf("%w, delete the variants before changing options", ErrVariantInvalid)
+	}
+
+	seen := map[string]bool{}
+	var options []domain.ProductOption
+	for i, name := range input.Options {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("%w, option names should be set and different", ErrVariantInvalid)
+		}
+		seen[strings.ToLower(name)] = true
+
+		options = append(options, domain.ProductOption{ProductId: product.ID, Name: name, Position: i})
+	}
+
+	if err := s.Repo.ReplaceProductOptions(product.ID, options); err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindProductById(productId)
+}
+
+// CreateVariant adds a variant for a combination of option values the
+// product doesn't have yet.
+func (s *CatalogService) CreateVariant(productId int, input dto.VariantRequest, user domain.User) (*domain.Variant, error) {
+
+	product, err := s.sellerProduct(productId, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(product.Options) == 0 {
+		return nil, fmt.Errorf("%w, set the product options first", ErrVariantInvalid)
+	}
+
+	values, err := variantOptions(product, input.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := &domain.Variant{
+		ProductId: product.ID,
+		Options:   values,
+		ImageUrl:  input.ImageUrl,
+	}
+
+	if err := s.setSKU(variant, input.SKU); err != nil {
+		return nil, err
+	}
+
+	if input.Price < 0 {
+		return nil, fmt.Errorf("%w, price can't be negative", ErrVariantInvalid)
+	}
+	if input.Price > 0 {
+		variant.Price = domain.NewMoney(input.Price, product.Price.Currency)
+	}
+
+	if input.Stock != nil {
+		variant.Stock = *input.Stock
+	}
+
+	if err := s.Repo.CreateVariant(variant); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.SyncProductStock(product.ID); err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+// UpdateVariant changes the SKU, price, stock or image of a variant. Its
+// options stay, a different combination is a new variant.
+func (s *CatalogService) UpdateVariant(productId int, variantId uint, input dto.VariantRequest, user domain.User) (*domain.Variant, error) {
+
+	product, err := s.sellerProduct(productId, user)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := product.Variant(variantId)
+	if variant == nil {
+		return nil, ErrVariantNotFound
+	}
+
+	if len(input.Options) > 0 {
+		return nil, fmt.Errorf("%w, options of a variant can't be changed", ErrVariantInvalid)
+	}
+
+	if len(input.SKU) > 0 && input.SKU != variant.SKU {
+		if err := s.setSKU(variant, input.SKU); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.Price < 0 {
+		return nil, fmt.Errorf("%w, price can't be negative", ErrVariantInvalid)
+	}
+	if input.Price > 0 {
+		variant.Price = domain.NewMoney(input.Price, product.Price.Currency)
+	}
+
+	if len(input.ImageUrl) > 0 {
+		variant.ImageUrl = input.ImageUrl
+	}
+
+	if input.Stock != nil {
+		variant.Stock = *input.Stock
+	}
+
+	if err := s.Repo.UpdateVariant(variant); err != nil {
+		return nil, err
+	}
+
+	if err := s.Repo.SyncProductStock(product.ID); err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+// DeleteVariant removes a variant, cart lines holding it are dropped at
+// checkout and past orders keep its SKU.
+func (s *CatalogService) DeleteVariant(productId int, variantId uint, user domain.User) error {
+
+	product, err := s.sellerProduct(productId, user)
+	if err != nil {
+		return err
+	}
+
+	if product.Variant(variantId) == nil {
+		return ErrVariantNotFound
+	}
+
+	if err := s.Repo.DeleteVariant(variantId); err != nil {
+		return err
+	}
+
+	return s.Repo.SyncProductStock(product.ID)
+}
+
+// setSKU gives the variant a SKU no other variant has
+func (s *CatalogService) setSKU(variant *domain.Variant, sku string) error {
+
+	sku = strings.TrimSpace(sku)
+	if sku == "" {
+		return fmt.Errorf("%w, it needs a sku", ErrVariantInvalid)
+	}
+
+	existing, err := s.Repo.FindVariantBySKU(sku)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != variant.ID {
+		return ErrSKUTaken
+	}
+
+	variant.SKU = sku
+	return nil
+}
+
+// variantOptions checks a variant has a value for every product option and
+// no other variant of the product has the same values.
+func variantOptions(product *domain.Product, input map[string]string) ([]domain.VariantOption, error) {
+
+	given := map[string]string{}
+	for name, value := range input {
+		given[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+
+	if len(given) != len(product.Options) {
+		return nil, fmt.Errorf("%w, give a value for each of its options", ErrVariantInvalid)
+	}
+
+	var values []domain.VariantOption
+	for _, option := range product.Options {
+		value := given[strings.ToLower(option.Name)]
+		if value == "" {
+			return nil, fmt.Errorf("%w, %s needs a value", ErrVariantInvalid, option.Name)
+		}
+
+		values = append(values, domain.VariantOption{OptionId: option.ID, Name: option.Name, Value: value})
+	}
+
+	for _, variant := range product.Variants {
+		same := len(variant.Options) == len(values)
+		for i := 0; same && i < len(values); i++ {
+			same = variant.Options[i].OptionId == values[i].OptionId &&
+				strings.EqualFold(variant.Options[i].Value, values[i].Value)
+		}
+		if same {
+			return nil, fmt.Errorf("%w, variant %s already has these options", ErrVariantInvalid, variant.SKU)
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+)
+
+var ErrWalletBalance = errors.New("not enough wallet balance")
+
+// postWallet changes the user's wallet balance by txn.Amount and records
+// the transaction. The wallet row stays locked until the surrounding
+// transaction ends, so concurrent spending can't take it below zero.
+func postWallet(repos repository.Repositories, userId int, txn *domain.WalletTransaction) error {
+
+	wallet, err := repos.Wallet.LockWallet(userId, txn.Amount.Currency)
+	if err != nil {
+		return err
+	}
+
+	if wallet.Balance.Currency != txn.Amount.Currency {
+		return errors.New("wallet is kept in a different currency")
+	}
+
+	balance := wallet.Balance.Add(txn.Amount)
+	if balance.IsNegative() {
+		return ErrWalletBalance
+	}
+
+	wallet.Balance = balance
+	if err := repos.Wallet.UpdateWallet(wallet); err != nil {
+		return err
+	}
+
+	txn.WalletId = wallet.ID
+	txn.UserId = userId
+	txn.BalanceAfter = balance
+	return repos.Wallet.CreateTransaction(txn)
+}
+
+// applyWallet takes the part of what is due the buyer chose to pay from
+// their wallet, the rest is left to pay by card
+func applyWallet(repos repository.Repositories, userId int, cart *dto.CartResponse) error {
+
+	cart.WalletCredit = domain.Money{Currency: cart.Total.Currency}
+
+	selected, err := repos.Wallet.FindCartWallet(userId)
+	if err != nil || selected == nil {
+		return err
+	}
+
+	wallet, err := repos.Wallet.FindWallet(userId)
+	if err != nil || wallet == nil {
+		return err
+	}
+
+	if wallet.Balance.Currency != cart.Total.Currency || !cart.AmountDue.GreaterThan(domain.Money{}) {
+		return nil
+	}
+
+	credit := cart.AmountDue.Min(wallet.Balance)
+	if selected.Amount.Currency == credit.Currency && selected.Amount.GreaterThan(domain.Money{}) {
+		credit = credit.Min(selected.Amount)
+	}
+
+	if credit.IsNegative() {
+		return nil
+	}
+
+	cart.WalletCredit = credit
+	cart.AmountDue = cart.AmountDue.Sub(credit)
+	return nil
+}
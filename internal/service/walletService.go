@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/configs"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+)
+
+// WalletService lets admins give buyers store credit, for promotions or to
+// correct a balance
+type WalletService struct {
+	Tx     repository.TxManager
+	Auth   helper.Auth
+	Config configs.AppConfig
+}
+
+func (s *WalletService) AdjustWallet(admin domain.User, userId int, input dto.WalletAdjustmentRequest) (*domain.WalletTransaction, error) {
+
+	switch input.Kind {
+	case domain.WALLET_PROMOTION:
+		if input.Amount <= 0 {
+			return nil, errors.New("promotional credit should be positive")
+		}
+	case domain.WALLET_ADJUSTMENT:
+		if input.Amount == 0 {
+			return nil, errors.New("adjustment amount is required")
+		}
+	default:
+		return nil, errors.New("kind should be promotion or adjustment")
+	}
+
+	//the note is the audit trail of why the balance changed
+	note := strings.TrimSpace(input.Note)
+	if len(note) < 1 {
+		return nil, errors.New("a note is required")
+	}
+
+	if _, err := s.Tx.Repositories().User.FindUserbyID(userId); err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	txn := &domain.WalletTransaction{
+		Kind:      input.Kind,
+		Amount:    domain.NewMoney(input.Amount, s.Config.Currency),
+		Note:      note,
+		ActorId:   admin.ID,
+		ActorRole: domain.ADMIN,
+	}
+
+	err := s.Tx.WithTx(func(repos repository.Repositories) error {
+		return postWallet(repos, userId, txn)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return txn, nil
+}
@@ -0,0 +1,152 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/configs"
+	"sync"
+)
+
+// DeclinedPaymentMethod makes the fake gateway reject a confirmation, the
+// same way stripe's pm_card_chargeDeclined test card does.
+const DeclinedPaymentMethod = "pm_card_chargeDeclined"
+
+// fakeClient is an in-process gateway used for tests and local development.
+// It keeps every intent in memory and never talks to the network. Webhooks
+// use the stripe payload and signature format, see SignWebhookPayload.
+type fakeClient struct {
+	mu            sync.Mutex
+	seq           int
+	webhookSecret string
+	intents       map[string]*PaymentIntent
+	refunded      map[string]int64
+}
+
+func NewFakePaymentClient(config configs.AppConfig) PaymentClient {
+	return &fakeClient{
+		webhookSecret: config.WebhookSecret,
+		intents:       map[string]*PaymentIntent{},
+		refunded:      map[string]int64{},
+	}
+}
+
+func (c *fakeClient) nextId(prefix string) string {
+	c.seq++
+	return fmt.Sprintf("%s_fake_%d", prefix, c.seq)
+}
+
+func (c *fakeClient) CreatePayment(amount int64, currency string, userId int, orderRef int) (*PaymentIntent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if amount <= 0 {
+		return nil, errors.New("payment amount must be positive")
+	}
+
+	id := c.nextId("pi")
+	pi := &PaymentIntent{
+		ID:           id,
+		ClientSecret: id + "_secret",
+		Amount:       amount,
+		Currency:     currency,
+		Status:       StatusRequiresPaymentMethod,
+	}
+	c.intents[id] = pi
+
+	copied := *pi
+	return &copied, nil
+}
+
+func (c *fakeClient) ConfirmPayment(paymentId string, paymentMethod string) (*PaymentIntent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pi, ok := c.intents[paymentId]
+	if !ok {
+		return nil, fmt.Errorf("payment %s not found", paymentId)
+	}
+
+	if pi.Status == StatusSucceeded || pi.Status == StatusCanceled {
+		return nil, fmt.Errorf("payment %s can not be confirmed in status %s", paymentId, pi.Status)
+	}
+
+	if paymentMethod == DeclinedPaymentMethod {
+		pi.Status = StatusRequiresPaymentMethod
+	} else {
+		pi.Status = StatusSucceeded
+		pi.TransactionId = c.nextId("ch")
+	}
+
+	copied := *pi
+	return &copied, nil
+}
+
+func (c *fakeClient) CapturePayment(paymentId string) (*PaymentIntent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pi, ok := c.intents[paymentId]
+	if !ok {
+		return nil, fmt.Errorf("payment %s not found", paymentId)
+	}
+
+	if pi.Status != StatusRequiresCapture && pi.Status != StatusSucceeded {
+		return nil, fmt.Errorf("payment %s can not be captured in status %s", paymentId, pi.Status)
+	}
+	pi.Status = StatusSucceeded
+
+	copied := *pi
+	return &copied, nil
+}
+
+func (c *fakeClient) CancelPayment(paymentId string) (*PaymentIntent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pi, ok := c.intents[paymentId]
+	if !ok {
+		return nil, fmt.Errorf("payment %s not found", paymentId)
+	}
+
+	if pi.Status == StatusSucceeded {
+		return nil, fmt.Errorf("payment %s already succeeded, refund it instead", paymentId)
+	}
+	pi.Status = StatusCanceled
+
+	copied := *pi
+	return &copied, nil
+}
+
+func (c *fakeClient) RefundPayment(paymentId string, amount int64) (*Refund, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pi, ok := c.intents[paymentId]
+	if !ok {
+		return nil, fmt.Errorf("payment %s not found", paymentId)
+	}
+
+	if pi.Status != StatusSucceeded {
+		return nil, fmt.Errorf("payment %s can not be refunded in status %s", paymentId, pi.Status)
+	}
+
+	if amount <= 0 || c.refunded[paymentId]+amount > pi.Amount {
+		return nil, errors.New("refund amount exceeds the captured amount")
+	}
+	c.refunded[paymentId] += amount
+
+	return &Refund{
+		ID:        c.nextId("re"),
+		PaymentId: paymentId,
+		Amount:    amount,
+		Status:    StatusSucceeded,
+	}, nil
+}
+
+func (c *fakeClient) ParseWebhook(payload []byte, signature string) (*WebhookEvent, error) {
+	//anyone could sign with an empty secret
+	if c.webhookSecret == "" {
+		return nil, fmt.Errorf("%w: no webhook secret set", ErrInvalidSignature)
+	}
+	return parseStripeEvent(payload, signature, c.webhookSecret)
+}
@@ -0,0 +1,90 @@
+package payment
+
+import (
+	"go-ecommerce-app/configs"
+	"testing"
+)
+
+// A buyer's card is declined once, the retry goes through and part of the
+// order is refunded later.
+func TestFakeCheckout(t *testing.T) {
+
+	pc := NewFakePaymentClient(configs.AppConfig{})
+
+	pi, err := pc.CreatePayment(2500, "USD", 7, 0)
+	if err != nil {
+		t.Fatalf("creating payment: %v", err)
+	}
+	if pi.Status != StatusRequiresPaymentMethod || pi.Amount != 2500 || pi.Currency != "USD" || pi.ClientSecret == "" {
+		t.Fatalf("unexpected new payment %+v", pi)
+	}
+
+	declined, err := pc.ConfirmPayment(pi.ID, DeclinedPaymentMethod)
+	if err != nil {
+		t.Fatalf("confirming declined card: %v", err)
+	}
+	if declined.Status != StatusRequiresPaymentMethod || declined.TransactionId != "" {
+		t.Fatalf("declined card got %+v", declined)
+	}
+
+	if _, err := pc.RefundPayment(pi.ID, 100); err == nil {
+		t.Fatal("refunded a payment that never succeeded")
+	}
+
+	paid, err := pc.ConfirmPayment(pi.ID, "pm_card_visa")
+	if err != nil {
+		t.Fatalf("confirming card: %v", err)
+	}
+	if paid.Status != StatusSucceeded || paid.TransactionId == "" {
+		t.Fatalf("paid payment got %+v", paid)
+	}
+
+	if _, err := pc.ConfirmPayment(pi.ID, "pm_card_visa"); err == nil {
+		t.Fatal("charged a succeeded payment twice")
+	}
+	if _, err := pc.CancelPayment(pi.ID); err == nil {
+		t.Fatal("cancelled a succeeded payment")
+	}
+
+	refund, err := pc.RefundPayment(pi.ID, 1000)
+	if err != nil {
+		t.Fatalf("refunding part of the payment: %v", err)
+	}
+	if refund.Status != StatusSucceeded || refund.Amount != 1000 || refund.PaymentId != pi.ID {
+		t.Fatalf("unexpected refund %+v", refund)
+	}
+
+	if _, err := pc.RefundPayment(pi.ID, 1501); err == nil {
+		t.Fatal("refunded more than was captured")
+	}
+	if _, err := pc.RefundPayment(pi.ID, 1500); err != nil {
+		t.Fatalf("refunding the rest: %v", err)
+	}
+}
+
+// A checkout abandoned before paying can't be charged any more.
+func TestFakeCheckoutCancelled(t *testing.T) {
+
+	pc := NewFakePaymentClient(configs.AppConfig{})
+
+	if _, err := pc.CreatePayment(0, "USD", 7, 0); err == nil {
+		t.Fatal("created a payment for nothing")
+	}
+
+	pi, err := pc.CreatePayment(900, "USD", 7, 0)
+	if err != nil {
+		t.Fatalf("creating payment: %v", err)
+	}
+
+	cancelled, err := pc.CancelPayment(pi.ID)
+	if err != nil {
+		t.Fatalf("cancelling payment: %v", err)
+	}
+	if cancelled.Status != StatusCanceled {
+		t.Fatalf("cancelled payment got %+v", cancelled)
+	}
+
+	if _, err := pc.ConfirmPayment(pi.ID, "pm_card_visa"); err == nil {
+		t.Fatal("charged a cancelled payment")
+	}
+}
@@ -0,0 +1,38 @@
+package payment
+
+// Payment intent statuses, named after the stripe ones so every gateway
+// reports the same vocabulary back to the services.
+const (
+	StatusRequiresPaymentMethod = "requires_payment_method"
+	StatusRequiresConfirmation  = "requires_confirmation"
+	StatusRequiresCapture       = "requires_capture"
+	StatusProcessing            = "processing"
+	StatusSucceeded             = "succeeded"
+	StatusCanceled              = "canceled"
+)
+
+// Amounts are in the smallest currency unit, e.g. cents.
+type PaymentIntent struct {
+	ID            string `json:"id"`
+	ClientSecret  string `json:"clientsecret"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Status        string `json:"status"`
+	TransactionId string `json:"transactionid"`
+}
+
+type Refund struct {
+	ID        string `json:"id"`
+	PaymentId string `json:"paymentid"`
+	Amount    int64  `json:"amount"`
+	Status    string `json:"status"`
+}
+
+type PaymentClient interface {
+	CreatePayment(amount int64, currency string, userId int, orderRef int) (*PaymentIntent, error)
+	ConfirmPayment(paymentId string, paymentMethod string) (*PaymentIntent, error)
+	CapturePayment(paymentId string) (*PaymentIntent, error)
+	CancelPayment(paymentId string) (*PaymentIntent, error)
+	RefundPayment(paymentId string, amount int64) (*Refund, error)
+	ParseWebhook(payload []byte, signature string) (*WebhookEvent, error)
+}
@@ -1,2 +1,118 @@
 package payment
 
+import (
+	"fmt"
+	"go-ecommerce-app/configs"
+	"strconv"
+	"strings"
+
+	"github.com/stripe/stripe-go/v78"
+	"github.com/stripe/stripe-go/v78/client"
+)
+
+type stripeClient struct {
+	api           *client.API
+	webhookSecret string
+}
+
+func NewPaymentClient(config configs.AppConfig) PaymentClient {
+	return &stripeClient{
+		api:           client.New(config.StripeSecret, nil),
+		webhookSecret: config.WebhookSecret,
+	}
+}
+
+func (c *stripeClient) CreatePayment(amount int64, currency string, userId int, orderRef int) (*PaymentIntent, error) {
+
+	params := &stripe.PaymentIntentParams{
+		Amount:   stripe.Int64(amount),
+		Currency: stripe.String(strings.ToLower(currency)),
+		AutomaticPaymentMethods: &stripe.PaymentIntentAutomaticPaymentMethodsParams{
+			Enabled: stripe.Bool(true),
+		},
+	}
+	params.AddMetadata("user_id", strconv.Itoa(userId))
+	params.AddMetadata("order_ref", strconv.Itoa(orderRef))
+
+	pi, err := c.api.PaymentIntents.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe payment intent creation failed %w", err)
+	}
+
+	return toPaymentIntent(pi), nil
+}
+
+func (c *stripeClient) ConfirmPayment(paymentId string, paymentMethod string) (*PaymentIntent, error) {
+
+	params := &stripe.PaymentIntentConfirmParams{}
+	if paymentMethod != "" {
+		params.PaymentMethod = stripe.String(paymentMethod)
+	}
+
+	pi, err := c.api.PaymentIntents.Confirm(paymentId, params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe payment confirmation failed %w", err)
+	}
+
+	return toPaymentIntent(pi), nil
+}
+
+func (c *stripeClient) CapturePayment(paymentId string) (*PaymentIntent, error) {
+
+	pi, err := c.api.PaymentIntents.Capture(paymentId, &stripe.PaymentIntentCaptureParams{})
+	if err != nil {
+		return nil, fmt.Errorf("stripe payment capture failed %w", err)
+	}
+
+	return toPaymentIntent(pi), nil
+}
+
+func (c *stripeClient) CancelPayment(paymentId string) (*PaymentIntent, error) {
+
+	pi, err := c.api.PaymentIntents.Cancel(paymentId, &stripe.PaymentIntentCancelParams{})
+	if err != nil {
+		return nil, fmt.Errorf("stripe payment cancellation failed %w", err)
+	}
+
+	return toPaymentIntent(pi), nil
+}
+
+func (c *stripeClient) RefundPayment(paymentId string, amount int64) (*Refund, error) {
+
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(paymentId),
+		Amount:        stripe.Int64(amount),
+	}
+
+	r, err := c.api.Refunds.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("stripe refund failed %w", err)
+	}
+
+	return &Refund{
+		ID:        r.ID,
+		PaymentId: paymentId,
+		Amount:    r.Amount,
+		Status:    string(r.Status),
+	}, nil
+}
+
+func (c *stripeClient) ParseWebhook(payload []byte, signature string) (*WebhookEvent, error) {
+	return parseStripeEvent(payload, signature, c.webhookSecret)
+}
+
+func toPaymentIntent(pi *stripe.PaymentIntent) *PaymentIntent {
+	intent := &PaymentIntent{
+		ID:           pi.ID,
+		ClientSecret: pi.ClientSecret,
+		Amount:       pi.Amount,
+		Currency:     strings.ToUpper(string(pi.Currency)),
+		Status:       string(pi.Status),
+	}
+
+	if pi.LatestCharge != nil {
+		intent.TransactionId = pi.LatestCharge.ID
+	}
+
+	return intent
+}
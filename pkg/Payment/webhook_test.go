@@ -0,0 +1,120 @@
+package payment
+
+import (
+	"errors"
+	"go-ecommerce-app/configs"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "whsec_test"
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	payload, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("reading fixture %s: %v", name, err)
+	}
+	return payload
+}
+
+func TestParseWebhookAcceptsSignedEvents(t *testing.T) {
+
+	pc := NewFakePaymentClient(configs.AppConfig{WebhookSecret: testWebhookSecret})
+
+	tests := []struct {
+		fixture string
+		want    WebhookEvent
+	}{
+		{
+			fixture: "payment_intent_succeeded.json",
+			want: WebhookEvent{
+				ID:            "evt_test_succeeded",
+				Type:          EventPaymentSucceeded,
+				ProviderType:  "payment_intent.succeeded",
+				PaymentId:     "pi_test_1",
+				TransactionId: "ch_test_1",
+				Amount:        1999,
+				Currency:      "USD",
+			},
+		},
+		{
+			fixture: "payment_intent_payment_failed.json",
+			want: WebhookEvent{
+				ID:            "evt_test_failed",
+				Type:          EventPaymentFailed,
+				ProviderType:  "payment_intent.payment_failed",
+				PaymentId:     "pi_test_1",
+				TransactionId: "ch_test_declined",
+				Amount:        1999,
+				Currency:      "USD",
+			},
+		},
+		{
+			fixture: "charge_refunded.json",
+			want: WebhookEvent{
+				ID:             "evt_test_refunded",
+				Type:           EventPaymentRefunded,
+				ProviderType:   "charge.refunded",
+				PaymentId:      "pi_test_1",
+				TransactionId:  "ch_test_1",
+				Amount:         1999,
+				Currency:       "USD",
+				AmountRefunded: 500,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			payload := fixture(t, tt.fixture)
+			signature := SignWebhookPayload(payload, testWebhookSecret, time.Now())
+
+			event, err := pc.ParseWebhook(payload, signature)
+			if err != nil {
+				t.Fatalf("signed event rejected: %v", err)
+			}
+
+			event.Payload = nil
+			if !reflect.DeepEqual(*event, tt.want) {
+				t.Fatalf("got event %+v, want %+v", *event, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWebhookRejectsBadSignatures(t *testing.T) {
+
+	payload := fixture(t, "payment_intent_succeeded.json")
+	signed := SignWebhookPayload(payload, testWebhookSecret, time.Now())
+
+	tampered := append([]byte{}, payload...)
+	tampered[len(tampered)-3] = ' '
+
+	tests := []struct {
+		name      string
+		secret    string
+		payload   []byte
+		signature string
+	}{
+		{name: "missing signature", secret: testWebhookSecret, payload: payload, signature: ""},
+		{name: "other secret", secret: testWebhookSecret, payload: payload, signature: SignWebhookPayload(payload, "whsec_other", time.Now())},
+		{name: "tampered payload", secret: testWebhookSecret, payload: tampered, signature: signed},
+		{name: "replayed too late", secret: testWebhookSecret, payload: payload, signature: SignWebhookPayload(payload, testWebhookSecret, time.Now().Add(-time.Hour))},
+		{name: "no secret set", secret: "", payload: payload, signature: SignWebhookPayload(payload, "", time.Now())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := NewFakePaymentClient(configs.AppConfig{WebhookSecret: tt.secret})
+
+			_, err := pc.ParseWebhook(tt.payload, tt.signature)
+			if !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("got error %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
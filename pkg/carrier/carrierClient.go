@@ -0,0 +1,85 @@
+package carrier
+
+import (
+	"fmt"
+	"go-ecommerce-app/configs"
+	"time"
+)
+
+// Tracking statuses every carrier reports back to the services.
+const (
+	StatusLabelCreated   = "label_created"
+	StatusInTransit      = "in_transit"
+	StatusOutForDelivery = "out_for_delivery"
+	StatusDelivered      = "delivered"
+	StatusException      = "exception"
+)
+
+type ShipmentRequest struct {
+	// Reference identifies the parcel on the seller side, e.g. the order ref
+	Reference string
+	// Service is the carrier service level, carriers fall back to their default
+	Service   string
+	ToName    string
+	ToAddress string
+	Items     int
+}
+
+type Label struct {
+	TrackingNumber string `json:"trackingnumber"`
+	LabelRef       string `json:"labelref"`
+}
+
+type TrackingEvent struct {
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	Location    string    `json:"location"`
+	OccurredAt  time.Time `json:"occurredAt"`
+}
+
+type CarrierClient interface {
+	Name() string
+	CreateShipment(req ShipmentRequest) (*Label, error)
+	// Track returns the full timeline of a parcel, oldest event first.
+	Track(trackingNumber string) ([]TrackingEvent, error)
+}
+
+// Carriers holds the configured carrier adapters by name.
+type Carriers struct {
+	clients  map[string]CarrierClient
+	fallback string
+}
+
+// NewCarriers sets up every carrier adapter, the configured carrier is used
+// when a shipment doesn't name one.
+func NewCarriers(config configs.AppConfig) (Carriers, error) {
+
+	simulator, err := NewSimulatorClient(config)
+	if err != nil {
+		return Carriers{}, err
+	}
+
+	c := Carriers{
+		clients:  map[string]CarrierClient{simulator.Name(): simulator},
+		fallback: config.Carrier,
+	}
+
+	if _, err := c.Get(""); err != nil {
+		return Carriers{}, err
+	}
+
+	return c, nil
+}
+
+func (c Carriers) Get(name string) (CarrierClient, error) {
+	if name == "" {
+		name = c.fallback
+	}
+
+	client, ok := c.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown carrier %s", name)
+	}
+
+	return client, nil
+}
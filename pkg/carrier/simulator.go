@@ -0,0 +1,146 @@
+package carrier
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-ecommerce-app/configs"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed fixtures/simulator.json
+var defaultFixtures []byte
+
+const simulatorName = "simulator"
+
+type simulatorStep struct {
+	After       string `json:"after"`
+	Status      string `json:"status"`
+	Description string `json:"description"`
+	Location    string `json:"location"`
+	offset      time.Duration
+}
+
+type simulatorFixtures struct {
+	Scenarios map[string][]simulatorStep `json:"scenarios"`
+}
+
+// simulatorClient is an offline carrier. Every parcel plays back a scripted
+// timeline from a fixture file, counted from when its label was created.
+// The scenario and creation time are kept in the tracking number, so
+// tracking keeps working across restarts without any state.
+type simulatorClient struct {
+	mu        sync.Mutex
+	seq       int
+	scenarios map[string][]simulatorStep
+	now       func() time.Time
+}
+
+// NewSimulatorClient loads the fixture file from CARRIER_FIXTURES, or the
+// bundled fixtures when none is configured.
+func NewSimulatorClient(config configs.AppConfig) (CarrierClient, error) {
+
+	data := defaultFixtures
+	if config.CarrierFixtures != "" {
+		file, err := os.ReadFile(config.CarrierFixtures)
+		if err != nil {
+			return nil, fmt.Errorf("reading carrier fixtures failed %w", err)
+		}
+		data = file
+	}
+
+	var fixtures simulatorFixtures
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("invalid carrier fixtures %w", err)
+	}
+
+	for name, steps := range fixtures.Scenarios {
+		if strings.Contains(name, "-") {
+			return nil, fmt.Errorf("carrier scenario %s: names cannot contain '-'", name)
+		}
+		for i := range steps {
+			offset, err := time.ParseDuration(steps[i].After)
+			if err != nil {
+				return nil, fmt.Errorf("carrier scenario %s step %d: %w", name, i, err)
+			}
+			steps[i].offset = offset
+		}
+	}
+
+	if _, ok := fixtures.Scenarios["standard"]; !ok {
+		return nil, errors.New("carrier fixtures need a standard scenario")
+	}
+
+	return &simulatorClient{
+		scenarios: fixtures.Scenarios,
+		now:       time.Now,
+	}, nil
+}
+
+func (c *simulatorClient) Name() string {
+	return simulatorName
+}
+
+func (c *simulatorClient) CreateShipment(req ShipmentRequest) (*Label, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	scenario := req.Service
+	if scenario == "" {
+		scenario = "standard"
+	}
+
+	if _, ok := c.scenarios[scenario]; !ok {
+		return nil, fmt.Errorf("unknown service %s", scenario)
+	}
+
+	c.seq++
+	tracking := fmt.Sprintf("SIM-%s-%d-%04d", scenario, c.now().Unix(), c.seq%10000)
+
+	return &Label{
+		TrackingNumber: tracking,
+		LabelRef:       fmt.Sprintf("simulator://labels/%s.pdf", tracking),
+	}, nil
+}
+
+func (c *simulatorClient) Track(trackingNumber string) ([]TrackingEvent, error) {
+
+	parts := strings.Split(trackingNumber, "-")
+	if len(parts) != 4 || parts[0] != "SIM" {
+		return nil, fmt.Errorf("unknown tracking number %s", trackingNumber)
+	}
+
+	steps, ok := c.scenarios[parts[1]]
+	if !ok {
+		return nil, fmt.Errorf("unknown tracking number %s", trackingNumber)
+	}
+
+	created, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unknown tracking number %s", trackingNumber)
+	}
+
+	start := time.Unix(created, 0)
+	now := c.now()
+
+	var events []TrackingEvent
+	for _, step := range steps {
+		at := start.Add(step.offset)
+		if at.After(now) {
+			break
+		}
+		events = append(events, TrackingEvent{
+			Status:      step.Status,
+			Description: step.Description,
+			Location:    step.Location,
+			OccurredAt:  at,
+		})
+	}
+
+	return events, nil
+}
@@ -0,0 +1,125 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/configs"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RATE_DECIMALS is the precision rates are rounded to. Prices are
+// converted at the rounded rate, the one recorded on orders, so a snapshot
+// reproduces the amounts exactly.
+const RATE_DECIMALS = 8
+
+var ErrUnknownCurrency = errors.New("no exchange rate for currency")
+
+// Table is one set of rates against a base currency: one unit of the base
+// buys Rates[code] of the currency.
+type Table struct {
+	Source string
+	Base   string
+	AsOf   time.Time
+	Rates  map[string]*big.Rat
+}
+
+// Rate is the price of one unit of From in To.
+type Rate struct {
+	From   string
+	To     string
+	Value  *big.Rat
+	Source string
+	AsOf   time.Time
+}
+
+func (r Rate) String() string {
+	return r.Value.FloatString(RATE_DECIMALS)
+}
+
+// Source is where rates come from. Latest is called on every pricing, so
+// sources that fetch rates remotely should cache them.
+type Source interface {
+	Latest() (*Table, error)
+}
+
+// NewSource sets up the configured rate source
+func NewSource(config configs.AppConfig) (Source, error) {
+
+	switch config.FXProvider {
+	case "static":
+		return NewStaticSource(config.FXRates)
+	}
+
+	return nil, fmt.Errorf("unknown exchange rate provider %s", config.FXProvider)
+}
+
+// Supports tells whether the table has a rate for the currency
+func (t *Table) Supports(currency string) bool {
+	_, ok := t.Rates[strings.ToUpper(currency)]
+	return ok
+}
+
+// Currencies lists the codes the table has rates for
+func (t *Table) Currencies() []string {
+	codes := make([]string, 0, len(t.Rates))
+	for code := range t.Rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Rate crosses the two currencies through the base
+func (t *Table) Rate(from string, to string) (Rate, error) {
+
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+
+	rate := Rate{From: from, To: to, Value: big.NewRat(1, 1), Source: t.Source, AsOf: t.AsOf}
+	if from == to {
+		return rate, nil
+	}
+
+	fromRate, ok := t.Rates[from]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w %s", ErrUnknownCurrency, from)
+	}
+
+	toRate, ok := t.Rates[to]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w %s", ErrUnknownCurrency, to)
+	}
+
+	cross := new(big.Rat).Quo(toRate, fromRate)
+	rate.Value, _ = new(big.Rat).SetString(cross.FloatString(RATE_DECIMALS))
+
+	return rate, nil
+}
+
+// newTable checks the rates and keys them by upper case code
+func newTable(source string, base string, asOf time.Time, rates map[string]string) (*Table, error) {
+
+	table := &Table{
+		Source: source,
+		Base:   strings.ToUpper(base),
+		AsOf:   asOf,
+		Rates:  map[string]*big.Rat{},
+	}
+
+	for code, value := range rates {
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("exchange rate for %s should be a positive number", code)
+		}
+		table.Rates[strings.ToUpper(code)] = rate
+	}
+
+	one, ok := table.Rates[table.Base]
+	if !ok || one.Cmp(big.NewRat(1, 1)) != 0 {
+		return nil, fmt.Errorf("exchange rates should list the base %s at 1", table.Base)
+	}
+
+	return table, nil
+}
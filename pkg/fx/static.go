@@ -0,0 +1,60 @@
+package fx
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+//go:embed rates/default.json
+var defaultRates []byte
+
+type rateFile struct {
+	Base  string            `json:"base"`
+	AsOf  string            `json:"asof"`
+	Rates map[string]string `json:"rates"`
+}
+
+// StaticSource serves rates from a file read once at startup, for offline
+// runs or when rates are set by hand.
+type StaticSource struct {
+	table *Table
+}
+
+// NewStaticSource reads the rates file at path, or the bundled one when
+// path is empty.
+func NewStaticSource(path string) (*StaticSource, error) {
+
+	data := defaultRates
+	if path != "" {
+		file, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading exchange rates failed %w", err)
+		}
+		data = file
+	}
+
+	var file rateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid exchange rates %w", err)
+	}
+
+	asOf, err := time.Parse("2006-01-02", file.AsOf)
+	if err != nil {
+		return nil, errors.New("exchange rates asof should be a date like 2006-01-02")
+	}
+
+	table, err := newTable("static", file.Base, asOf, file.Rates)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StaticSource{table: table}, nil
+}
+
+func (s *StaticSource) Latest() (*Table, error) {
+	return s.table, nil
+}
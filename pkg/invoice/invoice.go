@@ -0,0 +1,67 @@
+package invoice
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"time"
+)
+
+//go:embed templates/invoice.html
+var htmlTemplate string
+
+var page = template.Must(template.New("invoice").Parse(htmlTemplate))
+
+// Party is the seller or the buyer on an invoice, Lines is the address.
+type Party struct {
+	Name  string   `json:"name"`
+	Lines []string `json:"lines"`
+}
+
+// Line is one invoiced item. Amounts are formatted already, the invoice
+// only lays them out.
+type Line struct {
+	Description string `json:"description"`
+	SoldBy      string `json:"soldby"`
+	Qty         int    `json:"qty"`
+	UnitPrice   string `json:"unitprice"`
+	Discount    string `json:"discount"`
+	Tax         string `json:"tax"`
+	Amount      string `json:"amount"`
+}
+
+type TaxLine struct {
+	Label  string `json:"label"`
+	Amount string `json:"amount"`
+}
+
+// Document is everything printed on an invoice. It is stored when the
+// invoice is issued, so rendering it again later gives the same document.
+// TaxLines are printed with the totals, labelled with the rate and whether
+// the tax is included in the prices.
+type Document struct {
+	Number   string    `json:"number"`
+	IssuedAt time.Time `json:"issuedat"`
+	OrderRef string    `json:"orderref"`
+	Seller   Party     `json:"seller"`
+	BillTo   Party     `json:"billto"`
+	Lines    []Line    `json:"lines"`
+	Subtotal string    `json:"subtotal"`
+	Discount string    `json:"discount"`
+	Shipping string    `json:"shipping"`
+	Total    string    `json:"total"`
+	TaxLines []TaxLine `json:"taxlines"`
+	Notes    []string  `json:"notes"`
+}
+
+// RenderHTML renders the invoice as a standalone HTML page
+func RenderHTML(doc *Document) ([]byte, error) {
+
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, doc); err != nil {
+		return nil, fmt.Errorf("rendering invoice failed %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,256 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 in points
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 50.0
+)
+
+// table columns, amounts are right aligned at their x
+var columns = []struct {
+	title string
+	x     float64
+	right bool
+}{
+	{"Item", margin, false},
+	{"Qty", 300, true},
+	{"Unit price", 365, true},
+	{"Discount", 425, true},
+	{"Tax", 485, true},
+	{"Amount", pageWidth - margin, true},
+}
+
+// RenderPDF renders the invoice as a PDF. It only uses the standard
+// Helvetica fonts every PDF reader has, so nothing is embedded and no
+// external service is needed.
+func RenderPDF(doc *Document) ([]byte, error) {
+
+	w := &pdfWriter{}
+	w.newPage()
+
+	w.text(margin, w.y, 20, true, "Invoice")
+	w.y -= 18
+	w.text(margin, w.y, 9, false, fmt.Sprintf("%s  |  issued %s  |  order %s", doc.Number, doc.IssuedAt.Format("2 January 2006"), doc.OrderRef))
+	w.y -= 30
+
+	//seller and buyer side by side
+	top := w.y
+	if doc.Seller.Name != "" {
+		w.party(margin, "FROM", doc.Seller)
+	}
+	bottom := w.y
+	w.y = top
+	w.party(320, "BILL TO", doc.BillTo)
+	if bottom < w.y {
+		w.y = bottom
+	}
+	w.y -= 20
+
+	w.tableHeader()
+	for _, line := range doc.Lines {
+		if w.y < margin+40 {
+			w.newPage()
+			w.tableHeader()
+		}
+
+		values := []string{clip(line.Description, 48), fmt.Sprint(line.Qty), line.UnitPrice, line.Discount, line.Tax, line.Amount}
+		w.row(9, false, values)
+		if line.SoldBy != "" {
+			w.text(margin, w.y, 7, false, clip("sold by "+line.SoldBy, 60))
+			w.y -= 10
+		}
+		w.y -= 4
+	}
+
+	w.y -= 10
+	totals := [][2]string{{"Subtotal", doc.Subtotal}, {"Discount", doc.Discount}, {"Shipping", doc.Shipping}}
+	for _, t := range doc.TaxLines {
+		totals = append(totals, [2]string{t.Label, t.Amount})
+	}
+
+	for _, t := range totals {
+		if w.y < margin+20 {
+			w.newPage()
+		}
+		w.text(340, w.y, 9, false, t[0])
+		w.textRight(pageWidth-margin, w.y, 9, false, t[1])
+		w.y -= 14
+	}
+
+	w.line(340, w.y+10, pageWidth-margin, w.y+10)
+	w.text(340, w.y-2, 11, true, "Total")
+	w.textRight(pageWidth-margin, w.y-2, 11, true, doc.Total)
+	w.y -= 30
+
+	for _, note := range doc.Notes {
+		if w.y < margin {
+			w.newPage()
+		}
+		w.text(margin, w.y, 8, false, clip(note, 110))
+		w.y -= 12
+	}
+
+	return w.bytes(doc.Number), nil
+}
+
+type pdfWriter struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func (w *pdfWriter) newPage() {
+	w.pages = append(w.pages, &bytes.Buffer{})
+	w.y = pageHeight - margin
+}
+
+func (w *pdfWriter) content() *bytes.Buffer {
+	return w.pages[len(w.pages)-1]
+}
+
+func (w *pdfWriter) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(w.content(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escape(s))
+}
+
+func (w *pdfWriter) textRight(x, y, size float64, bold bool, s string) {
+	w.text(x-textWidth(s, size), y, size, bold, s)
+}
+
+func (w *pdfWriter) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(w.content(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+func (w *pdfWriter) party(x float64, title string, p Party) {
+	w.text(x, w.y, 8, true, title)
+	w.y -= 13
+	w.text(x, w.y, 10, false, clip(p.Name, 40))
+	w.y -= 13
+	for _, l := range p.Lines {
+		w.text(x, w.y, 9, false, clip(l, 45))
+		w.y -= 12
+	}
+}
+
+func (w *pdfWriter) tableHeader() {
+	titles := make([]string, len(columns))
+	for i, c := range columns {
+		titles[i] = c.title
+	}
+	w.row(9, true, titles)
+	w.line(margin, w.y+8, pageWidth-margin, w.y+8)
+	w.y -= 6
+}
+
+func (w *pdfWriter) row(size float64, bold bool, values []string) {
+	for i, c := range columns {
+		if c.right {
+			w.textRight(c.x, w.y, size, bold, values[i])
+		} else {
+			w.text(c.x, w.y, size, bold, values[i])
+		}
+	}
+	w.y -= size + 4
+}
+
+// bytes lays out the objects: catalog, page tree, the two fonts, then a
+// page and its content stream for every page, followed by the xref table.
+func (w *pdfWriter) bytes(title string) []byte {
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	const firstPage = 6
+	var kids []string
+	for i := range w.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+2*i))
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(w.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (go-ecommerce-app) >>", escape(title)))
+
+	for i, page := range w.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// escape encodes s for a PDF string in WinAnsi, characters it can't show
+// become '?'
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '€':
+			b.WriteString("\\200")
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+func clip(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+// helvetica advance widths per 1000 units for printable ASCII
+var helvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+func textWidth(s string, size float64) float64 {
+	units := 0
+	for _, r := range s {
+		if r >= 32 && r < 127 {
+			units += helvetica[r-32]
+		} else {
+			units += 556
+		}
+	}
+	return float64(units) * size / 1000
+}
@@ -0,0 +1,60 @@
+package payout
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RejectedSwiftCode makes the fake provider turn a transfer down, the way
+// a bank does for a closed account.
+const RejectedSwiftCode = "REJECTXX"
+
+// fakeClient is an in-process payout provider used for tests and local
+// development. It keeps every transfer in memory and never moves money.
+type fakeClient struct {
+	mu        sync.Mutex
+	seq       int
+	transfers map[string]*Transfer
+}
+
+func NewFakePayoutClient() PayoutClient {
+	return &fakeClient{
+		transfers: map[string]*Transfer{},
+	}
+}
+
+func (c *fakeClient) Transfer(req TransferRequest) (*Transfer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req.Reference == "" {
+		return nil, errors.New("transfer reference is required")
+	}
+
+	if t, ok := c.transfers[req.Reference]; ok {
+		copied := *t
+		return &copied, nil
+	}
+
+	if req.Amount <= 0 {
+		return nil, errors.New("transfer amount must be positive")
+	}
+
+	c.seq++
+	t := &Transfer{
+		ID:        fmt.Sprintf("tr_fake_%d", c.seq),
+		Reference: req.Reference,
+		Amount:    req.Amount,
+		Currency:  req.Currency,
+		Status:    StatusPaid,
+	}
+	if req.SwiftCode == RejectedSwiftCode {
+		t.Status = StatusFailed
+		t.FailureReason = "account closed"
+	}
+	c.transfers[req.Reference] = t
+
+	copied := *t
+	return &copied, nil
+}
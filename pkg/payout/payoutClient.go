@@ -0,0 +1,49 @@
+package payout
+
+import (
+	"fmt"
+	"go-ecommerce-app/configs"
+)
+
+// Transfer statuses every payout provider reports back to the services.
+const (
+	StatusPaid   = "paid"
+	StatusFailed = "failed"
+)
+
+// Amounts are in the smallest currency unit, e.g. cents.
+type TransferRequest struct {
+	// Reference is the idempotency key, sending it again returns the
+	// transfer made the first time
+	Reference     string
+	AccountNumber string
+	SwiftCode     string
+	Amount        int64
+	Currency      string
+}
+
+type Transfer struct {
+	ID            string `json:"id"`
+	Reference     string `json:"reference"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	Status        string `json:"status"`
+	FailureReason string `json:"failurereason"`
+}
+
+type PayoutClient interface {
+	// Transfer sends money to a bank account. A transfer the bank turned
+	// down comes back with StatusFailed, an error means it may be retried.
+	Transfer(req TransferRequest) (*Transfer, error)
+}
+
+// NewPayoutClient sets up the configured payout provider
+func NewPayoutClient(config configs.AppConfig) (PayoutClient, error) {
+
+	switch config.PayoutProvider {
+	case "fake":
+		return NewFakePayoutClient(), nil
+	}
+
+	return nil, fmt.Errorf("unknown payout provider %s", config.PayoutProvider)
+}
@@ -0,0 +1,177 @@
+package tax
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//go:embed rules/default.json
+var defaultRules []byte
+
+// Rates are in basis points, 1/100 of a percent, so 2000 is 20%.
+type CategoryRate struct {
+	Category uint  `json:"category"`
+	Rate     int64 `json:"rate"`
+}
+
+// Region is a tax area: a country, or a post code range within it when
+// PostCodeTo is set. Inclusive regions list prices with tax included.
+type Region struct {
+	Country          string         `json:"country"`
+	PostCodeFrom     uint           `json:"postcodefrom"`
+	PostCodeTo       uint           `json:"postcodeto"`
+	Name             string         `json:"name"`
+	Rate             int64          `json:"rate"`
+	Inclusive        bool           `json:"inclusive"`
+	CategoryRates    []CategoryRate `json:"categoryrates"`
+	ExemptCategories []uint         `json:"exemptcategories"`
+	ExemptSellers    []int          `json:"exemptsellers"`
+}
+
+// Rules is a versioned rate table. Changing rates means shipping a new
+// file with a new version, every tax line records the version it used.
+type Rules struct {
+	Version string   `json:"version"`
+	Regions []Region `json:"regions"`
+}
+
+// Line is one priced cart or order line, Amount is the line total in the
+// smallest currency unit.
+type Line struct {
+	Category uint
+	SellerId int
+	Amount   int64
+}
+
+type LineTax struct {
+	Name         string
+	Rate         int64
+	Inclusive    bool
+	Amount       int64
+	RulesVersion string
+}
+
+// LoadRules reads the rate table at path, or the bundled one when path is
+// empty.
+func LoadRules(path string) (*Rules, error) {
+
+	data := defaultRules
+	if path != "" {
+		file, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading tax rules failed %w", err)
+		}
+		data = file
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid tax rules %w", err)
+	}
+
+	if rules.Version == "" {
+		return nil, errors.New("tax rules need a version")
+	}
+
+	for _, region := range rules.Regions {
+		if region.Rate < 0 {
+			return nil, fmt.Errorf("tax region %s has a negative rate", region.Country)
+		}
+		if region.PostCodeTo > 0 && region.Country == "" {
+			return nil, errors.New("tax region with a post code range needs a country")
+		}
+	}
+
+	return &rules, nil
+}
+
+// Compute returns the tax on a line shipped to the given address, nil when
+// no region covers the address or the line is exempt.
+func (r *Rules) Compute(country string, postCode uint, line Line) *LineTax {
+
+	region := r.match(country, postCode)
+	if region == nil {
+		return nil
+	}
+
+	for _, seller := range region.ExemptSellers {
+		if seller == line.SellerId {
+			return nil
+		}
+	}
+
+	for _, category := range region.ExemptCategories {
+		if category == line.Category {
+			return nil
+		}
+	}
+
+	rate := region.Rate
+	for _, cr := range region.CategoryRates {
+		if cr.Category == line.Category {
+			rate = cr.Rate
+		}
+	}
+
+	if rate == 0 {
+		return nil
+	}
+
+	tax := &LineTax{
+		Name:         region.Name,
+		Rate:         rate,
+		Inclusive:    region.Inclusive,
+		RulesVersion: r.Version,
+	}
+
+	if region.Inclusive {
+		//share of the gross amount that is tax
+		tax.Amount = divRound(line.Amount*rate, 10000+rate)
+	} else {
+		tax.Amount = divRound(line.Amount*rate, 10000)
+	}
+
+	return tax
+}
+
+// match picks the most specific region covering the address: a post code
+// range, then a whole country.
+func (r *Rules) match(country string, postCode uint) *Region {
+
+	var best *Region
+	for i := range r.Regions {
+		region := &r.Regions[i]
+		if !strings.EqualFold(region.Country, country) {
+			continue
+		}
+
+		if region.PostCodeTo > 0 {
+			if postCode < region.PostCodeFrom || postCode > region.PostCodeTo {
+				continue
+			}
+			return region
+		}
+
+		if best == nil {
+			best = region
+		}
+	}
+
+	return best
+}
+
+// divRound divides rounding half away from zero
+func divRound(a int64, b int64) int64 {
+	q := a / b
+	r := a % b
+	if r*2 >= b {
+		q++
+	} else if r*2 <= -b {
+		q--
+	}
+	return q
+}